@@ -0,0 +1,229 @@
+package apu
+
+import "cpu"
+
+// The 2A03's audio processing unit. It's clocked once per CPU cycle; most of
+// the channels' timers run at half that rate, and the frame counter clocks
+// their envelopes, sweeps and length counters at roughly 240Hz.
+//
+// See -- http://wiki.nesdev.com/w/index.php/APU
+type APU struct {
+    Pulse1 *Pulse
+    Pulse2 *Pulse
+    Triangle *Triangle
+    Noise *Noise
+    DMC *DMC
+
+    // Sound from the cartridge, if it has any.
+    Expansion Expansion
+
+    FrameCounter
+
+    Region cpu.Region
+
+    // Where the frame and DMC IRQs are raised.
+    Bus cpu.Bus
+
+    // Asks the DMA unit to fetch a DMC sample byte and hand it to done.
+    DMA func(location cpu.Address, done func(byte))
+
+    // Odd CPU cycles are the second half of an APU cycle.
+    cycle int
+}
+
+const (
+    PULSE1 = 0x00
+    PULSE2 = 0x04
+    TRIANGLE = 0x08
+    NOISE = 0x0c
+    DMC_REGISTERS = 0x10
+    STATUS = 0x15
+    FRAME_COUNTER = 0x17
+)
+
+func NewAPU() *APU {
+    a := new(APU)
+
+    a.Pulse1 = NewPulse(1)
+    a.Pulse2 = NewPulse(2)
+    a.Triangle = NewTriangle()
+    a.Noise = NewNoise()
+    a.DMC = NewDMC()
+
+    a.SetRegion(cpu.NTSC)
+
+    return a
+}
+
+func (a *APU) SetRegion(region cpu.Region) {
+    a.Region = region
+    a.FrameCounter.steps = FrameSteps[region]
+    a.Noise.SetRegion(region)
+    a.DMC.SetRegion(region)
+}
+
+// The reset button silences every channel and restarts the frame counter
+// with whatever was last written to it.
+func (a *APU) Reset() {
+    a.Write(0x00, STATUS)
+    a.Triangle.step = 0
+    a.DMC.Level &= 0x01
+
+    a.FrameCounter.Write(a.FrameCounter.value, a.cycle & 0x01 == 0x01)
+    a.setInterrupted(false)
+}
+
+// Runs one CPU cycle.
+func (a *APU) Step() {
+    a.Triangle.ClockTimer()
+
+    if a.cycle & 0x01 == 0x01 {
+        a.Pulse1.ClockTimer()
+        a.Pulse2.ClockTimer()
+        a.Noise.ClockTimer()
+        a.DMC.ClockTimer()
+    }
+    a.cycle++
+
+    if a.Expansion != nil {
+        a.Expansion.Step()
+    }
+
+    a.fetchSample()
+
+    interrupted := a.FrameCounter.Interrupted
+
+    switch a.FrameCounter.Step() {
+        case HALF_FRAME:
+            a.clockHalfFrame()
+            a.clockQuarterFrame()
+        case QUARTER_FRAME:
+            a.clockQuarterFrame()
+    }
+
+    if a.FrameCounter.Interrupted && !interrupted {
+        a.setInterrupted(true)
+    }
+}
+
+// Raises or acknowledges one of the APU's interrupts.
+func (a *APU) signal(kind int, interrupted bool) {
+    if a.Bus == nil {
+        return
+    }
+
+    if interrupted {
+        a.Bus.Interrupt(kind)
+    } else {
+        a.Bus.Cancel(kind)
+    }
+}
+
+func (a *APU) setInterrupted(interrupted bool) {
+    a.FrameCounter.Interrupted = interrupted
+    a.signal(cpu.FRAME_IRQ, interrupted)
+}
+
+// Starts a DMA for the DMC's next sample byte if its buffer is empty.
+func (a *APU) fetchSample() {
+    location, ok := a.DMC.NeedsFetch()
+    if !ok || a.DMA == nil {
+        return
+    }
+
+    a.DMC.StartFetch()
+    a.DMA(location, a.SampleFetched)
+}
+
+// Hands the DMC the sample byte the DMA fetched for it.
+func (a *APU) SampleFetched(value byte) {
+    a.DMC.Fill(value)
+
+    if a.DMC.Interrupted {
+        a.signal(cpu.DMC_IRQ, true)
+    }
+}
+
+func (a *APU) clockQuarterFrame() {
+    a.Pulse1.Envelope.Clock()
+    a.Pulse2.Envelope.Clock()
+    a.Triangle.LinearCounter.Clock()
+    a.Noise.Envelope.Clock()
+}
+
+func (a *APU) clockHalfFrame() {
+    a.Pulse1.LengthCounter.Clock()
+    a.Pulse2.LengthCounter.Clock()
+    a.Triangle.LengthCounter.Clock()
+    a.Noise.LengthCounter.Clock()
+
+    a.Pulse1.ClockSweep()
+    a.Pulse2.ClockSweep()
+}
+
+// Reads a register, given as an offset from $4000. Only the status register
+// can be read; everything else is open bus. Reading the status acknowledges
+// the frame interrupt.
+func (a *APU) Read(location cpu.Address) byte {
+    if location != STATUS {
+        return 0x00
+    }
+
+    status := a.status()
+    a.setInterrupted(false)
+
+    return status
+}
+
+// Reads a register without acknowledging anything, for debuggers.
+func (a *APU) ReadDebug(location cpu.Address) byte {
+    if location != STATUS {
+        return 0x00
+    }
+
+    return a.status()
+}
+
+func (a *APU) status() byte {
+    var status = byte(0x00)
+
+    if a.Pulse1.LengthCounter.Active() { status |= 0x01 }
+    if a.Pulse2.LengthCounter.Active() { status |= 0x02 }
+    if a.Triangle.LengthCounter.Active() { status |= 0x04 }
+    if a.Noise.LengthCounter.Active() { status |= 0x08 }
+    if a.DMC.Active() { status |= 0x10 }
+    if a.FrameCounter.Interrupted { status |= 0x40 }
+    if a.DMC.Interrupted { status |= 0x80 }
+
+    return status
+}
+
+func (a *APU) Write(val byte, location cpu.Address) {
+    switch {
+        case location >= PULSE1 && location < PULSE1 + 4:
+            a.Pulse1.Write(val, int(location - PULSE1))
+        case location >= PULSE2 && location < PULSE2 + 4:
+            a.Pulse2.Write(val, int(location - PULSE2))
+        case location >= TRIANGLE && location < TRIANGLE + 4:
+            a.Triangle.Write(val, int(location - TRIANGLE))
+        case location >= NOISE && location < NOISE + 4:
+            a.Noise.Write(val, int(location - NOISE))
+        case location >= DMC_REGISTERS && location < DMC_REGISTERS + 4:
+            a.DMC.Write(val, int(location - DMC_REGISTERS))
+            if !a.DMC.Interrupted {
+                a.signal(cpu.DMC_IRQ, false)
+            }
+        case location == STATUS:
+            a.Pulse1.LengthCounter.SetEnabled(val & 0x01 == 0x01)
+            a.Pulse2.LengthCounter.SetEnabled(val & 0x02 == 0x02)
+            a.Triangle.LengthCounter.SetEnabled(val & 0x04 == 0x04)
+            a.Noise.LengthCounter.SetEnabled(val & 0x08 == 0x08)
+            a.DMC.SetEnabled(val & 0x10 == 0x10)
+            a.signal(cpu.DMC_IRQ, false)
+        case location == FRAME_COUNTER:
+            a.FrameCounter.Write(val, a.cycle & 0x01 == 0x01)
+            if a.FrameCounter.InhibitIRQ {
+                a.setInterrupted(false)
+            }
+    }
+}
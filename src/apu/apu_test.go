@@ -0,0 +1,141 @@
+package apu
+
+import (
+    "cpu"
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+type testBus struct {
+    irq bool
+}
+
+func (b *testBus) Interrupt(kind int) { b.irq = true }
+func (b *testBus) Cancel(kind int) { b.irq = false }
+
+func steps(a *APU, cycles int) {
+    for i := 0; i < cycles; i++ {
+        a.Step()
+    }
+}
+
+func TestStatusReportsActiveLengthCounters(t *testing.T) {
+    a := NewAPU()
+
+    a.Write(0x0f, STATUS)
+    a.Write(0x08, PULSE2 + 3)
+    a.Write(0x08, NOISE + 3)
+
+    assert.Equal(t, a.Read(STATUS), byte(0x0a))
+
+    a.Write(0x00, STATUS)
+    assert.Equal(t, a.Read(STATUS), byte(0x00))
+}
+
+func TestFrameCounterClocksLengthCounters(t *testing.T) {
+    a := NewAPU()
+    a.Write(0x40, FRAME_COUNTER)
+    a.Write(0x01, STATUS)
+    a.Write(0x18, PULSE1 + 3)
+    steps(a, 4)
+
+    steps(a, 14913)
+    assert.Equal(t, a.Pulse1.LengthCounter.Value, byte(1))
+
+    steps(a, 29829 - 14913)
+    assert.Equal(t, a.Read(STATUS), byte(0x00))
+}
+
+func TestFrameCounterSequenceLength(t *testing.T) {
+    f := FrameCounter { steps: FrameSteps[cpu.NTSC] }
+
+    var halves = 0
+    for i := 0; i < 29830 * 2; i++ {
+        if f.Step() == HALF_FRAME {
+            halves++
+        }
+    }
+
+    assert.Equal(t, halves, 4)
+    assert.Equal(t, f.cycle, 0)
+}
+
+func TestFrameCounterWriteIsDelayed(t *testing.T) {
+    a := NewAPU()
+    a.Write(0x01, STATUS)
+    a.Write(0x18, PULSE1 + 3)
+
+    a.Write(0x80, FRAME_COUNTER)
+    assert.Equal(t, a.Pulse1.LengthCounter.Value, byte(2))
+
+    // Writes landing on the first half of an APU cycle take effect after
+    // three cycles, and five step mode clocks everything when it does.
+    steps(a, 2)
+    assert.Equal(t, a.Pulse1.LengthCounter.Value, byte(2))
+    steps(a, 1)
+    assert.Equal(t, a.Pulse1.LengthCounter.Value, byte(1))
+
+    // The second half takes another cycle.
+    a.Write(0x80, FRAME_COUNTER)
+    steps(a, 3)
+    assert.Equal(t, a.Pulse1.LengthCounter.Value, byte(1))
+    steps(a, 1)
+    assert.Equal(t, a.Pulse1.LengthCounter.Value, byte(0))
+}
+
+func TestFrameIRQ(t *testing.T) {
+    a := NewAPU()
+    bus := new(testBus)
+    a.Bus = bus
+
+    steps(a, 29827)
+    assert.False(t, bus.irq)
+
+    a.Step()
+    assert.True(t, bus.irq)
+    assert.Equal(t, a.Read(STATUS) & 0x40, byte(0x40))
+    assert.False(t, bus.irq)
+
+    // It's set again on the next two cycles.
+    a.Step()
+    assert.True(t, bus.irq)
+    a.Step()
+    a.Read(STATUS)
+    a.Step()
+    assert.False(t, bus.irq)
+    assert.Equal(t, a.Read(STATUS) & 0x40, byte(0x00))
+}
+
+func TestInhibitingFrameIRQClearsIt(t *testing.T) {
+    a := NewAPU()
+    bus := new(testBus)
+    a.Bus = bus
+
+    steps(a, 29828)
+    assert.True(t, bus.irq)
+
+    a.Write(0x40, FRAME_COUNTER)
+    assert.False(t, bus.irq)
+
+    steps(a, 29830)
+    assert.False(t, bus.irq)
+}
+
+func TestFiveStepModeHasNoIRQ(t *testing.T) {
+    a := NewAPU()
+    bus := new(testBus)
+    a.Bus = bus
+
+    a.Write(0x80, FRAME_COUNTER)
+    steps(a, 37282 * 2)
+
+    assert.False(t, bus.irq)
+}
+
+func TestReadDebugDoesNotAcknowledge(t *testing.T) {
+    a := NewAPU()
+    steps(a, 29828)
+
+    assert.Equal(t, a.ReadDebug(STATUS), byte(0x40))
+    assert.Equal(t, a.Read(STATUS), byte(0x40))
+}
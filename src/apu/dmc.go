@@ -0,0 +1,178 @@
+package apu
+
+import "cpu"
+
+// Timer periods, in CPU cycles, for each of the sixteen rate settings.
+var DMCRates = map[cpu.Region][16]uint16 {
+    cpu.NTSC:  { 428, 380, 340, 320, 286, 254, 226, 214, 190, 160, 142, 128, 106, 84, 72, 54 },
+    cpu.PAL:   { 398, 354, 316, 298, 276, 236, 210, 198, 176, 148, 132, 118, 98, 78, 66, 50 },
+    cpu.Dendy: { 428, 380, 340, 320, 286, 254, 226, 214, 190, 160, 142, 128, 106, 84, 72, 54 },
+}
+
+// The delta modulation channel plays 1 bit delta encoded samples from CPU
+// memory, fetched a byte at a time by the DMA unit, nudging a 7 bit output
+// level up or down by two for each bit.
+//
+// See -- http://wiki.nesdev.com/w/index.php/APU_DMC
+type DMC struct {
+    IRQEnabled bool
+    Loop bool
+    Rate uint16
+    Level byte
+
+    SampleAddress cpu.Address
+    SampleLength uint16
+
+    // The memory reader's position in the current sample.
+    Address cpu.Address
+    Remaining uint16
+
+    // The DMC's interrupt flag, read through $4015.
+    Interrupted bool
+
+    rates [16]uint16
+    index byte
+    timer uint16
+
+    // The output unit.
+    shift byte
+    bits byte
+    silent bool
+
+    // The sample buffer, and whether a DMA is on its way to fill it.
+    buffer byte
+    full bool
+    fetching bool
+}
+
+func NewDMC() *DMC {
+    d := new(DMC)
+
+    d.rates = DMCRates[cpu.NTSC]
+    d.Rate = d.rates[0]
+    d.SampleAddress = 0xc000
+    d.SampleLength = 1
+    d.bits = 8
+    d.silent = true
+
+    return d
+}
+
+func (d *DMC) SetRegion(region cpu.Region) {
+    d.rates = DMCRates[region]
+    d.Rate = d.rates[d.index]
+}
+
+func (d *DMC) Write(val byte, register int) {
+    switch register {
+        case 0:
+            d.IRQEnabled = val & 0x80 == 0x80
+            d.Loop = val & 0x40 == 0x40
+            d.index = val & 0x0f
+            d.Rate = d.rates[d.index]
+
+            if !d.IRQEnabled {
+                d.Interrupted = false
+            }
+        case 1:
+            d.Level = val & 0x7f
+        case 2:
+            d.SampleAddress = 0xc000 + cpu.Address(val) * 64
+        case 3:
+            d.SampleLength = uint16(val) * 16 + 1
+    }
+}
+
+// Enabling the channel through $4015 restarts the sample if it had finished;
+// disabling it stops it where it is.
+func (d *DMC) SetEnabled(enabled bool) {
+    d.Interrupted = false
+
+    if !enabled {
+        d.Remaining = 0
+    } else if d.Remaining == 0 {
+        d.restart()
+    }
+}
+
+func (d *DMC) Active() bool {
+    return d.Remaining > 0
+}
+
+func (d *DMC) restart() {
+    d.Address = d.SampleAddress
+    d.Remaining = d.SampleLength
+}
+
+// Whether the memory reader wants a byte fetched, and where from.
+func (d *DMC) NeedsFetch() (cpu.Address, bool) {
+    if d.full || d.fetching || d.Remaining == 0 {
+        return 0, false
+    }
+
+    return d.Address, true
+}
+
+func (d *DMC) StartFetch() {
+    d.fetching = true
+}
+
+// Fills the sample buffer with the byte the DMA fetched. Samples wrap from
+// $FFFF to $8000, and at the end either loop or raise an IRQ.
+func (d *DMC) Fill(value byte) {
+    d.fetching = false
+    d.buffer = value
+    d.full = true
+
+    if d.Address == 0xffff {
+        d.Address = 0x8000
+    } else {
+        d.Address++
+    }
+
+    d.Remaining--
+    if d.Remaining == 0 {
+        switch {
+            case d.Loop:
+                d.restart()
+            case d.IRQEnabled:
+                d.Interrupted = true
+        }
+    }
+}
+
+// Clocked every APU cycle.
+func (d *DMC) ClockTimer() {
+    if d.timer > 0 {
+        d.timer--
+        return
+    }
+
+    d.timer = d.Rate / 2 - 1
+
+    if !d.silent {
+        if d.shift & 0x01 == 0x01 {
+            if d.Level <= 125 {
+                d.Level += 2
+            }
+        } else if d.Level >= 2 {
+            d.Level -= 2
+        }
+    }
+    d.shift >>= 1
+
+    d.bits--
+    if d.bits == 0 {
+        d.bits = 8
+
+        d.silent = !d.full
+        if d.full {
+            d.shift = d.buffer
+            d.full = false
+        }
+    }
+}
+
+func (d *DMC) Output() byte {
+    return d.Level
+}
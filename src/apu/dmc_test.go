@@ -0,0 +1,106 @@
+package apu
+
+import (
+    "cpu"
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func TestDMCRegisters(t *testing.T) {
+    d := NewDMC()
+
+    d.Write(0xcf, 0)
+    d.Write(0xff, 1)
+    d.Write(0x01, 2)
+    d.Write(0x02, 3)
+
+    assert.True(t, d.IRQEnabled)
+    assert.True(t, d.Loop)
+    assert.Equal(t, d.Rate, uint16(54))
+    assert.Equal(t, d.Level, byte(0x7f))
+    assert.Equal(t, d.SampleAddress, cpu.Address(0xc040))
+    assert.Equal(t, d.SampleLength, uint16(33))
+}
+
+func TestDMCOutputFollowsSampleBits(t *testing.T) {
+    d := NewDMC()
+    d.Write(0x40, 1)
+    d.SampleLength = 1
+    d.SetEnabled(true)
+    d.Fill(0x0f)
+
+    // The first output cycle has no sample to play, then picks it up.
+    for i := 0; i < 8; i++ {
+        d.timer = 0
+        d.ClockTimer()
+    }
+    assert.Equal(t, d.Level, byte(0x40))
+
+    for i := 0; i < 4; i++ {
+        d.timer = 0
+        d.ClockTimer()
+    }
+    assert.Equal(t, d.Level, byte(0x48))
+
+    for i := 0; i < 4; i++ {
+        d.timer = 0
+        d.ClockTimer()
+    }
+    assert.Equal(t, d.Level, byte(0x40))
+}
+
+func TestDMCAddressWrapsToBankStart(t *testing.T) {
+    d := NewDMC()
+    d.SampleAddress = 0xffff
+    d.SampleLength = 2
+    d.SetEnabled(true)
+
+    d.Fill(0x00)
+
+    assert.Equal(t, d.Address, cpu.Address(0x8000))
+}
+
+func TestDMCLoopsOrInterrupts(t *testing.T) {
+    d := NewDMC()
+    d.Write(0x80, 0)
+    d.Write(0x00, 3)
+    d.SetEnabled(true)
+
+    d.Fill(0x00)
+    assert.True(t, d.Interrupted)
+    assert.False(t, d.Active())
+
+    d.Write(0x40, 0)
+    assert.False(t, d.Interrupted)
+
+    d.SetEnabled(true)
+    d.Fill(0x00)
+    assert.True(t, d.Active())
+    assert.Equal(t, d.Address, cpu.Address(0xc000))
+}
+
+func TestAPUFetchesSamplesThroughDMA(t *testing.T) {
+    a := NewAPU()
+    bus := new(testBus)
+    a.Bus = bus
+
+    var fetched []cpu.Address
+    a.DMA = func(location cpu.Address, done func(byte)) {
+        fetched = append(fetched, location)
+        done(0xff)
+    }
+
+    a.Write(0x8f, DMC_REGISTERS)
+    a.Write(0x10, DMC_REGISTERS + 2)
+    a.Write(0x00, DMC_REGISTERS + 3)
+    a.Write(0x10, STATUS)
+
+    a.Step()
+
+    assert.Equal(t, fetched, []cpu.Address { 0xc400 })
+    assert.True(t, bus.irq)
+    assert.Equal(t, a.Read(STATUS) & 0x90, byte(0x80))
+
+    a.Write(0x00, STATUS)
+    assert.False(t, bus.irq)
+}
@@ -0,0 +1,45 @@
+package apu
+
+// Sound hardware on a cartridge, like the VRC6's or the FDS's, is mixed in
+// with the APU's own channels through the cartridge connector. The APU clocks
+// it every CPU cycle along with everything else.
+type Expansion interface {
+    Step()
+
+    // The expansion's current output, from 0 to 1.
+    Output() float64
+
+    // How loud the expansion is relative to the APU, i.e. what its full
+    // output adds to the mix, where the APU's own channels together reach
+    // just under 1. This differs a lot between chips and even between
+    // consoles.
+    Level() float64
+}
+
+// More than one expansion at once, as NSFs can ask for. Each is mixed in at
+// its own level.
+type Mix []Expansion
+
+func (m Mix) Step() {
+    for _, e := range m {
+        e.Step()
+    }
+}
+
+func (m Mix) Output() float64 {
+    output := 0.0
+    for _, e := range m {
+        output += e.Output() * e.Level()
+    }
+
+    return output / m.Level()
+}
+
+func (m Mix) Level() float64 {
+    level := 0.0
+    for _, e := range m {
+        level += e.Level()
+    }
+
+    return level
+}
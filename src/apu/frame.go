@@ -0,0 +1,124 @@
+package apu
+
+import "cpu"
+
+// What the frame counter clocks on a given cycle.
+const (
+    NO_FRAME = iota
+    QUARTER_FRAME
+    HALF_FRAME
+)
+
+// The CPU cycles, counted from when the sequence starts, at which each step
+// of the four and five step sequences happen. Each sequence starts over on
+// the cycle after its last step.
+type Steps struct {
+    Four [4]int
+    Five [5]int
+}
+
+var FrameSteps = map[cpu.Region]Steps {
+    cpu.NTSC:  Steps { [4]int { 7457, 14913, 22371, 29829 }, [5]int { 7457, 14913, 22371, 29829, 37281 } },
+    cpu.PAL:   Steps { [4]int { 8313, 16627, 24939, 33253 }, [5]int { 8313, 16627, 24939, 33253, 41565 } },
+    cpu.Dendy: Steps { [4]int { 7457, 14913, 22371, 29829 }, [5]int { 7457, 14913, 22371, 29829, 37281 } },
+}
+
+// The frame counter's sequencer, which clocks the channels' envelopes, sweeps
+// and length counters, and in the four step mode raises an IRQ at the end of
+// every sequence unless inhibited.
+type FrameCounter struct {
+    FiveStep bool
+    InhibitIRQ bool
+
+    // The frame interrupt flag, read through $4015.
+    Interrupted bool
+
+    steps Steps
+    cycle int
+
+    // The last value written to $4017, which only takes effect a few cycles
+    // after it's written.
+    value byte
+    delay int
+}
+
+// Runs one CPU cycle, returning what should be clocked.
+func (f *FrameCounter) Step() int {
+    if f.delay > 0 {
+        f.delay--
+
+        if f.delay == 0 {
+            return f.restart()
+        }
+    }
+
+    sequence := f.steps.Four[:]
+    if f.FiveStep {
+        sequence = f.steps.Five[:]
+    }
+    last := sequence[len(sequence) - 1]
+
+    f.cycle++
+    wrapped := f.cycle > last
+    if wrapped {
+        f.cycle = 0
+    }
+
+    // The flag is set on the last two cycles of the sequence and the first
+    // of the next.
+    if !f.FiveStep && !f.InhibitIRQ && (f.cycle >= last - 1 || wrapped) {
+        f.Interrupted = true
+    }
+
+    for i, at := range sequence {
+        if f.cycle != at {
+            continue
+        }
+
+        // The five step sequence's fourth step does nothing.
+        switch {
+            case f.FiveStep && i == 3:
+                return NO_FRAME
+            case i == 1 || i == len(sequence) - 1:
+                return HALF_FRAME
+            default:
+                return QUARTER_FRAME
+        }
+    }
+
+    return NO_FRAME
+}
+
+// Writing $4017 sets the IRQ inhibit flag, and clears the interrupt,
+// straight away, but only restarts the sequence 3 or 4 cycles later
+// depending on whether the write lands on an APU cycle. odd is whether the
+// write happens on the second half of an APU cycle.
+//
+// See -- http://wiki.nesdev.com/w/index.php/APU_Frame_Counter
+func (f *FrameCounter) Write(val byte, odd bool) {
+    f.value = val
+
+    f.InhibitIRQ = val & 0x40 == 0x40
+    if f.InhibitIRQ {
+        f.Interrupted = false
+    }
+
+    if odd {
+        f.delay = 4
+    } else {
+        f.delay = 3
+    }
+}
+
+// Restarts the sequence with the last value written to $4017. Selecting the
+// five step sequence clocks everything straight away.
+func (f *FrameCounter) restart() int {
+    f.FiveStep = f.value & 0x80 == 0x80
+    f.cycle = 0
+
+    if f.FiveStep {
+        return HALF_FRAME
+    }
+
+    return NO_FRAME
+}
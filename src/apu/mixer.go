@@ -0,0 +1,56 @@
+package apu
+
+// The APU mixes its channels through two resistor networks, one for the
+// pulses and one for the triangle, noise and DMC, whose outputs aren't
+// linear in their inputs. The tables hold the output of each network for
+// every combination of inputs that matters.
+//
+// See -- http://wiki.nesdev.com/w/index.php/APU_Mixer
+var pulseTable [31]float64
+var tndTable [203]float64
+
+func init() {
+    for i := 1; i < len(pulseTable); i++ {
+        pulseTable[i] = 95.52 / (8128.0 / float64(i) + 100)
+    }
+
+    for i := 1; i < len(tndTable); i++ {
+        tndTable[i] = 163.67 / (24329.0 / float64(i) + 100)
+    }
+}
+
+// The mixed output of every channel, from 0 to just under 1, plus whatever
+// the cartridge adds.
+func (a *APU) Output() float64 {
+    p1 := a.Pulse1.Output()
+    p2 := a.Pulse2.Output()
+    t := a.Triangle.Output()
+    n := a.Noise.Output()
+    d := a.DMC.Output()
+
+    return pulseTable[p1 + p2] + tndTable[3 * int(t) + 2 * int(n) + int(d)] + a.expansion()
+}
+
+func (a *APU) expansion() float64 {
+    if a.Expansion == nil {
+        return 0
+    }
+
+    return a.Expansion.Output() * a.Expansion.Level()
+}
+
+var StemNames = []string { "pulse1", "pulse2", "triangle", "noise", "dmc", "expansion" }
+
+// Each channel's output on its own, as it would come out of the mixer with
+// every other channel silent. The mixer isn't linear, so they don't quite
+// add up to Output.
+func (a *APU) Stems() []float64 {
+    return []float64 {
+        pulseTable[a.Pulse1.Output()],
+        pulseTable[a.Pulse2.Output()],
+        tndTable[3 * int(a.Triangle.Output())],
+        tndTable[2 * int(a.Noise.Output())],
+        tndTable[a.DMC.Output()],
+        a.expansion(),
+    }
+}
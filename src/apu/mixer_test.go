@@ -0,0 +1,62 @@
+package apu
+
+import (
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func TestSilentAPUOutputsTheTriangleLevel(t *testing.T) {
+    a := NewAPU()
+
+    // The triangle powers on holding the top of its sequence.
+    assert.Equal(t, a.Output(), tndTable[3 * 15])
+
+    a.Triangle.step = 15
+    assert.Equal(t, a.Output(), 0.0)
+}
+
+func TestMixerIsNonlinear(t *testing.T) {
+    assert.True(t, pulseTable[30] < 2 * pulseTable[15])
+    assert.True(t, pulseTable[30] > 0.25 && pulseTable[30] < 0.26)
+}
+
+func TestStemsMatchOutputForOneChannel(t *testing.T) {
+    a := NewAPU()
+    a.Triangle.step = 15
+    a.DMC.Level = 0x40
+
+    assert.Equal(t, a.Stems()[4], a.Output())
+    assert.Equal(t, len(a.Stems()), len(StemNames))
+}
+
+type square struct {
+    steps int
+}
+
+func (s *square) Step() {
+    s.steps++
+}
+
+func (s *square) Output() float64 {
+    return float64(s.steps % 2)
+}
+
+func (s *square) Level() float64 {
+    return 0.5
+}
+
+func TestExpansionIsClockedAndMixedIn(t *testing.T) {
+    a := NewAPU()
+    a.Triangle.step = 15
+
+    expansion := new(square)
+    a.Expansion = expansion
+
+    a.Step()
+    assert.Equal(t, expansion.steps, 1)
+    assert.Equal(t, a.Output(), 0.5)
+    assert.Equal(t, a.Stems()[5], 0.5)
+
+    a.Step()
+    assert.Equal(t, a.Output(), 0.0)
+}
@@ -0,0 +1,129 @@
+package apu
+
+import "cpu"
+
+// The MMC5's sound: two more pulse channels, like the APU's but without
+// sweeps, and an 8 bit PCM channel written directly. Their envelopes and
+// length counters are clocked at a steady 240Hz by a timer of the MMC5's
+// own. Along with the sound come the MMC5's 1K of ExRAM and its multiplier,
+// which NSF drivers for it can rely on too.
+//
+// See -- http://wiki.nesdev.com/w/index.php/MMC5_audio
+type MMC5 struct {
+    Pulse1 *Pulse
+    Pulse2 *Pulse
+
+    // The PCM channel's level, and whether it's read from memory instead
+    // of written. Reading isn't supported, so it's silent in that mode.
+    PCM byte
+    ReadMode bool
+
+    ExRAM [0x400]byte
+
+    // The multiplier's operands.
+    Multiplicand byte
+    Multiplier byte
+
+    cycle int
+    timer int
+}
+
+// CPU cycles between the clocks of the envelopes and length counters.
+const MMC5_FRAME_PERIOD = 7457
+
+func NewMMC5() *MMC5 {
+    m := new(MMC5)
+
+    m.Pulse1 = NewPulse(1)
+    m.Pulse2 = NewPulse(2)
+    m.Multiplicand = 0xff
+    m.Multiplier = 0xff
+
+    return m
+}
+
+func (m *MMC5) Write(val byte, location cpu.Address) {
+    switch {
+        case location >= 0x5000 && location <= 0x5003:
+            m.Pulse1.Write(val, int(location - 0x5000))
+        case location >= 0x5004 && location <= 0x5007:
+            m.Pulse2.Write(val, int(location - 0x5004))
+        case location == 0x5010:
+            m.ReadMode = val & 0x01 == 0x01
+        case location == 0x5011:
+            // Writing 0 does nothing in write mode.
+            if !m.ReadMode && val != 0x00 {
+                m.PCM = val
+            }
+        case location == 0x5015:
+            m.Pulse1.LengthCounter.SetEnabled(val & 0x01 == 0x01)
+            m.Pulse2.LengthCounter.SetEnabled(val & 0x02 == 0x02)
+        case location == 0x5205:
+            m.Multiplicand = val
+        case location == 0x5206:
+            m.Multiplier = val
+        case location >= 0x5c00 && location <= 0x5fff:
+            m.ExRAM[location - 0x5c00] = val
+    }
+}
+
+func (m *MMC5) Read(location cpu.Address) byte {
+    product := uint16(m.Multiplicand) * uint16(m.Multiplier)
+
+    switch {
+        case location == 0x5015:
+            var status = byte(0x00)
+            if m.Pulse1.LengthCounter.Active() { status |= 0x01 }
+            if m.Pulse2.LengthCounter.Active() { status |= 0x02 }
+            return status
+        case location == 0x5205:
+            return byte(product)
+        case location == 0x5206:
+            return byte(product >> 8)
+        case location >= 0x5c00 && location <= 0x5fff:
+            return m.ExRAM[location - 0x5c00]
+    }
+
+    return 0x00
+}
+
+// The pulses' timers run at the APU's rate, every other CPU cycle.
+func (m *MMC5) Step() {
+    if m.cycle & 0x01 == 0x01 {
+        m.Pulse1.ClockTimer()
+        m.Pulse2.ClockTimer()
+    }
+    m.cycle++
+
+    m.timer++
+    if m.timer >= MMC5_FRAME_PERIOD {
+        m.timer = 0
+
+        for _, p := range []*Pulse { m.Pulse1, m.Pulse2 } {
+            p.Envelope.Clock()
+            p.LengthCounter.Clock()
+        }
+    }
+}
+
+// Without a sweep unit, short periods don't mute the MMC5's pulses.
+func (m *MMC5) pulseOutput(p *Pulse) byte {
+    if !p.LengthCounter.Active() || DutyTable[p.Duty][p.step] == 0 {
+        return 0
+    }
+
+    return p.Envelope.Output()
+}
+
+// The pulses go through a network like the APU's, and the PCM channel is
+// about as loud as the DMC at the same level.
+func (m *MMC5) Output() float64 {
+    pulses := pulseTable[m.pulseOutput(m.Pulse1) + m.pulseOutput(m.Pulse2)]
+    pcm := tndTable[m.PCM >> 1]
+
+    return (pulses + pcm) / m.Level()
+}
+
+func (m *MMC5) Level() float64 {
+    return pulseTable[30] + tndTable[0x7f]
+}
@@ -0,0 +1,53 @@
+package apu
+
+import (
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func TestMMC5PulsesIgnoreShortPeriods(t *testing.T) {
+    m := NewMMC5()
+    m.Write(0x03, 0x5015)
+    m.Write(0xbf, 0x5000)
+    m.Write(0x02, 0x5002)
+    m.Write(0x08, 0x5003)
+
+    outputs := []byte {}
+    for i := 0; i < 48; i++ {
+        m.Step()
+        outputs = append(outputs, m.pulseOutput(m.Pulse1))
+    }
+
+    assert.Equal(t, m.Read(0x5015), byte(0x01))
+    assert.Contains(t, outputs, byte(15))
+    assert.Contains(t, outputs, byte(0))
+}
+
+func TestMMC5LengthCountersRunAt240Hz(t *testing.T) {
+    m := NewMMC5()
+    m.Write(0x01, 0x5015)
+    m.Write(0x18, 0x5003) // A length of 2.
+
+    for i := 0; i < MMC5_FRAME_PERIOD * 2; i++ {
+        m.Step()
+    }
+
+    assert.Equal(t, m.Read(0x5015), byte(0x00))
+}
+
+func TestMMC5Multiplies(t *testing.T) {
+    m := NewMMC5()
+    m.Write(200, 0x5205)
+    m.Write(100, 0x5206)
+
+    assert.Equal(t, m.Read(0x5205), byte(20000 & 0xff))
+    assert.Equal(t, m.Read(0x5206), byte(20000 >> 8))
+}
+
+func TestMMC5PCMIgnoresZero(t *testing.T) {
+    m := NewMMC5()
+    m.Write(0x40, 0x5011)
+    m.Write(0x00, 0x5011)
+
+    assert.Equal(t, m.PCM, byte(0x40))
+}
@@ -0,0 +1,119 @@
+package apu
+
+import "cpu"
+
+// Namco's 163 plays up to eight wavetable channels out of 128 bytes of RAM,
+// which hold both the channels' registers, from $40 up, and their 4 bit
+// samples. It updates one channel every 15 CPU cycles, so the more channels
+// are enabled, the lower they all play. Writing $F800 sets the RAM address,
+// with bit 7 to step it after every access, and $4800 reads and writes it.
+//
+// See -- http://wiki.nesdev.com/w/index.php/Namco_163_audio
+type N163 struct {
+    RAM [0x80]byte
+
+    Address byte
+    AutoIncrement bool
+
+    // Each channel's last output, from -120 to 105, with channel 0 the one
+    // whose registers are at $78-$7F.
+    outputs [8]int
+
+    channel int
+    timer int
+}
+
+// CPU cycles spent on each channel update.
+const N163_UPDATE_PERIOD = 15
+
+func NewN163() *N163 {
+    return new(N163)
+}
+
+func (n *N163) Write(val byte, location cpu.Address) {
+    switch location & 0xf800 {
+        case 0x4800:
+            n.RAM[n.Address] = val
+            n.step()
+        case 0xf800:
+            n.Address = val & 0x7f
+            n.AutoIncrement = val & 0x80 == 0x80
+    }
+}
+
+func (n *N163) Read(location cpu.Address) byte {
+    if location & 0xf800 != 0x4800 {
+        return 0x00
+    }
+
+    val := n.RAM[n.Address]
+    n.step()
+
+    return val
+}
+
+func (n *N163) step() {
+    if n.AutoIncrement {
+        n.Address = (n.Address + 1) & 0x7f
+    }
+}
+
+// How many channels are enabled, from 1 to 8.
+func (n *N163) Channels() int {
+    return int(n.RAM[0x7f] >> 4) & 0x07 + 1
+}
+
+func (n *N163) Step() {
+    n.timer++
+    if n.timer < N163_UPDATE_PERIOD {
+        return
+    }
+    n.timer = 0
+
+    channels := n.Channels()
+    if n.channel >= channels {
+        n.channel = 0
+    }
+
+    n.update(n.channel)
+    n.channel++
+}
+
+// Advances a channel's 24 bit phase by its frequency, wrapping at its
+// length, and outputs the sample it lands on.
+func (n *N163) update(channel int) {
+    r := n.RAM[0x78 - channel * 8:0x80 - channel * 8]
+
+    frequency := int(r[0]) | int(r[2]) << 8 | int(r[4] & 0x03) << 16
+    phase := int(r[1]) | int(r[3]) << 8 | int(r[5]) << 16
+    length := (256 - int(r[4] & 0xfc)) << 16
+
+    phase = (phase + frequency) % length
+    r[1], r[3], r[5] = byte(phase), byte(phase >> 8), byte(phase >> 16)
+
+    address := (int(r[6]) + phase >> 16) & 0xff
+    sample := n.RAM[address >> 1 & 0x7f]
+    if address & 0x01 == 0x01 {
+        sample >>= 4
+    }
+
+    n.outputs[channel] = (int(sample & 0x0f) - 8) * int(r[7] & 0x0f)
+}
+
+// The channels take turns at the output, which comes out as their average.
+func (n *N163) Output() float64 {
+    channels := n.Channels()
+
+    sum := 0
+    for _, output := range n.outputs[:channels] {
+        sum += output
+    }
+
+    return (float64(sum) / float64(channels) + 120) / 240
+}
+
+// Boards mix the 163 in at very different levels; this has a channel at
+// full volume about as loud as both APU pulses at full volume.
+func (n *N163) Level() float64 {
+    return pulseTable[30]
+}
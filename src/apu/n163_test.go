@@ -0,0 +1,54 @@
+package apu
+
+import (
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func TestN163AddressAutoIncrements(t *testing.T) {
+    n := NewN163()
+    n.Write(0x80 | 0x7e, 0xf800)
+    n.Write(0x11, 0x4800)
+    n.Write(0x22, 0x4800)
+    n.Write(0x33, 0x4800)
+
+    assert.Equal(t, n.RAM[0x7e], byte(0x11))
+    assert.Equal(t, n.RAM[0x7f], byte(0x22))
+    assert.Equal(t, n.RAM[0x00], byte(0x33))
+
+    n.Write(0x7f, 0xf800)
+    assert.Equal(t, n.Read(0x4800), byte(0x22))
+    assert.Equal(t, n.Read(0x4800), byte(0x22))
+}
+
+func TestN163PlaysItsWavetable(t *testing.T) {
+    n := NewN163()
+
+    // Four samples, 0 F 0 F, at address 0, and one channel stepping a
+    // sample per update at full volume.
+    n.RAM[0x00], n.RAM[0x01] = 0xf0, 0xf0
+    n.RAM[0x78] = 0x00
+    n.RAM[0x7a] = 0x00
+    n.RAM[0x7c] = 0xfc | 0x01
+    n.RAM[0x7e] = 0x00
+    n.RAM[0x7f] = 0x0f
+
+    outputs := []int {}
+    for i := 0; i < 4 * N163_UPDATE_PERIOD; i++ {
+        n.Step()
+        if n.timer == 0 {
+            outputs = append(outputs, n.outputs[0])
+        }
+    }
+
+    assert.Equal(t, outputs, []int { 7 * 15, -8 * 15, 7 * 15, -8 * 15 })
+}
+
+func TestMixWeighsEachExpansionByItsLevel(t *testing.T) {
+    n := NewN163()
+    v := NewVRC6()
+    mix := Mix { n, v }
+
+    assert.InDelta(t, mix.Level(), n.Level() + v.Level(), 1e-9)
+    assert.InDelta(t, mix.Output() * mix.Level(), n.Output() * n.Level(), 1e-9)
+}
@@ -0,0 +1,84 @@
+package apu
+
+import "cpu"
+
+// Timer periods, in CPU cycles, for each of the sixteen period settings.
+var NoisePeriods = map[cpu.Region][16]uint16 {
+    cpu.NTSC:  { 4, 8, 16, 32, 64, 96, 128, 160, 202, 254, 380, 508, 762, 1016, 2034, 4068 },
+    cpu.PAL:   { 4, 8, 14, 30, 60, 88, 118, 148, 188, 236, 354, 472, 708, 944, 1890, 3778 },
+    cpu.Dendy: { 4, 8, 16, 32, 64, 96, 128, 160, 202, 254, 380, 508, 762, 1016, 2034, 4068 },
+}
+
+// Pseudo-random noise from a 15 bit linear feedback shift register. In short
+// mode the feedback comes from bit 6 rather than bit 1, which makes for a
+// sequence just 93 (or 31) steps long, and a metallic tone.
+type Noise struct {
+    Short bool
+    Period uint16
+
+    LengthCounter
+    Envelope
+
+    periods [16]uint16
+    index byte
+    timer uint16
+    shift uint16
+}
+
+func NewNoise() *Noise {
+    n := new(Noise)
+
+    n.shift = 0x0001
+    n.periods = NoisePeriods[cpu.NTSC]
+    n.Period = n.periods[0]
+
+    return n
+}
+
+func (n *Noise) SetRegion(region cpu.Region) {
+    n.periods = NoisePeriods[region]
+    n.Period = n.periods[n.index]
+}
+
+func (n *Noise) Write(val byte, register int) {
+    switch register {
+        case 0:
+            n.LengthCounter.Halt = val & 0x20 == 0x20
+            n.Envelope.Loop = val & 0x20 == 0x20
+            n.Envelope.Constant = val & 0x10 == 0x10
+            n.Envelope.Volume = val & 0x0f
+        case 2:
+            n.Short = val & 0x80 == 0x80
+            n.index = val & 0x0f
+            n.Period = n.periods[n.index]
+        case 3:
+            n.LengthCounter.Load(val >> 3)
+            n.Envelope.Start = true
+    }
+}
+
+// Clocked every APU cycle, so the timer counts down in pairs of CPU cycles.
+func (n *Noise) ClockTimer() {
+    if n.timer > 0 {
+        n.timer--
+        return
+    }
+
+    n.timer = n.Period / 2 - 1
+
+    tap := uint(1)
+    if n.Short {
+        tap = 6
+    }
+
+    feedback := (n.shift ^ (n.shift >> tap)) & 0x01
+    n.shift = (n.shift >> 1) | feedback << 14
+}
+
+func (n *Noise) Output() byte {
+    if !n.LengthCounter.Active() || n.shift & 0x01 == 0x01 {
+        return 0
+    }
+
+    return n.Envelope.Output()
+}
@@ -0,0 +1,50 @@
+package apu
+
+import (
+    "cpu"
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func TestNoisePeriodsFollowRegion(t *testing.T) {
+    n := NewNoise()
+    n.Write(0x02, 2)
+    assert.Equal(t, n.Period, uint16(16))
+
+    n.SetRegion(cpu.PAL)
+    assert.Equal(t, n.Period, uint16(14))
+}
+
+func sequenceLength(short bool) int {
+    n := NewNoise()
+    n.Short = short
+
+    start := n.shift
+    for i := 1; i < 0x8000; i++ {
+        n.timer = 0
+        n.ClockTimer()
+
+        if n.shift == start {
+            return i
+        }
+    }
+
+    return 0
+}
+
+func TestNoiseSequenceLengths(t *testing.T) {
+    assert.Equal(t, sequenceLength(false), 32767)
+    assert.Equal(t, sequenceLength(true), 93)
+}
+
+func TestNoiseIsSilentWhenBitZeroIsSet(t *testing.T) {
+    n := NewNoise()
+    n.LengthCounter.SetEnabled(true)
+    n.Write(0x1f, 0)
+    n.Write(0x08, 3)
+
+    assert.Equal(t, n.Output(), byte(0))
+
+    n.shift = 0x0002
+    assert.Equal(t, n.Output(), byte(0x0f))
+}
@@ -0,0 +1,122 @@
+package apu
+
+var DutyTable = [4][8]byte {
+    { 0, 1, 0, 0, 0, 0, 0, 0 },
+    { 0, 1, 1, 0, 0, 0, 0, 0 },
+    { 0, 1, 1, 1, 1, 0, 0, 0 },
+    { 1, 0, 0, 1, 1, 1, 1, 1 },
+}
+
+// Adjusts a pulse channel's period up or down every few half frames.
+type Sweep struct {
+    Enabled bool
+    Period byte
+    Negate bool
+    Shift byte
+    Reload bool
+
+    divider byte
+}
+
+type Pulse struct {
+    // Pulse 1 negates its sweep with one's complement, pulse 2 with two's
+    // complement, so the same shift sweeps them down by different amounts.
+    Channel int
+
+    Duty byte
+    Period uint16
+
+    LengthCounter
+    Envelope
+    Sweep
+
+    timer uint16
+    step byte
+}
+
+func NewPulse(channel int) *Pulse {
+    p := new(Pulse)
+    p.Channel = channel
+
+    return p
+}
+
+func (p *Pulse) Write(val byte, register int) {
+    switch register {
+        case 0:
+            p.Duty = val >> 6
+            p.LengthCounter.Halt = val & 0x20 == 0x20
+            p.Envelope.Loop = val & 0x20 == 0x20
+            p.Envelope.Constant = val & 0x10 == 0x10
+            p.Envelope.Volume = val & 0x0f
+        case 1:
+            p.Sweep.Enabled = val & 0x80 == 0x80
+            p.Sweep.Period = (val >> 4) & 0x07
+            p.Sweep.Negate = val & 0x08 == 0x08
+            p.Sweep.Shift = val & 0x07
+            p.Sweep.Reload = true
+        case 2:
+            p.Period = (p.Period & 0x0700) | uint16(val)
+        case 3:
+            p.Period = (p.Period & 0x00ff) | uint16(val & 0x07) << 8
+            p.LengthCounter.Load(val >> 3)
+            p.Envelope.Start = true
+            p.step = 0
+    }
+}
+
+// Clocked every APU cycle, i.e. every other CPU cycle.
+func (p *Pulse) ClockTimer() {
+    if p.timer == 0 {
+        p.timer = p.Period
+        p.step = (p.step + 1) & 0x07
+    } else {
+        p.timer--
+    }
+}
+
+func (p *Pulse) targetPeriod() uint16 {
+    change := p.Period >> p.Sweep.Shift
+
+    if !p.Sweep.Negate {
+        return p.Period + change
+    }
+
+    if p.Channel == 1 {
+        change++
+    }
+    if change > p.Period {
+        return 0
+    }
+
+    return p.Period - change
+}
+
+// The sweep unit mutes the channel whenever the period is too short, or its
+// target too long, even when it isn't enabled.
+func (p *Pulse) muted() bool {
+    return p.Period < 8 || p.targetPeriod() > 0x7ff
+}
+
+func (p *Pulse) ClockSweep() {
+    s := &p.Sweep
+
+    if s.divider == 0 && s.Enabled && s.Shift > 0 && !p.muted() {
+        p.Period = p.targetPeriod()
+    }
+
+    if s.divider == 0 || s.Reload {
+        s.divider = s.Period
+        s.Reload = false
+    } else {
+        s.divider--
+    }
+}
+
+func (p *Pulse) Output() byte {
+    if !p.LengthCounter.Active() || p.muted() || DutyTable[p.Duty][p.step] == 0 {
+        return 0
+    }
+
+    return p.Envelope.Output()
+}
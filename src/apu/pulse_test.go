@@ -0,0 +1,116 @@
+package apu
+
+import (
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func TestPulseRegistersSetPeriodAndDuty(t *testing.T) {
+    p := NewPulse(1)
+    p.LengthCounter.SetEnabled(true)
+
+    p.Write(0xbf, 0)
+    p.Write(0x34, 2)
+    p.Write(0x52, 3)
+
+    assert.Equal(t, p.Duty, byte(0x02))
+    assert.Equal(t, p.Period, uint16(0x0234))
+    assert.Equal(t, p.LengthCounter.Value, byte(60))
+    assert.True(t, p.LengthCounter.Halt)
+    assert.True(t, p.Envelope.Constant)
+}
+
+func TestPulseLengthOnlyLoadsWhenEnabled(t *testing.T) {
+    p := NewPulse(1)
+
+    p.Write(0x08, 3)
+
+    assert.Equal(t, p.LengthCounter.Value, byte(0))
+}
+
+func TestPulseSweepNegatesDifferentlyPerChannel(t *testing.T) {
+    p1 := NewPulse(1)
+    p2 := NewPulse(2)
+
+    for _, p := range []*Pulse { p1, p2 } {
+        p.Period = 0x100
+        p.Write(0x89, 1)
+    }
+
+    assert.Equal(t, p1.targetPeriod(), uint16(0x100 - 0x80 - 1))
+    assert.Equal(t, p2.targetPeriod(), uint16(0x100 - 0x80))
+}
+
+func TestPulseSweepUpdatesPeriod(t *testing.T) {
+    p := NewPulse(2)
+    p.Period = 0x100
+    p.Write(0x82, 1)
+
+    p.ClockSweep()
+
+    assert.Equal(t, p.Period, uint16(0x140))
+}
+
+func TestPulseSweepMutesOutOfRangePeriods(t *testing.T) {
+    p := NewPulse(1)
+    p.LengthCounter.SetEnabled(true)
+    p.Write(0xdf, 0)
+    p.Write(0x08, 3)
+    p.step = 3
+
+    p.Period = 0x0007
+    assert.Equal(t, p.Output(), byte(0))
+
+    // Muted by the target period even with the sweep disabled.
+    p.Period = 0x0600
+    p.Write(0x01, 1)
+    assert.Equal(t, p.Output(), byte(0))
+
+    p.Write(0x08, 1)
+    assert.Equal(t, p.Output(), byte(0x0f))
+}
+
+func TestPulseTimerStepsTheDuty(t *testing.T) {
+    p := NewPulse(1)
+    p.LengthCounter.SetEnabled(true)
+    p.Write(0x1f, 0)
+    p.Write(0x08, 2)
+    p.Write(0x08, 3)
+
+    assert.Equal(t, p.Output(), byte(0))
+
+    p.ClockTimer()
+    assert.Equal(t, p.Output(), byte(0x0f))
+
+    // Each step lasts the period plus one clocks.
+    for i := 0; i < 8; i++ {
+        p.ClockTimer()
+    }
+    assert.Equal(t, p.Output(), byte(0x0f))
+
+    p.ClockTimer()
+    assert.Equal(t, p.Output(), byte(0))
+}
+
+func TestEnvelopeDecays(t *testing.T) {
+    e := Envelope { Start: true, Volume: 1 }
+
+    e.Clock()
+    assert.Equal(t, e.Output(), byte(15))
+
+    e.Clock()
+    e.Clock()
+    assert.Equal(t, e.Output(), byte(14))
+}
+
+func TestEnvelopeLoops(t *testing.T) {
+    e := Envelope { Start: true, Loop: true }
+
+    for i := 0; i < 16; i++ {
+        e.Clock()
+    }
+    assert.Equal(t, e.Output(), byte(0))
+
+    e.Clock()
+    assert.Equal(t, e.Output(), byte(15))
+}
@@ -0,0 +1,133 @@
+package apu
+
+import "state"
+
+const STATE_VERSION = 1
+
+// Saves or loads every channel, the frame counter, and where the APU is in
+// its cycle. Expansion sound belongs to the cartridge.
+func (a *APU) State(s state.Stream) {
+    s.Int(&a.cycle)
+
+    a.Pulse1.state(s)
+    a.Pulse2.state(s)
+    a.Triangle.state(s)
+    a.Noise.state(s)
+    a.DMC.state(s)
+
+    f := &a.FrameCounter
+    s.Bool(&f.FiveStep)
+    s.Bool(&f.InhibitIRQ)
+    s.Bool(&f.Interrupted)
+    s.Int(&f.cycle)
+    s.Byte(&f.value)
+    s.Int(&f.delay)
+}
+
+func (l *LengthCounter) state(s state.Stream) {
+    s.Bool(&l.Enabled)
+    s.Bool(&l.Halt)
+    s.Byte(&l.Value)
+}
+
+func (e *Envelope) state(s state.Stream) {
+    s.Bool(&e.Start)
+    s.Bool(&e.Loop)
+    s.Bool(&e.Constant)
+    s.Byte(&e.Volume)
+    s.Byte(&e.divider)
+    s.Byte(&e.decay)
+}
+
+func (p *Pulse) state(s state.Stream) {
+    s.Byte(&p.Duty)
+    s.Uint16(&p.Period)
+    p.LengthCounter.state(s)
+    p.Envelope.state(s)
+
+    s.Bool(&p.Sweep.Enabled)
+    s.Byte(&p.Sweep.Period)
+    s.Bool(&p.Sweep.Negate)
+    s.Byte(&p.Sweep.Shift)
+    s.Bool(&p.Sweep.Reload)
+    s.Byte(&p.Sweep.divider)
+
+    s.Uint16(&p.timer)
+    s.Byte(&p.step)
+}
+
+func (t *Triangle) state(s state.Stream) {
+    s.Uint16(&t.Period)
+    t.LengthCounter.state(s)
+
+    s.Bool(&t.LinearCounter.Control)
+    s.Bool(&t.LinearCounter.Reload)
+    s.Byte(&t.LinearCounter.ReloadValue)
+    s.Byte(&t.LinearCounter.Value)
+
+    s.Uint16(&t.timer)
+    s.Byte(&t.step)
+}
+
+func (n *Noise) state(s state.Stream) {
+    s.Bool(&n.Short)
+    s.Byte(&n.index)
+    n.LengthCounter.state(s)
+    n.Envelope.state(s)
+
+    s.Uint16(&n.timer)
+    s.Uint16(&n.shift)
+
+    if s.Loading() {
+        n.Period = n.periods[n.index]
+    }
+}
+
+func (d *DMC) state(s state.Stream) {
+    s.Bool(&d.IRQEnabled)
+    s.Bool(&d.Loop)
+    s.Byte(&d.index)
+    s.Byte(&d.Level)
+    s.Uint16((*uint16)(&d.SampleAddress))
+    s.Uint16(&d.SampleLength)
+    s.Uint16((*uint16)(&d.Address))
+    s.Uint16(&d.Remaining)
+    s.Bool(&d.Interrupted)
+
+    s.Uint16(&d.timer)
+    s.Byte(&d.shift)
+    s.Byte(&d.bits)
+    s.Bool(&d.silent)
+    s.Byte(&d.buffer)
+    s.Bool(&d.full)
+    s.Bool(&d.fetching)
+
+    if s.Loading() {
+        d.Rate = d.rates[d.index]
+    }
+}
+
+// Saves or loads all three channels, for mappers with the VRC6 on board.
+func (v *VRC6) State(s state.Stream) {
+    s.Bool(&v.Halt)
+    shift := uint16(v.shift)
+    s.Uint16(&shift)
+    v.shift = uint(shift)
+
+    for _, p := range []*VRC6Pulse { v.Pulse1, v.Pulse2 } {
+        s.Bool(&p.Mode)
+        s.Byte(&p.Duty)
+        s.Byte(&p.Volume)
+        s.Uint16(&p.Period)
+        s.Bool(&p.Enabled)
+        s.Uint16(&p.timer)
+        s.Byte(&p.step)
+    }
+
+    s.Byte(&v.Saw.Rate)
+    s.Uint16(&v.Saw.Period)
+    s.Bool(&v.Saw.Enabled)
+    s.Uint16(&v.Saw.timer)
+    s.Byte(&v.Saw.step)
+    s.Byte(&v.Saw.accumulator)
+}
@@ -0,0 +1,76 @@
+package apu
+
+var TriangleTable = [32]byte {
+    15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0,
+    0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+}
+
+// Gates the triangle channel with finer resolution than the length counter.
+// Clocked on quarter frames.
+type LinearCounter struct {
+    Control bool
+    Reload bool
+    ReloadValue byte
+    Value byte
+}
+
+func (l *LinearCounter) Clock() {
+    if l.Reload {
+        l.Value = l.ReloadValue
+    } else if l.Value > 0 {
+        l.Value--
+    }
+
+    if !l.Control {
+        l.Reload = false
+    }
+}
+
+type Triangle struct {
+    Period uint16
+
+    LengthCounter
+    LinearCounter
+
+    timer uint16
+    step byte
+}
+
+func NewTriangle() *Triangle {
+    return new(Triangle)
+}
+
+func (t *Triangle) Write(val byte, register int) {
+    switch register {
+        case 0:
+            t.LinearCounter.Control = val & 0x80 == 0x80
+            t.LengthCounter.Halt = val & 0x80 == 0x80
+            t.LinearCounter.ReloadValue = val & 0x7f
+        case 2:
+            t.Period = (t.Period & 0x0700) | uint16(val)
+        case 3:
+            t.Period = (t.Period & 0x00ff) | uint16(val & 0x07) << 8
+            t.LengthCounter.Load(val >> 3)
+            t.LinearCounter.Reload = true
+    }
+}
+
+// Unlike the other channels the triangle's timer runs at the CPU's rate. The
+// sequencer only moves while both counters are running, so silencing the
+// channel leaves it holding its last level.
+func (t *Triangle) ClockTimer() {
+    if t.timer > 0 {
+        t.timer--
+        return
+    }
+
+    t.timer = t.Period
+
+    if t.LengthCounter.Active() && t.LinearCounter.Value > 0 {
+        t.step = (t.step + 1) & 0x1f
+    }
+}
+
+func (t *Triangle) Output() byte {
+    return TriangleTable[t.step]
+}
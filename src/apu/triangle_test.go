@@ -0,0 +1,45 @@
+package apu
+
+import (
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func TestTriangleLinearCounterReloads(t *testing.T) {
+    tr := NewTriangle()
+    tr.LengthCounter.SetEnabled(true)
+
+    tr.Write(0x05, 0)
+    tr.Write(0x08, 3)
+    tr.LinearCounter.Clock()
+    assert.Equal(t, tr.LinearCounter.Value, byte(5))
+
+    // Without the control flag the reload flag is cleared after use.
+    tr.LinearCounter.Clock()
+    assert.Equal(t, tr.LinearCounter.Value, byte(4))
+}
+
+func TestTriangleSequencesWhileCountersRun(t *testing.T) {
+    tr := NewTriangle()
+    tr.LengthCounter.SetEnabled(true)
+    tr.Write(0x81, 0)
+    tr.Write(0x02, 2)
+    tr.Write(0x08, 3)
+
+    assert.Equal(t, tr.Output(), byte(15))
+
+    // The linear counter isn't loaded until it's clocked.
+    tr.ClockTimer()
+    assert.Equal(t, tr.Output(), byte(15))
+
+    tr.LinearCounter.Clock()
+    for i := 0; i < 3 * 16; i++ {
+        tr.ClockTimer()
+    }
+    assert.Equal(t, tr.Output(), byte(0))
+
+    tr.ClockTimer()
+    tr.ClockTimer()
+    tr.ClockTimer()
+    assert.Equal(t, tr.Output(), byte(1))
+}
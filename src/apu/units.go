@@ -0,0 +1,83 @@
+package apu
+
+// The building blocks shared between channels, clocked by the frame counter.
+
+var LengthTable = [32]byte {
+    10, 254, 20, 2, 40, 4, 80, 6, 160, 8, 60, 10, 14, 12, 26, 14,
+    12, 16, 24, 18, 48, 20, 96, 22, 192, 24, 72, 26, 16, 28, 32, 30,
+}
+
+// Silences a channel once it runs out, unless halted. Clocked on half frames.
+type LengthCounter struct {
+    Enabled bool
+    Halt bool
+    Value byte
+}
+
+func (l *LengthCounter) Load(index byte) {
+    if l.Enabled {
+        l.Value = LengthTable[index & 0x1f]
+    }
+}
+
+func (l *LengthCounter) SetEnabled(enabled bool) {
+    l.Enabled = enabled
+    if !enabled {
+        l.Value = 0
+    }
+}
+
+func (l *LengthCounter) Clock() {
+    if !l.Halt && l.Value > 0 {
+        l.Value--
+    }
+}
+
+func (l *LengthCounter) Active() bool {
+    return l.Value > 0
+}
+
+// Either a constant volume or a sawtooth decaying from 15 to 0, optionally
+// looping. Clocked on quarter frames.
+type Envelope struct {
+    Start bool
+    Loop bool
+    Constant bool
+
+    // The constant volume, or the period of the decay.
+    Volume byte
+
+    divider byte
+    decay byte
+}
+
+func (e *Envelope) Clock() {
+    if e.Start {
+        e.Start = false
+        e.decay = 15
+        e.divider = e.Volume
+        return
+    }
+
+    if e.divider > 0 {
+        e.divider--
+        return
+    }
+
+    e.divider = e.Volume
+
+    switch {
+        case e.decay > 0:
+            e.decay--
+        case e.Loop:
+            e.decay = 15
+    }
+}
+
+func (e *Envelope) Output() byte {
+    if e.Constant {
+        return e.Volume
+    }
+
+    return e.decay
+}
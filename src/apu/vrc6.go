@@ -0,0 +1,179 @@
+package apu
+
+import "cpu"
+
+// Konami's VRC6 adds two more pulse channels, with eight duty cycles and no
+// envelopes, and a sawtooth. Its registers are given here at their VRC6a
+// (mapper 24) addresses; VRC6b swaps A0 and A1.
+//
+// See -- http://wiki.nesdev.com/w/index.php/VRC6_audio
+type VRC6 struct {
+    Pulse1 *VRC6Pulse
+    Pulse2 *VRC6Pulse
+    Saw *VRC6Saw
+
+    // $9003 can stop every channel's timer, or speed them up 16 or 256 times.
+    Halt bool
+    shift uint
+}
+
+type VRC6Pulse struct {
+    // In mode, the channel outputs its volume constantly, for digitized
+    // samples.
+    Mode bool
+    Duty byte
+    Volume byte
+    Period uint16
+    Enabled bool
+
+    timer uint16
+    step byte
+}
+
+type VRC6Saw struct {
+    Rate byte
+    Period uint16
+    Enabled bool
+
+    timer uint16
+    step byte
+    accumulator byte
+}
+
+func NewVRC6() *VRC6 {
+    v := new(VRC6)
+
+    v.Pulse1 = new(VRC6Pulse)
+    v.Pulse2 = new(VRC6Pulse)
+    v.Saw = new(VRC6Saw)
+
+    return v
+}
+
+func (v *VRC6) Write(val byte, location cpu.Address) {
+    switch location & 0xf003 {
+        case 0x9000: v.Pulse1.Write(val, 0)
+        case 0x9001: v.Pulse1.Write(val, 1)
+        case 0x9002: v.Pulse1.Write(val, 2)
+        case 0x9003:
+            v.Halt = val & 0x01 == 0x01
+            switch {
+                case val & 0x04 == 0x04: v.shift = 8
+                case val & 0x02 == 0x02: v.shift = 4
+                default:                 v.shift = 0
+            }
+        case 0xa000: v.Pulse2.Write(val, 0)
+        case 0xa001: v.Pulse2.Write(val, 1)
+        case 0xa002: v.Pulse2.Write(val, 2)
+        case 0xb000: v.Saw.Write(val, 0)
+        case 0xb001: v.Saw.Write(val, 1)
+        case 0xb002: v.Saw.Write(val, 2)
+    }
+}
+
+// Unlike the APU's channels, the VRC6's timers run at the CPU's rate.
+func (v *VRC6) Step() {
+    if v.Halt {
+        return
+    }
+
+    v.Pulse1.ClockTimer(v.shift)
+    v.Pulse2.ClockTimer(v.shift)
+    v.Saw.ClockTimer(v.shift)
+}
+
+func (v *VRC6) Output() float64 {
+    return float64(v.Pulse1.Output() + v.Pulse2.Output() + v.Saw.Output()) / 61
+}
+
+// A VRC6 pulse at full volume is about as loud as an APU pulse at full
+// volume.
+func (v *VRC6) Level() float64 {
+    return pulseTable[15] * 61 / 15
+}
+
+func (p *VRC6Pulse) Write(val byte, register int) {
+    switch register {
+        case 0:
+            p.Mode = val & 0x80 == 0x80
+            p.Duty = (val >> 4) & 0x07
+            p.Volume = val & 0x0f
+        case 1:
+            p.Period = (p.Period & 0x0f00) | uint16(val)
+        case 2:
+            p.Period = (p.Period & 0x00ff) | uint16(val & 0x0f) << 8
+            p.Enabled = val & 0x80 == 0x80
+
+            // Disabling the channel resets its duty cycle.
+            if !p.Enabled {
+                p.step = 15
+            }
+    }
+}
+
+func (p *VRC6Pulse) ClockTimer(shift uint) {
+    if !p.Enabled {
+        return
+    }
+
+    if p.timer > 0 {
+        p.timer--
+        return
+    }
+
+    p.timer = p.Period >> shift
+    p.step = (p.step - 1) & 0x0f
+}
+
+func (p *VRC6Pulse) Output() byte {
+    if !p.Enabled || (!p.Mode && p.step > p.Duty) {
+        return 0
+    }
+
+    return p.Volume
+}
+
+func (s *VRC6Saw) Write(val byte, register int) {
+    switch register {
+        case 0:
+            s.Rate = val & 0x3f
+        case 1:
+            s.Period = (s.Period & 0x0f00) | uint16(val)
+        case 2:
+            s.Period = (s.Period & 0x00ff) | uint16(val & 0x0f) << 8
+            s.Enabled = val & 0x80 == 0x80
+
+            if !s.Enabled {
+                s.step = 0
+                s.accumulator = 0
+            }
+    }
+}
+
+// The accumulator takes the rate on every other clock, and is reset on the
+// fourteenth.
+func (s *VRC6Saw) ClockTimer(shift uint) {
+    if !s.Enabled {
+        return
+    }
+
+    if s.timer > 0 {
+        s.timer--
+        return
+    }
+
+    s.timer = s.Period >> shift
+
+    s.step++
+    switch {
+        case s.step == 14:
+            s.step = 0
+            s.accumulator = 0
+        case s.step & 0x01 == 0x00:
+            s.accumulator += s.Rate
+    }
+}
+
+func (s *VRC6Saw) Output() byte {
+    return s.accumulator >> 3
+}
@@ -0,0 +1,59 @@
+package apu
+
+import (
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func TestVRC6PulseDuty(t *testing.T) {
+    v := NewVRC6()
+
+    // A 2/16 duty cycle at volume 9, with a period of 1.
+    v.Write(0x19, 0x9000)
+    v.Write(0x01, 0x9001)
+    v.Write(0x80, 0x9002)
+
+    outputs := []byte {}
+    for i := 0; i < 32; i++ {
+        v.Step()
+        if i % 2 == 0 {
+            outputs = append(outputs, v.Pulse1.Output())
+        }
+    }
+
+    assert.Equal(t, outputs, []byte { 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 9, 9 })
+}
+
+func TestVRC6PulseMode(t *testing.T) {
+    v := NewVRC6()
+    v.Write(0x85, 0xa000)
+    v.Write(0x80, 0xa002)
+
+    assert.Equal(t, v.Pulse2.Output(), byte(5))
+}
+
+func TestVRC6SawRamps(t *testing.T) {
+    v := NewVRC6()
+    v.Write(42, 0xb000)
+    v.Write(0x80, 0xb002)
+
+    outputs := []byte {}
+    for i := 0; i < 14; i++ {
+        v.Step()
+        outputs = append(outputs, v.Saw.Output())
+    }
+
+    assert.Equal(t, outputs, []byte { 0, 5, 5, 10, 10, 15, 15, 21, 21, 26, 26, 31, 31, 0 })
+}
+
+func TestVRC6Halt(t *testing.T) {
+    v := NewVRC6()
+    v.Write(42, 0xb000)
+    v.Write(0x80, 0xb002)
+    v.Write(0x01, 0x9003)
+
+    v.Step()
+    v.Step()
+
+    assert.Equal(t, v.Saw.Output(), byte(0))
+}
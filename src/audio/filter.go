@@ -0,0 +1,58 @@
+package audio
+
+import "math"
+
+type Filter interface {
+    Process(sample float64) float64
+}
+
+// A first order high-pass filter, as an RC network would make.
+type HighPass struct {
+    alpha float64
+    lastIn float64
+    lastOut float64
+}
+
+func NewHighPass(rate float64, cutoff float64) *HighPass {
+    rc := 1 / (2 * math.Pi * cutoff)
+    dt := 1 / rate
+
+    return &HighPass { alpha: rc / (rc + dt) }
+}
+
+func (f *HighPass) Process(sample float64) float64 {
+    f.lastOut = f.alpha * (f.lastOut + sample - f.lastIn)
+    f.lastIn = sample
+
+    return f.lastOut
+}
+
+// A first order low-pass filter.
+type LowPass struct {
+    alpha float64
+    lastOut float64
+}
+
+func NewLowPass(rate float64, cutoff float64) *LowPass {
+    rc := 1 / (2 * math.Pi * cutoff)
+    dt := 1 / rate
+
+    return &LowPass { alpha: dt / (rc + dt) }
+}
+
+func (f *LowPass) Process(sample float64) float64 {
+    f.lastOut += f.alpha * (sample - f.lastOut)
+    return f.lastOut
+}
+
+// The filters between the 2A03 and the NES's audio output: two high-passes,
+// at 90Hz and 440Hz, and a low-pass at 14kHz.
+//
+// See -- http://wiki.nesdev.com/w/index.php/APU_Mixer
+func NESFilters(rate float64) []Filter {
+    return []Filter {
+        NewHighPass(rate, 90),
+        NewHighPass(rate, 440),
+        NewLowPass(rate, 14000),
+    }
+}
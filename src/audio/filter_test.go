@@ -0,0 +1,44 @@
+package audio
+
+import (
+    "math"
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func TestHighPassRemovesDC(t *testing.T) {
+    f := NewHighPass(44100, 90)
+
+    var out float64
+    for i := 0; i < 44100; i++ {
+        out = f.Process(1)
+    }
+
+    assert.True(t, math.Abs(out) < 1e-3)
+}
+
+func TestLowPassKeepsDC(t *testing.T) {
+    f := NewLowPass(44100, 14000)
+
+    var out float64
+    for i := 0; i < 1000; i++ {
+        out = f.Process(1)
+    }
+
+    assert.True(t, math.Abs(out - 1) < 1e-9)
+}
+
+func TestLowPassAttenuatesHighFrequencies(t *testing.T) {
+    f := NewLowPass(44100, 1000)
+
+    var max = 0.0
+    for i := 0; i < 44100; i++ {
+        sample := 1.0
+        if i % 2 == 0 {
+            sample = -1
+        }
+        max = math.Max(max, math.Abs(f.Process(sample)))
+    }
+
+    assert.True(t, max < 0.2)
+}
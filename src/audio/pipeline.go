@@ -0,0 +1,127 @@
+package audio
+
+// Anything producing a level every clock, like the APU, along with the
+// levels of each of its channels for stems.
+type Source interface {
+    Output() float64
+    Stems() []float64
+}
+
+// A chain from a source's output at its clock rate to a sink: resampling,
+// the NES's output filters, then the sink. Stems, if added, each get a
+// chain of their own.
+type Pipeline struct {
+    SampleRate int
+    Sink AudioSink
+
+    // The first error from a sink, after which nothing more is written.
+    Err error
+
+    main *chain
+    stems []*chain
+}
+
+// Samples are handed to sinks in chunks of this many.
+const CHUNK_SIZE = 1024
+
+type chain struct {
+    resampler *Resampler
+    filters []Filter
+    sink AudioSink
+
+    raw []float64
+    samples []float32
+}
+
+func newChain(clock float64, rate int, sink AudioSink) *chain {
+    c := new(chain)
+
+    c.resampler = NewResampler(clock, float64(rate))
+    c.filters = NESFilters(float64(rate))
+    c.sink = sink
+    c.raw = make([]float64, CHUNK_SIZE)
+    c.samples = make([]float32, 0, CHUNK_SIZE)
+
+    return c
+}
+
+func (c *chain) flush() error {
+    for c.resampler.Available() > 0 {
+        count := c.resampler.Read(c.raw)
+
+        c.samples = c.samples[:0]
+        for _, sample := range c.raw[:count] {
+            for _, filter := range c.filters {
+                sample = filter.Process(sample)
+            }
+            c.samples = append(c.samples, float32(sample))
+        }
+
+        if err := c.sink.Write(c.samples); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+func NewPipeline(clock float64, rate int, sink AudioSink) *Pipeline {
+    p := new(Pipeline)
+
+    p.SampleRate = rate
+    p.Sink = sink
+    p.main = newChain(clock, rate, sink)
+
+    return p
+}
+
+// Adds a sink for each of the source's stems, in the order it lists them.
+func (p *Pipeline) AddStems(sinks []AudioSink) {
+    for _, sink := range sinks {
+        p.stems = append(p.stems, newChain(p.main.resampler.ClockRate, p.SampleRate, sink))
+    }
+}
+
+// Takes one clock of the source's output.
+func (p *Pipeline) Clock(source Source) {
+    p.main.resampler.Clock(source.Output())
+
+    if len(p.stems) > 0 {
+        for i, level := range source.Stems() {
+            p.stems[i].resampler.Clock(level)
+        }
+    }
+
+    if p.main.resampler.Available() >= CHUNK_SIZE {
+        p.Flush()
+    }
+}
+
+// Pushes every finished sample through to the sinks.
+func (p *Pipeline) Flush() error {
+    if p.Err != nil {
+        return p.Err
+    }
+
+    for _, c := range append([]*chain { p.main }, p.stems...) {
+        if err := c.flush(); err != nil {
+            p.Err = err
+            return err
+        }
+    }
+
+    return nil
+}
+
+// Flushes and closes every sink.
+func (p *Pipeline) Close() error {
+    err := p.Flush()
+
+    for _, c := range append([]*chain { p.main }, p.stems...) {
+        if closeErr := c.sink.Close(); err == nil {
+            err = closeErr
+        }
+    }
+
+    return err
+}
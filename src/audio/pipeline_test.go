@@ -0,0 +1,60 @@
+package audio
+
+import (
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+type square struct {
+    clock int
+}
+
+func (s *square) Output() float64 {
+    s.clock++
+    if (s.clock / 4000) % 2 == 0 {
+        return 0.5
+    }
+
+    return 0
+}
+
+func (s *square) Stems() []float64 {
+    return []float64 { 0.25, 0.25 }
+}
+
+func TestPipelineWritesToItsSinks(t *testing.T) {
+    sink := new(NullSink)
+    stems := []AudioSink { new(NullSink), new(NullSink) }
+
+    p := NewPipeline(1789773, 48000, sink)
+    p.AddStems(stems)
+
+    source := new(square)
+    for i := 0; i < 1789773 / 10; i++ {
+        p.Clock(source)
+    }
+    assert.Nil(t, p.Close())
+
+    assert.True(t, sink.Samples > 4800 - 20 && sink.Samples <= 4800)
+    assert.Equal(t, stems[0].(*NullSink).Samples, sink.Samples)
+}
+
+type failingSink struct {
+    NullSink
+}
+
+func (s *failingSink) Write(samples []float32) error {
+    return assert.AnError
+}
+
+func TestPipelineKeepsTheFirstSinkError(t *testing.T) {
+    p := NewPipeline(1789773, 48000, new(failingSink))
+
+    source := new(square)
+    for i := 0; i < 100000; i++ {
+        p.Clock(source)
+    }
+
+    assert.Equal(t, p.Err, assert.AnError)
+    assert.Equal(t, p.Close(), assert.AnError)
+}
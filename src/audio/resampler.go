@@ -0,0 +1,140 @@
+package audio
+
+import "math"
+
+// Band-limited step synthesis. The APU's output is a level that holds
+// steady for many cycles then jumps, so rather than filtering 1.79 million
+// samples a second, each jump is drawn into the output at its exact time as
+// a step with everything above the output's Nyquist frequency taken out.
+// Between jumps nothing needs doing at all.
+//
+// The steps are drawn as their impulses, from a table of windowed sinc
+// kernels at PHASES fractional positions between output samples, and summed
+// back into levels as samples are read.
+const (
+    PHASES = 32
+    TAPS = 16
+
+    // As a fraction of the output sample rate.
+    CUTOFF = 0.45
+)
+
+var kernels [PHASES][TAPS]float64
+
+func init() {
+    for p := 0; p < PHASES; p++ {
+        var sum = 0.0
+        offset := float64(p) / PHASES
+
+        for k := 0; k < TAPS; k++ {
+            x := float64(k - TAPS / 2 + 1) - offset
+
+            // Blackman window
+            w := (x + TAPS / 2) / TAPS
+            window := 0.42 - 0.5 * math.Cos(2 * math.Pi * w) + 0.08 * math.Cos(4 * math.Pi * w)
+
+            sinc := 1.0
+            if x != 0 {
+                sinc = math.Sin(2 * math.Pi * CUTOFF * x) / (2 * math.Pi * CUTOFF * x)
+            }
+
+            kernels[p][k] = sinc * window
+            sum += kernels[p][k]
+        }
+
+        for k := 0; k < TAPS; k++ {
+            kernels[p][k] /= sum
+        }
+    }
+}
+
+type Resampler struct {
+    ClockRate float64
+    SampleRate float64
+
+    // Output samples per input clock.
+    ratio float64
+
+    // Impulses not yet read out, starting from the next sample to be read,
+    // and where the input has got to relative to that sample.
+    impulses []float64
+    time float64
+
+    level float64
+    sum float64
+}
+
+func NewResampler(clock float64, rate float64) *Resampler {
+    r := new(Resampler)
+
+    r.ClockRate = clock
+    r.SampleRate = rate
+    r.ratio = rate / clock
+    r.impulses = make([]float64, TAPS)
+
+    // Steps reach back half a kernel, so output starts that far behind.
+    r.time = TAPS / 2
+
+    return r
+}
+
+// Takes the input level for one clock.
+func (r *Resampler) Clock(level float64) {
+    if level != r.level {
+        r.step(level - r.level)
+        r.level = level
+    }
+
+    r.time += r.ratio
+}
+
+func (r *Resampler) step(delta float64) {
+    whole := int(r.time)
+    phase := int((r.time - float64(whole)) * PHASES)
+
+    // Available never lets the read position catch up with the first tap.
+    first := whole - TAPS / 2 + 1
+
+    for len(r.impulses) < first + TAPS {
+        r.impulses = append(r.impulses, 0)
+    }
+
+    for k := 0; k < TAPS; k++ {
+        r.impulses[first + k] += delta * kernels[phase][k]
+    }
+}
+
+// The number of samples which can be read: those which no future step can
+// reach back to.
+func (r *Resampler) Available() int {
+    available := int(r.time) - TAPS / 2
+    if available < 0 {
+        return 0
+    }
+
+    return available
+}
+
+// Reads as many samples as are available and fit in out.
+func (r *Resampler) Read(out []float64) int {
+    count := r.Available()
+    if count > len(out) {
+        count = len(out)
+    }
+
+    for i := 0; i < count; i++ {
+        if i < len(r.impulses) {
+            r.sum += r.impulses[i]
+        }
+        out[i] = r.sum
+    }
+
+    if count < len(r.impulses) {
+        r.impulses = r.impulses[:copy(r.impulses, r.impulses[count:])]
+    } else {
+        r.impulses = r.impulses[:0]
+    }
+    r.time -= float64(count)
+
+    return count
+}
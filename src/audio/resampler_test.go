@@ -0,0 +1,66 @@
+package audio
+
+import (
+    "math"
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func TestResamplerProducesSamplesAtItsRate(t *testing.T) {
+    r := NewResampler(1789773, 44100)
+    out := make([]float64, 50000)
+
+    total := 0
+    for i := 0; i < 1789773; i++ {
+        r.Clock(0)
+        total += r.Read(out)
+    }
+
+    assert.True(t, total > 44100 - TAPS && total <= 44100)
+}
+
+func TestResamplerSettlesOnSteps(t *testing.T) {
+    r := NewResampler(1789773, 44100)
+    out := make([]float64, 1000)
+
+    for i := 0; i < 10000; i++ {
+        r.Clock(0.5)
+    }
+    count := r.Read(out)
+
+    assert.True(t, math.Abs(out[count - 1] - 0.5) < 1e-9)
+}
+
+func TestResamplerRemovesFrequenciesAboveNyquist(t *testing.T) {
+    r := NewResampler(1789773, 44100)
+    out := make([]float64, 50000)
+
+    // A 60kHz square wave has nothing below 22kHz to keep.
+    total := 0
+    for i := 0; i < 200000; i++ {
+        level := 0.0
+        if (i / 15) % 2 == 0 {
+            level = 1
+        }
+        r.Clock(level)
+        total += r.Read(out[total:])
+    }
+
+    var max = 0.0
+    for _, sample := range out[100:total] {
+        max = math.Max(max, math.Abs(sample - 0.5))
+    }
+
+    assert.True(t, max < 0.05)
+}
+
+func TestKernelsSumToOne(t *testing.T) {
+    for p := 0; p < PHASES; p++ {
+        var sum = 0.0
+        for k := 0; k < TAPS; k++ {
+            sum += kernels[p][k]
+        }
+
+        assert.True(t, math.Abs(sum - 1) < 1e-9)
+    }
+}
@@ -0,0 +1,157 @@
+package audio
+
+import (
+    "io"
+    "encoding/binary"
+)
+
+// Somewhere for audio to go: a file, a sound card, or nowhere at all.
+// Samples are mono, between -1 and 1, at whatever rate the sink was set up
+// with.
+type AudioSink interface {
+    Write(samples []float32) error
+    Close() error
+}
+
+// Throws audio away, counting the samples it was given.
+type NullSink struct {
+    Samples int
+}
+
+func (s *NullSink) Write(samples []float32) error {
+    s.Samples += len(samples)
+    return nil
+}
+
+func (s *NullSink) Close() error {
+    return nil
+}
+
+// Writes 16 bit mono PCM to a WAV file. The sizes in the header are filled
+// in when it's closed.
+type WAVSink struct {
+    SampleRate int
+
+    w io.WriteSeeker
+    samples int
+}
+
+const WAV_HEADER_SIZE = 44
+
+func NewWAVSink(w io.WriteSeeker, rate int) (*WAVSink, error) {
+    s := new(WAVSink)
+
+    s.SampleRate = rate
+    s.w = w
+
+    if err := s.writeHeader(); err != nil {
+        return nil, err
+    }
+
+    return s, nil
+}
+
+func (s *WAVSink) writeHeader() error {
+    dataSize := uint32(s.samples * 2)
+
+    header := make([]byte, WAV_HEADER_SIZE)
+    copy(header[0:], "RIFF")
+    binary.LittleEndian.PutUint32(header[4:], 36 + dataSize)
+    copy(header[8:], "WAVE")
+
+    copy(header[12:], "fmt ")
+    binary.LittleEndian.PutUint32(header[16:], 16)
+    binary.LittleEndian.PutUint16(header[20:], 1)
+    binary.LittleEndian.PutUint16(header[22:], 1)
+    binary.LittleEndian.PutUint32(header[24:], uint32(s.SampleRate))
+    binary.LittleEndian.PutUint32(header[28:], uint32(s.SampleRate * 2))
+    binary.LittleEndian.PutUint16(header[32:], 2)
+    binary.LittleEndian.PutUint16(header[34:], 16)
+
+    copy(header[36:], "data")
+    binary.LittleEndian.PutUint32(header[40:], dataSize)
+
+    _, err := s.w.Write(header)
+    return err
+}
+
+func (s *WAVSink) Write(samples []float32) error {
+    data := make([]byte, len(samples) * 2)
+
+    for i, sample := range samples {
+        switch {
+            case sample > 1:
+                sample = 1
+            case sample < -1:
+                sample = -1
+        }
+
+        binary.LittleEndian.PutUint16(data[i*2:], uint16(int16(sample * 32767)))
+    }
+
+    s.samples += len(samples)
+
+    _, err := s.w.Write(data)
+    return err
+}
+
+func (s *WAVSink) Close() error {
+    var err error
+    if _, err = s.w.Seek(0, io.SeekStart); err != nil {
+        return err
+    }
+    if err = s.writeHeader(); err != nil {
+        return err
+    }
+
+    _, err = s.w.Seek(0, io.SeekEnd)
+    if closer, ok := s.w.(io.Closer); ok && err == nil {
+        err = closer.Close()
+    }
+
+    return err
+}
+
+// Passes samples on to another sink, fading them out linearly over Fade
+// samples once Length have gone by, and dropping everything after that.
+type FadeSink struct {
+    Sink AudioSink
+    Length int
+    Fade int
+
+    position int
+}
+
+func NewFadeSink(sink AudioSink, length int, fade int) *FadeSink {
+    return &FadeSink { Sink: sink, Length: length, Fade: fade }
+}
+
+// Whether every sample up to the end of the fade has been written.
+func (s *FadeSink) Done() bool {
+    return s.position >= s.Length + s.Fade
+}
+
+func (s *FadeSink) Write(samples []float32) error {
+    end := s.Length + s.Fade - s.position
+    if end <= 0 {
+        return nil
+    }
+    if end < len(samples) {
+        samples = samples[:end]
+    }
+
+    faded := make([]float32, len(samples))
+    for i, sample := range samples {
+        if into := s.position + i - s.Length; into > 0 {
+            sample *= 1 - float32(into) / float32(s.Fade)
+        }
+        faded[i] = sample
+    }
+    s.position += len(samples)
+
+    return s.Sink.Write(faded)
+}
+
+func (s *FadeSink) Close() error {
+    return s.Sink.Close()
+}
@@ -0,0 +1,55 @@
+package audio
+
+import (
+    "os"
+    "io/ioutil"
+    "testing"
+    "encoding/binary"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func TestWAVSinkWritesHeaderAndSamples(t *testing.T) {
+    file, err := ioutil.TempFile("", "gones-wav")
+    assert.Nil(t, err)
+    defer os.Remove(file.Name())
+
+    sink, err := NewWAVSink(file, 48000)
+    assert.Nil(t, err)
+
+    sink.Write([]float32 { 0, 1, -1, 2 })
+    assert.Nil(t, sink.Close())
+
+    data, _ := ioutil.ReadFile(file.Name())
+
+    assert.Equal(t, len(data), WAV_HEADER_SIZE + 8)
+    assert.Equal(t, string(data[0:4]), "RIFF")
+    assert.Equal(t, string(data[8:12]), "WAVE")
+    assert.Equal(t, binary.LittleEndian.Uint32(data[24:]), uint32(48000))
+    assert.Equal(t, binary.LittleEndian.Uint32(data[40:]), uint32(8))
+    assert.Equal(t, int16(binary.LittleEndian.Uint16(data[46:])), int16(32767))
+    assert.Equal(t, int16(binary.LittleEndian.Uint16(data[48:])), int16(-32767))
+    assert.Equal(t, int16(binary.LittleEndian.Uint16(data[50:])), int16(32767))
+}
+
+type recordingSink struct {
+    NullSink
+    samples []float32
+}
+
+func (s *recordingSink) Write(samples []float32) error {
+    s.samples = append(s.samples, samples...)
+    return nil
+}
+
+func TestFadeSinkFadesOutAndStops(t *testing.T) {
+    out := new(recordingSink)
+    sink := NewFadeSink(out, 2, 4)
+
+    sink.Write([]float32 { 1, 1, 1, 1 })
+    assert.False(t, sink.Done())
+
+    sink.Write([]float32 { 1, 1, 1, 1 })
+    assert.True(t, sink.Done())
+
+    assert.Equal(t, out.samples, []float32 { 1, 1, 1, 0.75, 0.5, 0.25 })
+}
@@ -2,8 +2,13 @@ package cpu
 
 type Address uint16
 
+// Interrupt kinds. NMI is edge triggered; the rest are sources of IRQs, which
+// are level triggered and held until their source cancels them.
 const (
     NMI = iota
+    FRAME_IRQ
+    DMC_IRQ
+    MAPPER_IRQ
 )
 
 type Interrupt struct {
@@ -20,14 +25,29 @@ type CPU struct {
     A, X, Y, SP, Flags byte
     PC Address
     Memory Memory
+    RAM *InternalRAM
     Debug bool
 
     Cycle func()
 
+    // Access, if set, is told about every read and write the CPU makes, after
+    // it's made.
+    Access func(location Address, value byte, write bool)
+
+    // DMA, if set, is called before every read cycle. DMA units can only halt
+    // the CPU on a read, so this is where they take over the bus; location is
+    // the read the CPU will repeat once they're done.
+    DMA func(location Address)
+
     operations map[Opcode]Op
     cycles int
 
     nmi Interrupt
+
+    // The IRQ line, with a bit for each source asserting it, and when it was
+    // first asserted.
+    irq int
+    irqCycle int
 }
 
 type Opcode byte
@@ -42,25 +62,42 @@ func NewCPU() *CPU {
     p := new(CPU)
 
     p.Memory = *NewMemory()
-    p.Memory.Mount(NewInternalRAM(), 0x0000, 0x1fff)
+    p.RAM = NewInternalRAM()
+    p.Memory.Mount(p.RAM, 0x0000, 0x1fff)
 
     p.nmi = Interrupt { false, 0 }
 
     return p
 }
 
-func (p *CPU) Read(location Address) byte {
+// Spends a cycle without the CPU touching the bus, for instance while it's
+// halted by a DMA.
+func (p *CPU) Tick() {
     if p.Cycle != nil { p.Cycle() }
     p.cycles++
+}
 
-    return p.Memory.Read(location)
+// The number of cycles since the CPU was reset.
+func (p *CPU) Cycles() int {
+    return p.cycles
+}
+
+func (p *CPU) Read(location Address) byte {
+    if p.DMA != nil { p.DMA(location) }
+
+    p.Tick()
+
+    value := p.Memory.Read(location)
+    if p.Access != nil { p.Access(location, value, false) }
+
+    return value
 }
 
 func (p *CPU) Write(value byte, location Address) {
-    if p.Cycle != nil { p.Cycle() }
-    p.cycles++
+    p.Tick()
 
     p.Memory.Write(value, location)
+    if p.Access != nil { p.Access(location, value, true) }
 }
 
 func (p *CPU) Execute(op Op) {
@@ -83,10 +120,22 @@ func (p *CPU) Step() int {
 
     if p.Debug { p.Debugf(opcode, op) }
 
+    // Interrupts are polled before the last cycle of an instruction, so CLI,
+    // SEI and PLP change the I flag too late to affect the poll; RTI restores
+    // it early enough to.
+    disabled := p.InterruptDisable()
+
     p.Execute(op)
 
-    if p.nmi.Occurred && p.nmi.Cycle < (p.cycles - 1) {
-        p.HandleNMI()
+    if opcode == 0x40 {
+        disabled = p.InterruptDisable()
+    }
+
+    switch {
+        case p.nmi.Occurred && p.nmi.Cycle < (p.cycles - 1):
+            p.HandleNMI()
+        case p.irq != 0 && p.irqCycle < (p.cycles - 1) && !disabled:
+            p.HandleIRQ()
     }
 
     return p.cycles
@@ -339,11 +388,38 @@ func (p *CPU) Reset() {
     p.cycles = 0
 }
 
+// Pressing the reset button. The CPU goes through the motions of an
+// interrupt, but with the stack writes turned into reads, then jumps through
+// the reset vector.
+func (p *CPU) SoftReset() {
+    p.nmi.Occurred = false
+
+    p.Read(p.PC)
+    p.Read(p.PC)
+
+    for i := 0; i < 3; i++ {
+        p.Read(0x0100 | Address(p.SP))
+        p.SP--
+    }
+
+    p.setInterruptDisable(true)
+
+    low := p.Read(0xfffc)
+    high := p.Read(0xfffd)
+
+    p.PC = (Address(high) << 8) | Address(low)
+}
+
 func (p *CPU) Interrupt(kind int) {
     switch kind {
         case NMI:
             p.nmi.Occurred = true
             p.nmi.Cycle = p.cycles
+        default:
+            if p.irq == 0 {
+                p.irqCycle = p.cycles
+            }
+            p.irq |= 1 << uint(kind)
     }
 }
 
@@ -351,9 +427,16 @@ func (p *CPU) Cancel(kind int) {
     switch kind {
         case NMI:
             p.nmi.Occurred = false
+        default:
+            p.irq &^= 1 << uint(kind)
     }
 }
 
+// Whether a source is asserting the IRQ line.
+func (p *CPU) IRQ(kind int) bool {
+    return p.irq & (1 << uint(kind)) != 0
+}
+
 func (p *CPU) setFlag(mask byte, value bool) {
     if value {
         p.Flags |= mask
@@ -0,0 +1,90 @@
+package cpu
+
+import (
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func irqCPU() *CPU {
+    p := NewCPU()
+    p.Memory.Mount(NewRAM(0xe000), 0x2000, 0xffff)
+    p.Reset()
+
+    p.Memory.Write(0xef, 0xfffe)
+    p.Memory.Write(0xbe, 0xffff)
+
+    // NOPs
+    for i := 0; i < 0x10; i++ {
+        p.Memory.Write(0xea, Address(i))
+    }
+    p.PC = 0x0000
+
+    return p
+}
+
+func TestIRQIsIgnoredWithInterruptsDisabled(t *testing.T) {
+    p := irqCPU()
+
+    p.Interrupt(FRAME_IRQ)
+    p.Step()
+    p.Step()
+
+    assert.Equal(t, p.PC, Address(0x0002))
+    assert.True(t, p.IRQ(FRAME_IRQ))
+}
+
+func TestIRQIsHandledWithInterruptsEnabled(t *testing.T) {
+    p := irqCPU()
+    p.setInterruptDisable(false)
+    p.Flags |= 0x10
+
+    p.Interrupt(MAPPER_IRQ)
+    p.Step()
+
+    assert.Equal(t, p.PC, Address(0xbeef))
+    assert.True(t, p.InterruptDisable())
+
+    // The break flag is clear in the pushed flags.
+    assert.Equal(t, p.Memory.Read(0x01fb) & 0x10, byte(0x00))
+    assert.Equal(t, p.Memory.Read(0x01fc), byte(0x01))
+}
+
+func TestIRQAfterCLIWaitsAnInstruction(t *testing.T) {
+    p := irqCPU()
+    p.Memory.Write(0x58, 0x0000)
+
+    p.Interrupt(FRAME_IRQ)
+    p.Step()
+    assert.Equal(t, p.PC, Address(0x0001))
+
+    p.Step()
+    assert.Equal(t, p.PC, Address(0xbeef))
+}
+
+func TestIRQIsLevelTriggered(t *testing.T) {
+    p := irqCPU()
+    p.setInterruptDisable(false)
+
+    p.Interrupt(FRAME_IRQ)
+    p.Interrupt(DMC_IRQ)
+    p.Cancel(FRAME_IRQ)
+
+    p.Step()
+    assert.Equal(t, p.PC, Address(0xbeef))
+
+    p.Cancel(DMC_IRQ)
+    assert.False(t, p.IRQ(DMC_IRQ))
+}
+
+func TestSoftResetJumpsThroughResetVector(t *testing.T) {
+    p := irqCPU()
+    p.Memory.Write(0x34, 0xfffc)
+    p.Memory.Write(0x12, 0xfffd)
+    p.setInterruptDisable(false)
+
+    p.SoftReset()
+
+    assert.Equal(t, p.PC, Address(0x1234))
+    assert.Equal(t, p.SP, byte(0xfa))
+    assert.True(t, p.InterruptDisable())
+}
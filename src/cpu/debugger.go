@@ -75,5 +75,5 @@ func (p *CPU) Debugf(opcode Opcode, op Op) {
 
     debug += fmt.Sprintf("A:%02X X:%02X Y:%02X P:%02X SP:%02X\n", p.A, p.X, p.Y, p.Flags, p.SP)
 
-    fmt.Printf(debug)
+    fmt.Print(debug)
 }
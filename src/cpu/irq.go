@@ -10,9 +10,26 @@ func (p *CPU) HandleNMI() {
     p.push(byte(p.PC & 0x00ff))
 
     p.push(p.Flags)
+    p.setInterruptDisable(true)
 
     low := p.Memory.Read(0xfffa)
     high := p.Memory.Read(0xfffb)
 
     p.PC = (Address(high) << 8) | Address(low)
 }
+
+func (p *CPU) HandleIRQ() {
+    p.Read(p.PC)
+    p.Read(p.PC)
+
+    p.push(byte(p.PC >> 8))
+    p.push(byte(p.PC & 0x00ff))
+
+    p.push(p.Flags &^ 0x10)
+    p.setInterruptDisable(true)
+
+    low := p.Memory.Read(0xfffe)
+    high := p.Memory.Read(0xffff)
+
+    p.PC = (Address(high) << 8) | Address(low)
+}
@@ -0,0 +1,60 @@
+package cpu
+
+import (
+    "fmt"
+    "strings"
+)
+
+// The console's region. The CPU, APU and PPU all come in regional variants
+// which differ in clock rates and a handful of behaviours. Dendy is the
+// Famiclone common in the former Soviet Union, which pairs PAL frame timing
+// with an NTSC-like CPU.
+type Region int
+
+const (
+    NTSC Region = iota
+    PAL
+    Dendy
+)
+
+var RegionNames = map[Region]string {
+    NTSC:  "NTSC",
+    PAL:   "PAL",
+    Dendy: "Dendy",
+}
+
+type Timing struct {
+    // CPU cycles per second.
+    Clock float64
+
+    // Frames per second.
+    FrameRate float64
+
+    // The PPU runs PPUDots dots for every CPUCycles CPU cycles.
+    PPUDots int
+    CPUCycles int
+}
+
+var Timings = map[Region]Timing {
+    NTSC:  Timing { 1789773, 60.0988, 3, 1 },
+    PAL:   Timing { 1662607, 50.0070, 16, 5 },
+    Dendy: Timing { 1773448, 50.0070, 3, 1 },
+}
+
+func (r Region) Timing() Timing {
+    return Timings[r]
+}
+
+func (r Region) String() string {
+    return RegionNames[r]
+}
+
+func ParseRegion(name string) (Region, error) {
+    for region, regionName := range RegionNames {
+        if strings.EqualFold(name, regionName) {
+            return region, nil
+        }
+    }
+
+    return NTSC, fmt.Errorf("Unknown region %q", name)
+}
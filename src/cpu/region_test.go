@@ -0,0 +1,19 @@
+package cpu
+
+import (
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func TestParseRegion(t *testing.T) {
+    region, err := ParseRegion("pal")
+    assert.Nil(t, err)
+    assert.Equal(t, region, PAL)
+
+    region, err = ParseRegion("Dendy")
+    assert.Nil(t, err)
+    assert.Equal(t, region, Dendy)
+
+    _, err = ParseRegion("SECAM")
+    assert.NotNil(t, err)
+}
@@ -0,0 +1,31 @@
+package cpu
+
+import "state"
+
+const STATE_VERSION = 1
+
+// Saves or loads the registers, the cycle count and the interrupt lines.
+// What's mounted in memory looks after itself.
+func (p *CPU) State(s state.Stream) {
+    s.Byte(&p.A)
+    s.Byte(&p.X)
+    s.Byte(&p.Y)
+    s.Byte(&p.SP)
+    s.Byte(&p.Flags)
+    s.Uint16((*uint16)(&p.PC))
+    s.Int(&p.cycles)
+
+    s.Bool(&p.nmi.Occurred)
+    s.Int(&p.nmi.Cycle)
+    s.Int(&p.irq)
+    s.Int(&p.irqCycle)
+}
+
+// Only the first 2KB is real; the rest mirrors it.
+func (r *InternalRAM) State(s state.Stream) {
+    s.Bytes(r.buffer[:0x800])
+}
+
+func (r *RAM) State(s state.Stream) {
+    s.Bytes(r.buffer)
+}
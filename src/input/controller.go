@@ -0,0 +1,80 @@
+package input
+
+// Buttons, in the order the controller reports them.
+const (
+    BUTTON_A = 1 << iota
+    BUTTON_B
+    BUTTON_SELECT
+    BUTTON_START
+    BUTTON_UP
+    BUTTON_DOWN
+    BUTTON_LEFT
+    BUTTON_RIGHT
+)
+
+var ButtonNames = map[byte]string {
+    BUTTON_A:      "A",
+    BUTTON_B:      "B",
+    BUTTON_SELECT: "Select",
+    BUTTON_START:  "Start",
+    BUTTON_UP:     "Up",
+    BUTTON_DOWN:   "Down",
+    BUTTON_LEFT:   "Left",
+    BUTTON_RIGHT:  "Right",
+}
+
+// The standard controller: a shift register which is loaded with the
+// buttons while the strobe is high, then shifted out a bit per read on D0.
+// Once all eight have been read an official controller returns 1s.
+//
+// See -- http://wiki.nesdev.com/w/index.php/Standard_controller
+type Controller struct {
+    buttons byte
+
+    strobe bool
+    shift byte
+}
+
+func NewController() *Controller {
+    return new(Controller)
+}
+
+func (c *Controller) SetButtons(buttons byte) {
+    c.buttons = buttons
+
+    if c.strobe {
+        c.shift = buttons
+    }
+}
+
+func (c *Controller) Buttons() byte {
+    return c.buttons
+}
+
+func (c *Controller) Write(val byte) {
+    c.strobe = val & 0x01 == 0x01
+
+    if c.strobe {
+        c.shift = c.buttons
+    }
+}
+
+func (c *Controller) Read() byte {
+    bit := c.Peek()
+
+    if !c.strobe {
+        c.shift = c.shift >> 1 | 0x80
+    }
+
+    return bit
+}
+
+// While the strobe is high the register keeps reloading, so reads only ever
+// see A.
+func (c *Controller) Peek() byte {
+    if c.strobe {
+        return c.buttons & 0x01
+    }
+
+    return c.shift & 0x01
+}
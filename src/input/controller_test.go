@@ -0,0 +1,96 @@
+package input
+
+import (
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func readAll(device InputDevice, count int) []byte {
+    bits := make([]byte, count)
+    for i := range bits {
+        bits[i] = device.Read()
+    }
+
+    return bits
+}
+
+func TestControllerShiftsOutButtonsInOrder(t *testing.T) {
+    c := NewController()
+    c.SetButtons(BUTTON_A | BUTTON_START | BUTTON_RIGHT)
+
+    c.Write(0x01)
+    c.Write(0x00)
+
+    assert.Equal(t, readAll(c, 10), []byte { 1, 0, 0, 1, 0, 0, 0, 1, 1, 1 })
+}
+
+func TestControllerLatchesWhenStrobed(t *testing.T) {
+    c := NewController()
+    c.SetButtons(BUTTON_B)
+
+    c.Write(0x01)
+    c.Write(0x00)
+    c.SetButtons(BUTTON_A)
+
+    assert.Equal(t, readAll(c, 2), []byte { 0, 1 })
+}
+
+func TestControllerReportsAWhileStrobeIsHigh(t *testing.T) {
+    c := NewController()
+    c.Write(0x01)
+    c.SetButtons(BUTTON_A | BUTTON_B)
+
+    assert.Equal(t, readAll(c, 3), []byte { 1, 1, 1 })
+}
+
+func TestControllerPeekDoesntShift(t *testing.T) {
+    c := NewController()
+    c.SetButtons(BUTTON_A)
+    c.Write(0x01)
+    c.Write(0x00)
+
+    assert.Equal(t, c.Peek(), byte(1))
+    assert.Equal(t, c.Peek(), byte(1))
+}
+
+func TestPortsReadNothingFromEmptyPorts(t *testing.T) {
+    p := NewPorts()
+
+    assert.Equal(t, p.Read(1, true), byte(0x00))
+    assert.False(t, p.SetButtons(1, BUTTON_A))
+}
+
+func TestPortsNumberPlayersAcrossPorts(t *testing.T) {
+    p := NewPorts()
+    c := NewController()
+    p.Plug(1, c)
+
+    // With nothing in the first port, the second has the first player.
+    assert.True(t, p.SetButtons(0, BUTTON_UP))
+    assert.Equal(t, c.Buttons(), byte(BUTTON_UP))
+    assert.False(t, p.SetButtons(1, BUTTON_UP))
+}
+
+func TestHeldPortsWaitForRelease(t *testing.T) {
+    p := NewPorts()
+    c := NewController()
+    p.Plug(0, c)
+
+    p.Hold()
+
+    done := make(chan bool)
+    go func() {
+        p.SetButtons(0, BUTTON_A)
+        close(done)
+    }()
+
+    // The emulation can still read the ports while they're held.
+    p.Write(0x01)
+    p.Write(0x00)
+    assert.Equal(t, p.Read(0, true), byte(0x00))
+
+    p.Release()
+    <-done
+
+    assert.Equal(t, c.Buttons(), byte(BUTTON_A))
+}
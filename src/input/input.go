@@ -0,0 +1,251 @@
+package input
+
+import "sync"
+
+// Something plugged into one of the console's controller ports.
+//
+// Writes to $4016 go to every device: bit 0 is the strobe, and bits 1 and 2
+// are only wired to the Famicom's expansion port. Reads of $4016 and $4017
+// come from the first and second port, and a device drives whichever of the
+// low five data lines it's wired to.
+//
+// See -- http://wiki.nesdev.com/w/index.php/Input_devices
+type InputDevice interface {
+    Write(val byte)
+
+    // Returns the data lines and clocks the device on to its next bit.
+    Read() byte
+
+    // Returns the data lines without clocking anything.
+    Peek() byte
+}
+
+// Devices with buttons, which frontends can press from any goroutine by way
+// of Ports.
+type ButtonDevice interface {
+    InputDevice
+    SetButtons(buttons byte)
+    Buttons() byte
+}
+
+// Adapters with several controllers plugged into them.
+type MultiplayerDevice interface {
+    Players() []*Controller
+}
+
+// Something plugged into the Famicom's expansion port, which sees every
+// write to $4016 and can drive data lines on reads of either $4016 or
+// $4017.
+type ExpansionDevice interface {
+    Write(val byte)
+    Read(port int) byte
+    Peek(port int) byte
+}
+
+// The two controller ports, and the Famicom's expansion port. The devices
+// plugged in and their state are shared between the emulation and whatever
+// is feeding it input, so everything goes through a lock.
+type Ports struct {
+    mutex sync.Mutex
+
+    // Taken by anything feeding the ports input, on top of mutex, so the
+    // emulation can hold input steady with Hold.
+    host sync.Mutex
+
+    devices [2]InputDevice
+    expansion ExpansionDevice
+}
+
+func NewPorts() *Ports {
+    return new(Ports)
+}
+
+// Plugs a device into a port, numbered from 0. A nil device unplugs it.
+func (p *Ports) Plug(port int, device InputDevice) {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+
+    p.devices[port] = device
+}
+
+func (p *Ports) Device(port int) InputDevice {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+
+    return p.devices[port]
+}
+
+// Plugs a device into the expansion port. A nil device unplugs it.
+func (p *Ports) PlugExpansion(device ExpansionDevice) {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+
+    p.expansion = device
+}
+
+func (p *Ports) Expansion() ExpansionDevice {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+
+    return p.expansion
+}
+
+// Every controller plugged in, in player order: whatever's in the first
+// port, then the second, then the expansion port.
+func (p *Ports) players() []ButtonDevice {
+    players := []ButtonDevice {}
+
+    for _, device := range []interface{} { p.devices[0], p.devices[1], p.expansion } {
+        switch d := device.(type) {
+            case MultiplayerDevice:
+                for _, controller := range d.Players() {
+                    players = append(players, controller)
+                }
+            case ButtonDevice:
+                players = append(players, d)
+        }
+    }
+
+    return players
+}
+
+// Sets the buttons a player's holding, numbered from 0, returning false if
+// there aren't that many controllers plugged in.
+func (p *Ports) SetButtons(player int, buttons byte) bool {
+    p.host.Lock()
+    defer p.host.Unlock()
+
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+
+    players := p.players()
+    if player < 0 || player >= len(players) {
+        return false
+    }
+
+    players[player].SetButtons(buttons)
+    return true
+}
+
+// The buttons a player's holding, or false if there aren't that many
+// controllers plugged in.
+func (p *Ports) Buttons(player int) (byte, bool) {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+
+    players := p.players()
+    if player < 0 || player >= len(players) {
+        return 0, false
+    }
+
+    return players[player].Buttons(), true
+}
+
+// Points the device in a port somewhere, returning false if it doesn't have
+// a pointer. Port 2 is the expansion port.
+func (p *Ports) SetPointer(port int, x int, y int, trigger bool) bool {
+    p.host.Lock()
+    defer p.host.Unlock()
+
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+
+    var device interface{} = p.expansion
+    if port < len(p.devices) {
+        device = p.devices[port]
+    }
+
+    pointer, ok := device.(pointer)
+    if ok {
+        pointer.SetPointer(x, y, trigger)
+    }
+
+    return ok
+}
+
+// Presses or releases a key on any keyboard plugged in, returning false if
+// there isn't one.
+func (p *Ports) SetKey(name string, pressed bool) bool {
+    found := false
+
+    p.host.Lock()
+    defer p.host.Unlock()
+
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+
+    for _, device := range []interface{} { p.devices[0], p.devices[1], p.expansion } {
+        if keyboard, ok := device.(KeyboardDevice); ok {
+            keyboard.SetKey(name, pressed)
+            found = true
+        }
+    }
+
+    return found
+}
+
+// Runs f with the ports locked, for changing devices' state some other way
+// than through Ports.
+func (p *Ports) Update(f func()) {
+    p.host.Lock()
+    defer p.host.Unlock()
+
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+
+    f()
+}
+
+// Holds off any new input until Release, for emulating frames that are
+// going to be undone: they all see the same input, and none is lost when
+// the ports' state is put back afterwards.
+func (p *Ports) Hold() {
+    p.host.Lock()
+}
+
+func (p *Ports) Release() {
+    p.host.Unlock()
+}
+
+func (p *Ports) Write(val byte) {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+
+    for _, device := range p.devices {
+        if device != nil {
+            device.Write(val)
+        }
+    }
+
+    if p.expansion != nil {
+        p.expansion.Write(val)
+    }
+}
+
+// Reads a port's data lines, along with anything on the expansion port
+// driving them. Empty ports drive nothing.
+func (p *Ports) Read(port int, clock bool) byte {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+
+    var value = byte(0x00)
+
+    device := p.devices[port]
+    switch {
+        case device == nil:
+        case clock:
+            value |= device.Read()
+        default:
+            value |= device.Peek()
+    }
+
+    switch {
+        case p.expansion == nil:
+        case clock:
+            value |= p.expansion.Read(port)
+        default:
+            value |= p.expansion.Peek(port)
+    }
+
+    return value & 0x1f
+}
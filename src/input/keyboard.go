@@ -0,0 +1,89 @@
+package input
+
+// The Family BASIC keyboard's matrix: nine rows, each read four keys at a
+// time as two columns. Keys are named as the frontend names them where
+// there's an obvious match.
+var KeyboardMatrix = [9][8]string {
+    { "]", "[", "Enter", "F8", "End", "\\", "RShift", "RAlt" },
+    { ";", ":", "@", "F7", "^", "-", "/", "_" },
+    { "K", "L", "O", "F6", "0", "P", ",", "." },
+    { "J", "U", "I", "F5", "8", "9", "N", "M" },
+    { "H", "G", "Y", "F4", "6", "7", "V", "B" },
+    { "D", "R", "T", "F3", "4", "5", "C", "F" },
+    { "A", "S", "W", "F2", "3", "E", "Z", "X" },
+    { "LCtrl", "Q", "Esc", "F1", "2", "1", "LAlt", "LShift" },
+    { "Left", "Right", "Up", "Home", "Insert", "Delete", "Space", "Down" },
+}
+
+// Devices with keys, which frontends can press from any goroutine by way of
+// Ports.
+type KeyboardDevice interface {
+    SetKey(name string, pressed bool)
+}
+
+// The Family BASIC keyboard plugs into the Famicom's expansion port and is
+// scanned through $4016 writes: bit 0 goes back to the first row, bit 1
+// picks a column and moves to the next row when it goes low, and bit 2
+// turns the matrix on. Each $4017 read gives four keys on D1-D4, low while
+// pressed.
+//
+// See -- http://wiki.nesdev.com/w/index.php/Family_BASIC_Keyboard
+type Keyboard struct {
+    keys map[string]bool
+
+    row int
+    column byte
+    enabled bool
+}
+
+func NewKeyboard() *Keyboard {
+    k := new(Keyboard)
+    k.keys = make(map[string]bool)
+
+    return k
+}
+
+func (k *Keyboard) SetKey(name string, pressed bool) {
+    if pressed {
+        k.keys[name] = true
+    } else {
+        delete(k.keys, name)
+    }
+}
+
+func (k *Keyboard) Write(val byte) {
+    column := (val >> 1) & 0x01
+    k.enabled = val & 0x04 == 0x04
+
+    switch {
+        case val & 0x01 == 0x01:
+            k.row = 0
+        case k.column == 1 && column == 0:
+            k.row++
+    }
+
+    k.column = column
+}
+
+func (k *Keyboard) Read(port int) byte {
+    return k.Peek(port)
+}
+
+func (k *Keyboard) Peek(port int) byte {
+    if port == 0 || !k.enabled {
+        return 0x00
+    }
+
+    if k.row >= len(KeyboardMatrix) {
+        return 0x1e
+    }
+
+    var value = byte(0x00)
+    for i := 0; i < 4; i++ {
+        if !k.keys[KeyboardMatrix[k.row][int(k.column) * 4 + i]] {
+            value |= 0x02 << uint(i)
+        }
+    }
+
+    return value
+}
@@ -0,0 +1,66 @@
+package input
+
+import (
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+// Scans the keyboard the way Family BASIC does, returning each row's two
+// columns.
+func scanKeyboard(k *Keyboard) [][2]byte {
+    rows := make([][2]byte, 10)
+
+    k.Write(0x05)
+    for i := range rows {
+        k.Write(0x04)
+        rows[i][0] = k.Read(1)
+        k.Write(0x06)
+        rows[i][1] = k.Read(1)
+    }
+
+    return rows
+}
+
+func TestKeyboardScansRowsAndColumns(t *testing.T) {
+    k := NewKeyboard()
+    k.SetKey("Enter", true)
+    k.SetKey("X", true)
+    k.SetKey("Space", true)
+
+    rows := scanKeyboard(k)
+
+    assert.Equal(t, rows[0], [2]byte { 0x16, 0x1e })
+    assert.Equal(t, rows[1], [2]byte { 0x1e, 0x1e })
+    assert.Equal(t, rows[6], [2]byte { 0x1e, 0x0e })
+    assert.Equal(t, rows[8], [2]byte { 0x1e, 0x16 })
+    assert.Equal(t, rows[9], [2]byte { 0x1e, 0x1e })
+}
+
+func TestKeyboardReadsNothingWhenDisabled(t *testing.T) {
+    k := NewKeyboard()
+
+    k.Write(0x01)
+    assert.Equal(t, k.Read(1), byte(0x00))
+
+    k.Write(0x04)
+    assert.Equal(t, k.Read(0), byte(0x00))
+    assert.Equal(t, k.Read(1), byte(0x1e))
+
+    k.SetKey("]", true)
+    assert.Equal(t, k.Read(1), byte(0x1c))
+    k.SetKey("]", false)
+    assert.Equal(t, k.Read(1), byte(0x1e))
+}
+
+func TestPortsPressKeysAndPointInTheExpansionPort(t *testing.T) {
+    p := NewPorts()
+    assert.Equal(t, p.SetKey("A", true), false)
+
+    p.PlugExpansion(NewKeyboard())
+    assert.Equal(t, p.SetKey("A", true), true)
+
+    vaus := NewFamicomVaus()
+    p.PlugExpansion(vaus)
+    assert.Equal(t, p.SetPointer(2, 255, 0, true), true)
+    assert.Equal(t, vaus.Position, byte(VAUS_MAX))
+}
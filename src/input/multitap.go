@@ -0,0 +1,136 @@
+package input
+
+// Multitap kinds
+const (
+    NO_MULTITAP = iota
+    FOUR_SCORE
+    HORI_ADAPTER
+)
+
+var MultitapNames = map[int]string {
+    NO_MULTITAP:  "none",
+    FOUR_SCORE:   "fourscore",
+    HORI_ADAPTER: "hori",
+}
+
+// Four controllers read through two ports. Each port gives the eight
+// buttons of one controller, then another, then a signature byte so games
+// can tell the adapter's there, most significant bit first, all a bit per
+// read; after that it returns 1s.
+//
+// The NES Four Score plugs into both controller ports and answers on D0,
+// with controllers 1 and 3 on $4016 and 2 and 4 on $4017. The Hori 4
+// Players Adapter plugs into the Famicom's expansion port and answers the
+// same way on D1, but with the signatures swapped.
+//
+// See -- http://wiki.nesdev.com/w/index.php/Four_player_adapters
+type Multitap struct {
+    Controllers [4]*Controller
+
+    signatures [2]byte
+    line uint
+
+    strobe bool
+    reads [2]int
+}
+
+func newMultitap(signatures [2]byte, line uint) *Multitap {
+    m := new(Multitap)
+
+    for i := range m.Controllers {
+        m.Controllers[i] = NewController()
+    }
+    m.signatures = signatures
+    m.line = line
+
+    return m
+}
+
+func NewFourScore() *Multitap {
+    return newMultitap([2]byte { 0x10, 0x20 }, 0)
+}
+
+func NewHoriAdapter() *Multitap {
+    return newMultitap([2]byte { 0x20, 0x10 }, 1)
+}
+
+func (m *Multitap) Players() []*Controller {
+    return m.Controllers[:]
+}
+
+func (m *Multitap) Write(val byte) {
+    m.strobe = val & 0x01 == 0x01
+
+    if m.strobe {
+        m.reads = [2]int { 0, 0 }
+    }
+
+    for _, controller := range m.Controllers {
+        controller.Write(val)
+    }
+}
+
+func (m *Multitap) Read(port int) byte {
+    return m.read(port, true)
+}
+
+func (m *Multitap) Peek(port int) byte {
+    return m.read(port, false)
+}
+
+func (m *Multitap) read(port int, clock bool) byte {
+    first, second := m.Controllers[port], m.Controllers[port + 2]
+
+    var bit byte
+    switch n := m.reads[port]; {
+        case m.strobe:
+            bit = first.Peek()
+        case n < 8:
+            bit = first.Peek()
+            if clock { first.Read() }
+        case n < 16:
+            bit = second.Peek()
+            if clock { second.Read() }
+        case n < 24:
+            bit = (m.signatures[port] >> uint(23 - n)) & 0x01
+        default:
+            bit = 1
+    }
+
+    if clock && !m.strobe && m.reads[port] < 24 {
+        m.reads[port]++
+    }
+
+    return bit << m.line
+}
+
+// The Four Score's side of one of the controller ports. The first has all
+// four players, so they're numbered in order.
+func (m *Multitap) Port(port int) InputDevice {
+    return &multitapPort { m, port }
+}
+
+type multitapPort struct {
+    multitap *Multitap
+    port int
+}
+
+func (p *multitapPort) Write(val byte) {
+    p.multitap.Write(val)
+}
+
+func (p *multitapPort) Read() byte {
+    return p.multitap.Read(p.port)
+}
+
+func (p *multitapPort) Peek() byte {
+    return p.multitap.Peek(p.port)
+}
+
+func (p *multitapPort) Players() []*Controller {
+    if p.port == 0 {
+        return p.multitap.Players()
+    }
+
+    return nil
+}
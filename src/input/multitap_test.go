@@ -0,0 +1,95 @@
+package input
+
+import (
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func readPort(p *Ports, port int, count int) []byte {
+    bits := make([]byte, count)
+    for i := range bits {
+        bits[i] = p.Read(port, true)
+    }
+
+    return bits
+}
+
+func fourPlayers(p *Ports) {
+    p.SetButtons(0, BUTTON_A)
+    p.SetButtons(1, BUTTON_B)
+    p.SetButtons(2, BUTTON_SELECT)
+    p.SetButtons(3, BUTTON_START)
+
+    p.Write(0x01)
+    p.Write(0x00)
+}
+
+func TestFourScoreReportsFourControllersAndItsSignature(t *testing.T) {
+    p := NewPorts()
+    tap := NewFourScore()
+    p.Plug(0, tap.Port(0))
+    p.Plug(1, tap.Port(1))
+    fourPlayers(p)
+
+    assert.Equal(t, readPort(p, 0, 26), []byte {
+        1, 0, 0, 0, 0, 0, 0, 0,
+        0, 0, 1, 0, 0, 0, 0, 0,
+        0, 0, 0, 1, 0, 0, 0, 0,
+        1, 1,
+    })
+
+    assert.Equal(t, readPort(p, 1, 26), []byte {
+        0, 1, 0, 0, 0, 0, 0, 0,
+        0, 0, 0, 1, 0, 0, 0, 0,
+        0, 0, 1, 0, 0, 0, 0, 0,
+        1, 1,
+    })
+}
+
+// Games roll the signature in with ROL, most significant bit first, and
+// look for $10 on $4016 and $20 on $4017.
+func TestFourScoreSignaturesRollInAsTheyreDocumented(t *testing.T) {
+    tap := NewFourScore()
+    tap.Write(0x01)
+    tap.Write(0x00)
+
+    for port, expected := range []byte { 0x10, 0x20 } {
+        for i := 0; i < 16; i++ {
+            tap.Read(port)
+        }
+
+        var signature byte
+        for i := 0; i < 8; i++ {
+            signature = signature << 1 | tap.Read(port)
+        }
+
+        assert.Equal(t, signature, expected)
+    }
+}
+
+func TestHoriAdapterAnswersOnD1(t *testing.T) {
+    p := NewPorts()
+    p.PlugExpansion(NewHoriAdapter())
+    fourPlayers(p)
+
+    bits := readPort(p, 0, 24)
+
+    assert.Equal(t, bits[0], byte(0x02))
+    assert.Equal(t, bits[10], byte(0x02))
+    assert.Equal(t, bits[16 + 2], byte(0x02))
+    assert.Equal(t, bits[16 + 3], byte(0x00))
+}
+
+func TestMultitapRestartsWhenStrobed(t *testing.T) {
+    tap := NewFourScore()
+    tap.Controllers[0].SetButtons(BUTTON_A)
+
+    tap.Write(0x01)
+    tap.Write(0x00)
+    tap.Read(0)
+    tap.Read(0)
+
+    tap.Write(0x01)
+    tap.Write(0x00)
+    assert.Equal(t, tap.Read(0), byte(1))
+}
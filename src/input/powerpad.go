@@ -0,0 +1,71 @@
+package input
+
+// The order the Power Pad's buttons, numbered 1 to 12 as on side B, are
+// shifted out on D3 and D4.
+var (
+    PowerPadD3 = [8]int { 2, 1, 5, 9, 6, 10, 11, 7 }
+    PowerPadD4 = [4]int { 4, 3, 12, 8 }
+)
+
+// The Power Pad mat: twelve buttons, read eight at a time on D3 and four on
+// D4, after which both lines read 1.
+//
+// See -- http://wiki.nesdev.com/w/index.php/Power_Pad
+type PowerPad struct {
+    // Bit n-1 is set while button n is stood on.
+    Pressed uint16
+
+    strobe bool
+    low byte
+    high byte
+}
+
+func NewPowerPad() *PowerPad {
+    return new(PowerPad)
+}
+
+func (p *PowerPad) SetPressed(pressed uint16) {
+    p.Pressed = pressed
+
+    if p.strobe {
+        p.latch()
+    }
+}
+
+func (p *PowerPad) bit(button int) byte {
+    return byte(p.Pressed >> uint(button - 1)) & 0x01
+}
+
+func (p *PowerPad) latch() {
+    p.low, p.high = 0x00, 0xf0
+
+    for i, button := range PowerPadD3 {
+        p.low |= p.bit(button) << uint(i)
+    }
+    for i, button := range PowerPadD4 {
+        p.high |= p.bit(button) << uint(i)
+    }
+}
+
+func (p *PowerPad) Write(val byte) {
+    p.strobe = val & 0x01 == 0x01
+
+    if p.strobe {
+        p.latch()
+    }
+}
+
+func (p *PowerPad) Read() byte {
+    value := p.Peek()
+
+    if !p.strobe {
+        p.low = p.low >> 1 | 0x80
+        p.high = p.high >> 1 | 0x80
+    }
+
+    return value
+}
+
+func (p *PowerPad) Peek() byte {
+    return (p.low & 0x01) << 3 | (p.high & 0x01) << 4
+}
@@ -0,0 +1,31 @@
+package input
+
+import (
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func TestPowerPadShiftsOutButtonsOnD3AndD4(t *testing.T) {
+    p := NewPowerPad()
+
+    // Buttons 1, 9 and 12.
+    p.SetPressed(0x0901)
+    p.Write(0x01)
+    p.Write(0x00)
+
+    reads := make([]byte, 10)
+    for i := range reads {
+        reads[i] = p.Read()
+    }
+
+    assert.Equal(t, reads, []byte { 0x00, 0x08, 0x10, 0x08, 0x10, 0x10, 0x10, 0x10, 0x18, 0x18 })
+}
+
+func TestPowerPadLatchesWhileStrobed(t *testing.T) {
+    p := NewPowerPad()
+    p.Write(0x01)
+
+    p.SetPressed(0x0002)
+    assert.Equal(t, p.Read(), byte(0x08))
+    assert.Equal(t, p.Read(), byte(0x08))
+}
@@ -0,0 +1,109 @@
+package input
+
+import (
+    "fmt"
+    "sort"
+    "state"
+)
+
+const STATE_VERSION = 1
+
+// Devices whose state can be saved and loaded.
+type StateDevice interface {
+    State(s state.Stream)
+}
+
+// Saves or loads the state of whatever's plugged in. A state saved with
+// different devices plugged in leaves them alone.
+func (p *Ports) State(s state.Stream) {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+
+    devices := []interface{} { p.devices[0], p.devices[1], p.expansion }
+
+    matches := true
+    for _, device := range devices {
+        name := fmt.Sprintf("%T", device)
+        saved := name
+        s.String(&saved)
+
+        matches = matches && saved == name
+    }
+
+    if !matches {
+        return
+    }
+
+    for _, device := range devices {
+        if device, ok := device.(StateDevice); ok {
+            device.State(s)
+        }
+    }
+}
+
+func (c *Controller) State(s state.Stream) {
+    s.Byte(&c.buttons)
+    s.Bool(&c.strobe)
+    s.Byte(&c.shift)
+}
+
+// Both ports share the one multitap, so it's saved twice over, which does
+// no harm.
+func (p *multitapPort) State(s state.Stream) {
+    p.multitap.State(s)
+}
+
+func (m *Multitap) State(s state.Stream) {
+    for _, controller := range m.Controllers {
+        controller.State(s)
+    }
+
+    s.Bool(&m.strobe)
+    s.Int(&m.reads[0])
+    s.Int(&m.reads[1])
+}
+
+func (z *Zapper) State(s state.Stream) {
+    s.Int(&z.X)
+    s.Int(&z.Y)
+    s.Bool(&z.Trigger)
+}
+
+func (p *paddle) State(s state.Stream) {
+    s.Byte(&p.Position)
+    s.Bool(&p.Fire)
+    s.Bool(&p.strobe)
+    s.Byte(&p.shift)
+}
+
+func (p *PowerPad) State(s state.Stream) {
+    s.Uint16(&p.Pressed)
+    s.Bool(&p.strobe)
+    s.Byte(&p.low)
+    s.Byte(&p.high)
+}
+
+func (k *Keyboard) State(s state.Stream) {
+    s.Int(&k.row)
+    s.Byte(&k.column)
+    s.Bool(&k.enabled)
+
+    keys := []string {}
+    for key := range k.keys {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+
+    count := len(keys)
+    s.Int(&count)
+
+    if s.Loading() {
+        keys = make([]string, count)
+        k.keys = make(map[string]bool)
+    }
+
+    for _, key := range keys {
+        s.String(&key)
+        k.keys[key] = true
+    }
+}
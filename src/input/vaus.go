@@ -0,0 +1,108 @@
+package input
+
+// The range the Vaus's potentiometer reads across, from fully left to fully
+// right.
+const (
+    VAUS_MIN = 0x62
+    VAUS_MAX = 0xf2
+)
+
+// Arkanoid's Vaus controller: a knob read as an eight bit value, most
+// significant bit first and inverted, which is latched by the strobe, and a
+// fire button. The NES version plugs into the second port and answers on D4
+// and D3; the Famicom one plugs into the expansion port and answers on D1,
+// with the button on $4016 and the knob on $4017.
+//
+// See -- http://wiki.nesdev.com/w/index.php/Arkanoid_controller
+type paddle struct {
+    Position byte
+    Fire bool
+
+    strobe bool
+    shift byte
+}
+
+// Turns the knob to match a pointer's x, from 0 to 255.
+func (p *paddle) SetPointer(x int, y int, trigger bool) {
+    switch {
+        case x < 0:
+            x = 0
+        case x > 255:
+            x = 255
+    }
+
+    p.Position = byte(VAUS_MIN + x * (VAUS_MAX - VAUS_MIN) / 255)
+    p.Fire = trigger
+}
+
+func (p *paddle) Write(val byte) {
+    p.strobe = val & 0x01 == 0x01
+
+    if p.strobe {
+        p.shift = p.Position
+    }
+}
+
+func (p *paddle) data(clock bool) byte {
+    bit := (^p.shift >> 7) & 0x01
+
+    if clock && !p.strobe {
+        p.shift <<= 1
+    }
+
+    return bit
+}
+
+func (p *paddle) fire() byte {
+    if p.Fire {
+        return 0x01
+    }
+
+    return 0x00
+}
+
+type Vaus struct {
+    paddle
+}
+
+func NewVaus() *Vaus {
+    v := new(Vaus)
+    v.Position = VAUS_MIN
+
+    return v
+}
+
+func (v *Vaus) Read() byte {
+    return v.data(true) << 4 | v.fire() << 3
+}
+
+func (v *Vaus) Peek() byte {
+    return v.data(false) << 4 | v.fire() << 3
+}
+
+type FamicomVaus struct {
+    paddle
+}
+
+func NewFamicomVaus() *FamicomVaus {
+    v := new(FamicomVaus)
+    v.Position = VAUS_MIN
+
+    return v
+}
+
+func (v *FamicomVaus) Read(port int) byte {
+    if port == 0 {
+        return v.fire() << 1
+    }
+
+    return v.data(true) << 1
+}
+
+func (v *FamicomVaus) Peek(port int) byte {
+    if port == 0 {
+        return v.fire() << 1
+    }
+
+    return v.data(false) << 1
+}
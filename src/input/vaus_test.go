@@ -0,0 +1,49 @@
+package input
+
+import (
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func TestVausShiftsOutItsKnobInvertedOnD4(t *testing.T) {
+    v := NewVaus()
+    v.Position = 0xa5
+    v.Fire = true
+
+    v.Write(0x01)
+    v.Write(0x00)
+
+    value := byte(0)
+    for i := 0; i < 8; i++ {
+        read := v.Read()
+        assert.Equal(t, read & 0x08, byte(0x08))
+        value = value << 1 | (read >> 4) & 0x01
+    }
+
+    assert.Equal(t, value, byte(0x5a))
+}
+
+func TestVausMapsThePointerAcrossItsRange(t *testing.T) {
+    v := NewVaus()
+
+    v.SetPointer(-10, 0, false)
+    assert.Equal(t, v.Position, byte(VAUS_MIN))
+
+    v.SetPointer(255, 0, true)
+    assert.Equal(t, v.Position, byte(VAUS_MAX))
+    assert.Equal(t, v.Fire, true)
+}
+
+func TestFamicomVausAnswersOnD1OfBothPorts(t *testing.T) {
+    v := NewFamicomVaus()
+    v.Position = 0x80
+    v.Fire = true
+
+    v.Write(0x01)
+    v.Write(0x00)
+
+    assert.Equal(t, v.Read(0), byte(0x02))
+    assert.Equal(t, v.Peek(1), byte(0x00))
+    assert.Equal(t, v.Read(1), byte(0x00))
+    assert.Equal(t, v.Read(1), byte(0x02))
+}
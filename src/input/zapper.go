@@ -0,0 +1,98 @@
+package input
+
+// The picture a light gun's pointed at, as it's being drawn.
+type Screen interface {
+    // The scanline and dot being drawn.
+    Beam() (scanline int, dot int)
+
+    // How bright a pixel is, from 0 to 1.
+    Brightness(x int, y int) float64
+}
+
+// Devices with a pointer, which frontends can move from any goroutine by
+// way of Ports.
+type PointerDevice interface {
+    InputDevice
+    pointer
+}
+
+type pointer interface {
+    SetPointer(x int, y int, trigger bool)
+}
+
+const (
+    // How far around where it's pointed the Zapper can see, in pixels.
+    ZAPPER_RADIUS = 2
+
+    // How long the photodiode stays lit after the beam passes, in scanlines.
+    ZAPPER_SENSE_LINES = 20
+
+    // How bright a pixel has to be to register.
+    ZAPPER_THRESHOLD = 0.5
+)
+
+// The Zapper reports its trigger on D4 and whether it sees light on D3. It
+// only sees light briefly as the beam passes the spot it's pointed at, so
+// games flash targets and check for light while they're being drawn rather
+// than once the frame's done.
+//
+// See -- http://wiki.nesdev.com/w/index.php/Zapper
+type Zapper struct {
+    Screen Screen
+
+    // Where it's pointed, in pixels. Anywhere off the screen sees nothing.
+    X, Y int
+    Trigger bool
+}
+
+func NewZapper(screen Screen) *Zapper {
+    return &Zapper { Screen: screen, X: -1, Y: -1 }
+}
+
+func (z *Zapper) SetPointer(x int, y int, trigger bool) {
+    z.X, z.Y = x, y
+    z.Trigger = trigger
+}
+
+func (z *Zapper) Write(val byte) {}
+
+func (z *Zapper) Read() byte {
+    return z.Peek()
+}
+
+func (z *Zapper) Peek() byte {
+    var value = byte(0x00)
+
+    if !z.Light() { value |= 0x08 }
+    if z.Trigger { value |= 0x10 }
+
+    return value
+}
+
+// Whether any bright pixel near the spot was drawn in the last few
+// scanlines.
+func (z *Zapper) Light() bool {
+    if z.Screen == nil || z.X < 0 || z.X >= 256 || z.Y < 0 || z.Y >= 240 {
+        return false
+    }
+
+    scanline, dot := z.Screen.Beam()
+
+    for y := z.Y - ZAPPER_RADIUS; y <= z.Y + ZAPPER_RADIUS; y++ {
+        if y < 0 || y >= 240 || y > scanline || scanline - y >= ZAPPER_SENSE_LINES {
+            continue
+        }
+
+        for x := z.X - ZAPPER_RADIUS; x <= z.X + ZAPPER_RADIUS; x++ {
+            if x < 0 || x >= 256 || (y == scanline && x >= dot - 1) {
+                continue
+            }
+
+            if z.Screen.Brightness(x, y) >= ZAPPER_THRESHOLD {
+                return true
+            }
+        }
+    }
+
+    return false
+}
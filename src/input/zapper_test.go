@@ -0,0 +1,66 @@
+package input
+
+import (
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+// A screen with a white square from (100, 100) to (109, 109) on black.
+type square struct {
+    scanline, dot int
+}
+
+func (s *square) Beam() (int, int) {
+    return s.scanline, s.dot
+}
+
+func (s *square) Brightness(x int, y int) float64 {
+    if x >= 100 && x < 110 && y >= 100 && y < 110 {
+        return 1
+    }
+
+    return 0
+}
+
+func TestZapperSeesLightAfterTheBeamPasses(t *testing.T) {
+    screen := new(square)
+    z := NewZapper(screen)
+    z.SetPointer(105, 105, false)
+
+    screen.scanline, screen.dot = 50, 0
+    assert.Equal(t, z.Read(), byte(0x08))
+
+    screen.scanline, screen.dot = 105, 200
+    assert.Equal(t, z.Read(), byte(0x00))
+
+    screen.scanline = 115
+    assert.Equal(t, z.Read(), byte(0x00))
+
+    // The photodiode's gone dark again by the time the beam's well past.
+    screen.scanline = 140
+    assert.Equal(t, z.Read(), byte(0x08))
+}
+
+func TestZapperOnlySeesWhatsBeenDrawn(t *testing.T) {
+    screen := new(square)
+    z := NewZapper(screen)
+    z.SetPointer(105, 105, false)
+
+    // The top of the square's still to be drawn, and the beam's left of it.
+    screen.scanline, screen.dot = 98, 200
+    assert.False(t, z.Light())
+
+    screen.scanline, screen.dot = 104, 50
+    assert.True(t, z.Light())
+}
+
+func TestZapperSeesNothingAwayFromTheLight(t *testing.T) {
+    screen := &square { 120, 0 }
+    z := NewZapper(screen)
+
+    z.SetPointer(50, 105, false)
+    assert.False(t, z.Light())
+
+    z.SetPointer(-1, -1, true)
+    assert.Equal(t, z.Read(), byte(0x18))
+}
@@ -0,0 +1,160 @@
+package keymap
+
+import (
+    "os"
+    "sort"
+    "strings"
+    "io/ioutil"
+    "path/filepath"
+    "encoding/json"
+)
+
+// Hotkey actions
+const (
+    PAUSE = "pause"
+    RESET = "reset"
+    FRAME_ADVANCE = "frame-advance"
+    SAVE_STATE = "save-state"
+    LOAD_STATE = "load-state"
+    NEXT_SLOT = "next-slot"
+    REWIND = "rewind"
+    REBIND_1 = "rebind-1"
+    REBIND_2 = "rebind-2"
+    REBIND_3 = "rebind-3"
+    REBIND_4 = "rebind-4"
+)
+
+// Up to four players, with a multitap.
+const PLAYERS = 4
+
+// The turbo buttons, which press and release their button over and over.
+const (
+    TURBO_A = "TurboA"
+    TURBO_B = "TurboB"
+)
+
+// The buttons, in the order they're asked for when rebinding.
+var Buttons = []string { "A", "B", "Select", "Start", "Up", "Down", "Left", "Right", TURBO_A, TURBO_B }
+
+// Key bindings as they're stored on disk. Keys are named as KeyNames does;
+// players map button names to keys, and hotkeys map actions to keys.
+type Config struct {
+    Players [PLAYERS]map[string]string `json:"players"`
+    Hotkeys map[string]string `json:"hotkeys"`
+
+    // Turbo presses per second.
+    TurboRate float64 `json:"turbo_rate"`
+}
+
+func DefaultConfig() Config {
+    return Config {
+        Players: [PLAYERS]map[string]string {
+            {
+                "A": "X", "B": "Z", "Select": "RShift", "Start": "Enter",
+                "Up": "Up", "Down": "Down", "Left": "Left", "Right": "Right",
+                TURBO_A: "S", TURBO_B: "A",
+            },
+            {
+                "A": "N", "B": "B", "Select": "T", "Start": "Y",
+                "Up": "I", "Down": "K", "Left": "J", "Right": "L",
+                TURBO_A: "H", TURBO_B: "G",
+            },
+            {},
+            {},
+        },
+        Hotkeys: map[string]string {
+            PAUSE: "P",
+            RESET: "R",
+            FRAME_ADVANCE: "F",
+            SAVE_STATE: "F5",
+            NEXT_SLOT: "F6",
+            LOAD_STATE: "F7",
+            REWIND: "Backspace",
+            REBIND_1: "F9",
+            REBIND_2: "F10",
+            REBIND_3: "F11",
+            REBIND_4: "F12",
+        },
+        TurboRate: 15,
+    }
+}
+
+// Reads bindings from a JSON file over the defaults, so a file only needs
+// the bindings it changes. A key bound in the file is taken away from
+// whatever the defaults bound it to. A missing file just gives the defaults.
+func LoadConfig(path string) (Config, error) {
+    config := DefaultConfig()
+
+    data, err := ioutil.ReadFile(path)
+    if os.IsNotExist(err) {
+        return config, nil
+    } else if err != nil {
+        return config, err
+    }
+
+    var file Config
+    if err = json.Unmarshal(data, &file); err != nil {
+        return config, err
+    }
+
+    for player, bound := range file.Players {
+        for _, button := range sortedKeys(bound) {
+            config.bind(player, button, bound[button])
+        }
+    }
+    for _, action := range sortedKeys(file.Hotkeys) {
+        config.bind(-1, action, file.Hotkeys[action])
+    }
+
+    if file.TurboRate > 0 {
+        config.TurboRate = file.TurboRate
+    }
+
+    return config, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+    keys := []string {}
+    for key := range m {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+
+    return keys
+}
+
+// Keymap.Bind, on the config alone. Either map may be missing.
+func (c *Config) bind(player int, target string, key string) {
+    for _, bound := range append(c.Players[:], c.Hotkeys) {
+        for name, other := range bound {
+            if strings.EqualFold(other, key) {
+                delete(bound, name)
+            }
+        }
+    }
+
+    if player < 0 {
+        if c.Hotkeys == nil {
+            c.Hotkeys = make(map[string]string)
+        }
+        c.Hotkeys[target] = key
+    } else {
+        if c.Players[player] == nil {
+            c.Players[player] = make(map[string]string)
+        }
+        c.Players[player][target] = key
+    }
+}
+
+func SaveConfig(path string, config Config) error {
+    data, err := json.MarshalIndent(config, "", "    ")
+    if err != nil {
+        return err
+    }
+
+    if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+        return err
+    }
+
+    return ioutil.WriteFile(path, data, 0644)
+}
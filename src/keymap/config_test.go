@@ -0,0 +1,80 @@
+package keymap
+
+import (
+    "os"
+    "io/ioutil"
+    "path/filepath"
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func TestConfigRoundTrips(t *testing.T) {
+    dir, _ := ioutil.TempDir("", "gones-keys")
+    defer os.RemoveAll(dir)
+    path := filepath.Join(dir, "gones", "keys.json")
+
+    config := DefaultConfig()
+    config.Players[0]["A"] = "Q"
+    config.TurboRate = 10
+
+    assert.Nil(t, SaveConfig(path, config))
+
+    loaded, err := LoadConfig(path)
+    assert.Nil(t, err)
+    assert.Equal(t, loaded, config)
+}
+
+func TestConfigFilesOnlyNeedChanges(t *testing.T) {
+    dir, _ := ioutil.TempDir("", "gones-keys")
+    defer os.RemoveAll(dir)
+    path := filepath.Join(dir, "keys.json")
+
+    ioutil.WriteFile(path, []byte(`{ "hotkeys": { "pause": "Space" } }`), 0644)
+
+    config, err := LoadConfig(path)
+    assert.Nil(t, err)
+    assert.Equal(t, config.Hotkeys[PAUSE], "Space")
+    assert.Equal(t, config.Hotkeys[RESET], "R")
+    assert.Equal(t, config.Players[0]["A"], "X")
+}
+
+func TestConfigFilesKeepOtherPlayersDefaults(t *testing.T) {
+    dir, _ := ioutil.TempDir("", "gones-keys")
+    defer os.RemoveAll(dir)
+    path := filepath.Join(dir, "keys.json")
+
+    ioutil.WriteFile(path, []byte(`{ "players": [ { "A": "Q" } ], "hotkeys": null }`), 0644)
+
+    config, err := LoadConfig(path)
+    assert.Nil(t, err)
+    assert.Equal(t, config.Players[0]["A"], "Q")
+    assert.Equal(t, config.Players[0]["B"], "Z")
+    assert.Equal(t, config.Players[1], DefaultConfig().Players[1])
+    assert.Equal(t, config.Hotkeys, DefaultConfig().Hotkeys)
+    assert.Equal(t, config.TurboRate, DefaultConfig().TurboRate)
+
+    // Binding it afterwards doesn't trip over anything missing.
+    New(config).Bind(-1, PAUSE, "Space")
+}
+
+func TestConfigFilesTakeKeysAwayFromTheDefaults(t *testing.T) {
+    dir, _ := ioutil.TempDir("", "gones-keys")
+    defer os.RemoveAll(dir)
+    path := filepath.Join(dir, "keys.json")
+
+    // Z is player 1's B by default.
+    ioutil.WriteFile(path, []byte(`{ "players": [ { "A": "Z" } ] }`), 0644)
+
+    config, err := LoadConfig(path)
+    assert.Nil(t, err)
+    assert.Equal(t, config.Players[0]["A"], "Z")
+    _, bound := config.Players[0]["B"]
+    assert.False(t, bound)
+}
+
+func TestMissingConfigIsTheDefault(t *testing.T) {
+    config, err := LoadConfig("/nonexistent/keys.json")
+
+    assert.Nil(t, err)
+    assert.Equal(t, config, DefaultConfig())
+}
@@ -0,0 +1,222 @@
+package keymap
+
+import (
+    "sync"
+    "input"
+    "strings"
+)
+
+// Turns keys held on a keyboard into controller buttons and hotkeys. Keys
+// are given by name, so nothing here knows about any windowing library;
+// keys are pressed from the window's goroutine and buttons read from the
+// emulation's, so everything goes through a lock.
+type Keymap struct {
+    // The console's frame rate, which turbo rates are relative to.
+    FrameRate float64
+
+    mutex sync.Mutex
+    config Config
+    bindings map[string]binding
+    held map[string]bool
+
+    // The player whose buttons are being rebound, and the next one to bind,
+    // or -1.
+    rebinding int
+    next int
+}
+
+// Rebinding's finished, and the config should be saved.
+const REBOUND = "rebound"
+
+type binding struct {
+    player int
+    button byte
+    turbo bool
+
+    action string
+}
+
+var buttons = map[string]binding {
+    "a":      { button: input.BUTTON_A },
+    "b":      { button: input.BUTTON_B },
+    "select": { button: input.BUTTON_SELECT },
+    "start":  { button: input.BUTTON_START },
+    "up":     { button: input.BUTTON_UP },
+    "down":   { button: input.BUTTON_DOWN },
+    "left":   { button: input.BUTTON_LEFT },
+    "right":  { button: input.BUTTON_RIGHT },
+    "turboa": { button: input.BUTTON_A, turbo: true },
+    "turbob": { button: input.BUTTON_B, turbo: true },
+}
+
+func New(config Config) *Keymap {
+    k := new(Keymap)
+
+    k.FrameRate = 60
+    k.held = make(map[string]bool)
+    k.rebinding = -1
+    k.setConfig(config)
+
+    return k
+}
+
+func (k *Keymap) setConfig(config Config) {
+    k.config = config
+    k.bindings = make(map[string]binding)
+
+    for player, bound := range config.Players {
+        for button, key := range bound {
+            if b, ok := buttons[strings.ToLower(button)]; ok {
+                b.player = player
+                k.bindings[strings.ToLower(key)] = b
+            }
+        }
+    }
+
+    for action, key := range config.Hotkeys {
+        k.bindings[strings.ToLower(key)] = binding { action: action }
+    }
+}
+
+func (k *Keymap) SetConfig(config Config) {
+    k.mutex.Lock()
+    defer k.mutex.Unlock()
+
+    k.setConfig(config)
+}
+
+// A copy of the current bindings, e.g. to save.
+func (k *Keymap) Config() Config {
+    k.mutex.Lock()
+    defer k.mutex.Unlock()
+
+    config := Config { TurboRate: k.config.TurboRate }
+    for player, bound := range k.config.Players {
+        config.Players[player] = copyMap(bound)
+    }
+    config.Hotkeys = copyMap(k.config.Hotkeys)
+
+    return config
+}
+
+func copyMap(m map[string]string) map[string]string {
+    result := make(map[string]string, len(m))
+    for key, value := range m {
+        result[key] = value
+    }
+
+    return result
+}
+
+// Binds a key to a player's button, or a hotkey action if player is -1,
+// taking it away from whatever it was bound to before.
+func (k *Keymap) Bind(player int, target string, key string) {
+    k.mutex.Lock()
+    defer k.mutex.Unlock()
+
+    k.bind(player, target, key)
+}
+
+func (k *Keymap) bind(player int, target string, key string) {
+    k.config.bind(player, target, key)
+    k.setConfig(k.config)
+}
+
+func (k *Keymap) SetTurboRate(rate float64) {
+    k.mutex.Lock()
+    defer k.mutex.Unlock()
+
+    k.config.TurboRate = rate
+}
+
+// Starts binding each of a player's buttons in turn to the next keys
+// pressed.
+func (k *Keymap) Rebind(player int) {
+    k.mutex.Lock()
+    defer k.mutex.Unlock()
+
+    k.rebinding = player
+    k.next = 0
+}
+
+// The button waiting for a key, if rebinding.
+func (k *Keymap) Rebinding() (player int, button string, ok bool) {
+    k.mutex.Lock()
+    defer k.mutex.Unlock()
+
+    if k.rebinding < 0 {
+        return -1, "", false
+    }
+
+    return k.rebinding, Buttons[k.next], true
+}
+
+// Presses a key, returning the hotkey action it triggers, if any.
+func (k *Keymap) KeyDown(key string) string {
+    k.mutex.Lock()
+    defer k.mutex.Unlock()
+
+    if k.rebinding >= 0 {
+        k.bind(k.rebinding, Buttons[k.next], key)
+
+        k.next++
+        if k.next == len(Buttons) {
+            k.rebinding = -1
+            return REBOUND
+        }
+
+        return ""
+    }
+
+    key = strings.ToLower(key)
+    k.held[key] = true
+
+    return k.bindings[key].action
+}
+
+func (k *Keymap) KeyUp(key string) {
+    k.mutex.Lock()
+    defer k.mutex.Unlock()
+
+    delete(k.held, strings.ToLower(key))
+}
+
+// Whether the key for a hotkey is held down, for actions which last as long
+// as it is.
+func (k *Keymap) Holding(action string) bool {
+    k.mutex.Lock()
+    defer k.mutex.Unlock()
+
+    key, ok := k.config.Hotkeys[action]
+    return ok && k.held[strings.ToLower(key)]
+}
+
+// The buttons a player is holding on a given frame, with turbo buttons
+// pressed for half of each turbo period.
+func (k *Keymap) Buttons(player int, frame int) byte {
+    k.mutex.Lock()
+    defer k.mutex.Unlock()
+
+    half := 1
+    if k.config.TurboRate > 0 {
+        half = int(k.FrameRate / (2 * k.config.TurboRate) + 0.5)
+    }
+    if half < 1 {
+        half = 1
+    }
+    turbo := (frame / half) % 2 == 0
+
+    var pressed = byte(0x00)
+    for key := range k.held {
+        b, ok := k.bindings[key]
+        if !ok || b.action != "" || b.player != player {
+            continue
+        }
+
+        if !b.turbo || turbo {
+            pressed |= b.button
+        }
+    }
+
+    return pressed
+}
@@ -0,0 +1,106 @@
+package keymap
+
+import (
+    "input"
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func TestKeysPressButtons(t *testing.T) {
+    k := New(DefaultConfig())
+
+    k.KeyDown("X")
+    k.KeyDown("Up")
+    k.KeyDown("N")
+
+    assert.Equal(t, k.Buttons(0, 0), byte(input.BUTTON_A | input.BUTTON_UP))
+    assert.Equal(t, k.Buttons(1, 0), byte(input.BUTTON_A))
+
+    k.KeyUp("x")
+    assert.Equal(t, k.Buttons(0, 0), byte(input.BUTTON_UP))
+}
+
+func TestTurboAlternates(t *testing.T) {
+    k := New(DefaultConfig())
+    k.SetTurboRate(15)
+
+    k.KeyDown("S")
+
+    // At 15 presses a second, two frames on then two frames off.
+    pressed := []byte {}
+    for frame := 0; frame < 6; frame++ {
+        pressed = append(pressed, k.Buttons(0, frame))
+    }
+
+    assert.Equal(t, pressed, []byte { 1, 1, 0, 0, 1, 1 })
+}
+
+func TestHotkeysReturnTheirAction(t *testing.T) {
+    k := New(DefaultConfig())
+
+    assert.Equal(t, k.KeyDown("p"), PAUSE)
+    assert.Equal(t, k.KeyDown("F5"), SAVE_STATE)
+    assert.Equal(t, k.KeyDown("X"), "")
+    assert.Equal(t, k.Buttons(0, 0), byte(input.BUTTON_A))
+}
+
+func TestHoldingHotkeys(t *testing.T) {
+    k := New(DefaultConfig())
+
+    assert.False(t, k.Holding(REWIND))
+    k.KeyDown("Backspace")
+    assert.True(t, k.Holding(REWIND))
+    k.KeyUp("Backspace")
+    assert.False(t, k.Holding(REWIND))
+}
+
+func TestBindTakesTheKeyAwayFromItsOldBinding(t *testing.T) {
+    k := New(DefaultConfig())
+    k.Bind(0, "Start", "P")
+
+    assert.Equal(t, k.KeyDown("P"), "")
+    assert.Equal(t, k.Buttons(0, 0), byte(input.BUTTON_START))
+
+    _, ok := k.Config().Hotkeys[PAUSE]
+    assert.False(t, ok)
+}
+
+func TestRebindingTakesTheNextKeys(t *testing.T) {
+    k := New(DefaultConfig())
+    k.Rebind(1)
+
+    player, button, ok := k.Rebinding()
+    assert.True(t, ok)
+    assert.Equal(t, player, 1)
+    assert.Equal(t, button, "A")
+
+    keys := []string { "1", "2", "3", "4", "5", "6", "7", "8", "9" }
+    for _, key := range keys {
+        assert.Equal(t, k.KeyDown(key), "")
+    }
+    assert.Equal(t, k.KeyDown("0"), REBOUND)
+
+    _, _, ok = k.Rebinding()
+    assert.False(t, ok)
+
+    k.KeyDown("4")
+    assert.Equal(t, k.Buttons(1, 0), byte(input.BUTTON_START))
+    assert.Equal(t, k.Config().Players[1][TURBO_B], "0")
+}
+
+func TestThirdAndFourthPlayersStartUnbound(t *testing.T) {
+    k := New(DefaultConfig())
+    assert.Equal(t, len(k.Config().Players[2]), 0)
+
+    k.Bind(2, "A", "Q")
+    k.KeyDown("Q")
+
+    assert.Equal(t, k.Buttons(2, 0), byte(input.BUTTON_A))
+}
+
+func TestBindingHotkeysWithoutAnyBound(t *testing.T) {
+    k := New(Config {})
+    k.Bind(-1, PAUSE, "P")
+
+    assert.Equal(t, k.KeyDown("P"), PAUSE)
+}
@@ -0,0 +1,295 @@
+package movie
+
+import (
+    "io"
+    "fmt"
+    "bufio"
+    "errors"
+    "strconv"
+    "strings"
+    "io/ioutil"
+    "archive/zip"
+    "encoding/hex"
+    "cpu"
+)
+
+// The buttons in a BK2 controller, in the order NesHawk logs them, and what
+// they're shown as.
+var BK2Buttons = []struct {
+    Name string
+    Mnemonic byte
+    Button byte
+} {
+    { "Up", 'U', 0x10 },
+    { "Down", 'D', 0x20 },
+    { "Left", 'L', 0x40 },
+    { "Right", 'R', 0x80 },
+    { "Start", 'S', 0x08 },
+    { "Select", 's', 0x04 },
+    { "B", 'B', 0x02 },
+    { "A", 'A', 0x01 },
+}
+
+// BizHawk's movies: a zip holding a header of "key value" lines and an
+// input log, whose LogKey line names each column of the frames that follow.
+// Columns for anything but the console's buttons and gamepads are ignored.
+// Movies starting from a savestate keep it in Core.bin, which has to be one
+// of ours.
+//
+// See -- http://tasvideos.org/Bizhawk/BK2Format.html
+func ReadBK2(r io.ReaderAt, size int64) (*Movie, error) {
+    archive, err := zip.NewReader(r, size)
+    if err != nil {
+        return nil, err
+    }
+
+    m := new(Movie)
+    header, log, fromState := false, false, false
+    var saved []byte
+
+    for _, file := range archive.File {
+        switch file.Name {
+            case "Header.txt":
+                err = readBK2File(file, func(scanner *bufio.Scanner) error {
+                    return readBK2Header(scanner, m, &fromState)
+                })
+                header = true
+            case "Input Log.txt":
+                err = readBK2File(file, func(scanner *bufio.Scanner) error {
+                    return readBK2Input(scanner, m)
+                })
+                log = true
+            case "Core.bin":
+                saved, err = readBK2State(file)
+        }
+
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    if !header || !log {
+        return nil, errors.New("the BK2 movie is missing its header or input log")
+    }
+
+    if fromState {
+        if !ourState(saved) {
+            return nil, errors.New("BK2 movies starting from BizHawk savestates aren't supported")
+        }
+
+        m.State = saved
+    }
+
+    return m, nil
+}
+
+func readBK2File(file *zip.File, read func(*bufio.Scanner) error) error {
+    contents, err := file.Open()
+    if err != nil {
+        return err
+    }
+    defer contents.Close()
+
+    scanner := bufio.NewScanner(contents)
+    scanner.Buffer(nil, 1 << 20)
+
+    if err = read(scanner); err != nil {
+        return err
+    }
+
+    return scanner.Err()
+}
+
+func readBK2State(file *zip.File) ([]byte, error) {
+    contents, err := file.Open()
+    if err != nil {
+        return nil, err
+    }
+    defer contents.Close()
+
+    return ioutil.ReadAll(contents)
+}
+
+func readBK2Header(scanner *bufio.Scanner, m *Movie, fromState *bool) error {
+    for scanner.Scan() {
+        fields := strings.SplitN(strings.TrimSpace(scanner.Text()), " ", 2)
+        key, value := fields[0], ""
+        if len(fields) > 1 {
+            value = fields[1]
+        }
+
+        switch key {
+            case "Platform":
+                if value != "NES" {
+                    return fmt.Errorf("the BK2 movie is for %s, not the NES", value)
+                }
+            case "GameName":
+                m.ROMName = value
+            case "SHA1":
+                sum, err := hex.DecodeString(value)
+                if err != nil {
+                    return fmt.Errorf("bad ROM checksum: %s", value)
+                }
+                m.SHA1 = sum
+            case "PAL":
+                if strings.EqualFold(value, "true") {
+                    m.Region = cpu.PAL
+                }
+            case "rerecordCount":
+                m.Rerecords, _ = strconv.Atoi(value)
+            case "StartsFromSavestate":
+                *fromState = strings.EqualFold(value, "true")
+            case "StartsFromSaveRam":
+                if strings.EqualFold(value, "true") {
+                    return errors.New("BK2 movies starting from save RAM aren't supported")
+                }
+        }
+    }
+
+    return nil
+}
+
+// What a column of the input log sets: a console command, or a player's
+// button.
+type bk2Column struct {
+    commands byte
+    player int
+    button byte
+}
+
+func readBK2Input(scanner *bufio.Scanner, m *Movie) error {
+    var columns []bk2Column
+
+    for scanner.Scan() {
+        line := strings.TrimSpace(scanner.Text())
+
+        switch {
+            case strings.HasPrefix(line, "LogKey:"):
+                columns = bk2Columns(strings.TrimPrefix(line, "LogKey:"), m)
+            case strings.HasPrefix(line, "|"):
+                if columns == nil {
+                    return errors.New("the BK2 input log has no LogKey")
+                }
+
+                m.Frames = append(m.Frames, readBK2Frame(line, columns))
+        }
+    }
+
+    return nil
+}
+
+func bk2Columns(key string, m *Movie) []bk2Column {
+    columns := []bk2Column {}
+
+    for _, name := range strings.Split(strings.Replace(key, "#", "|", -1), "|") {
+        var column bk2Column
+
+        switch {
+            case name == "":
+                continue
+            case name == "Reset":
+                column.commands = RESET
+            case name == "Power":
+                column.commands = POWER
+            case len(name) > 3 && name[0] == 'P' && name[2] == ' ' && name[1] >= '1' && name[1] <= '4':
+                column.player = int(name[1] - '1')
+                for _, button := range BK2Buttons {
+                    if button.Name == name[3:] {
+                        column.button = button.Button
+                    }
+                }
+
+                if column.player >= 2 {
+                    m.FourScore = true
+                }
+        }
+
+        columns = append(columns, column)
+    }
+
+    return columns
+}
+
+func readBK2Frame(line string, columns []bk2Column) Frame {
+    var frame Frame
+
+    states := strings.Replace(line, "|", "", -1)
+    for i, column := range columns {
+        if i >= len(states) || states[i] == '.' || states[i] == ' ' {
+            continue
+        }
+
+        frame.Commands |= column.commands
+        frame.Buttons[column.player] |= column.button
+    }
+
+    return frame
+}
+
+func WriteBK2(w io.Writer, m *Movie) error {
+    archive := zip.NewWriter(w)
+
+    header, err := archive.Create("Header.txt")
+    if err != nil {
+        return err
+    }
+
+    fmt.Fprintf(header, "MovieVersion BizHawk v2.0.0\nPlatform NES\nCore NesHawk\n")
+    fmt.Fprintf(header, "GameName %s\nSHA1 %X\nrerecordCount %d\n", m.ROMName, m.SHA1, m.Rerecords)
+    if m.Region == cpu.PAL {
+        fmt.Fprintf(header, "PAL True\n")
+    }
+
+    if m.State != nil {
+        fmt.Fprintf(header, "StartsFromSavestate True\n")
+
+        core, err := archive.Create("Core.bin")
+        if err != nil {
+            return err
+        }
+        if _, err = core.Write(m.State); err != nil {
+            return err
+        }
+    }
+
+    log, err := archive.Create("Input Log.txt")
+    if err != nil {
+        return err
+    }
+
+    out := bufio.NewWriter(log)
+    out.WriteString("[Input]\nLogKey:#Reset|Power|")
+    for player := 1; player <= m.Players(); player++ {
+        out.WriteByte('#')
+        for _, button := range BK2Buttons {
+            fmt.Fprintf(out, "P%d %s|", player, button.Name)
+        }
+    }
+    out.WriteByte('\n')
+
+    for _, frame := range m.Frames {
+        commands := []byte("..")
+        if frame.Commands & RESET != 0 { commands[0] = 'r' }
+        if frame.Commands & POWER != 0 { commands[1] = 'P' }
+
+        fmt.Fprintf(out, "|%s|", commands)
+        for player := 0; player < m.Players(); player++ {
+            for _, button := range BK2Buttons {
+                if frame.Buttons[player] & button.Button != 0 {
+                    out.WriteByte(button.Mnemonic)
+                } else {
+                    out.WriteByte('.')
+                }
+            }
+            out.WriteByte('|')
+        }
+        out.WriteByte('\n')
+    }
+    out.WriteString("[/Input]\n")
+
+    if err = out.Flush(); err != nil {
+        return err
+    }
+
+    return archive.Close()
+}
@@ -0,0 +1,72 @@
+package movie
+
+import (
+    "bytes"
+    "testing"
+    "archive/zip"
+    "github.com/stretchrcom/testify/assert"
+    "cpu"
+    "state"
+)
+
+func bk2Archive(files map[string]string) *bytes.Reader {
+    var out bytes.Buffer
+    archive := zip.NewWriter(&out)
+
+    for name, contents := range files {
+        file, _ := archive.Create(name)
+        file.Write([]byte(contents))
+    }
+    archive.Close()
+
+    return bytes.NewReader(out.Bytes())
+}
+
+func TestReadBK2(t *testing.T) {
+    r := bk2Archive(map[string]string {
+        "Header.txt": "MovieVersion BizHawk v2.0.0\nPlatform NES\nGameName Test\nSHA1 00FF\n",
+        "Input Log.txt": "[Input]\nLogKey:#Reset|Power|#P1 Up|P1 Down|P1 Left|P1 Right|P1 Start|P1 Select|P1 B|P1 A|#P2 Up|P2 Down|P2 Left|P2 Right|P2 Start|P2 Select|P2 B|P2 A|\n" +
+            "|..|........|........|\n|r.|U.....BA|....S...|\n[/Input]\n",
+    })
+
+    m, err := ReadBK2(r, r.Size())
+    assert.Equal(t, err, nil)
+
+    assert.Equal(t, m.ROMName, "Test")
+    assert.Equal(t, m.SHA1, []byte { 0x00, 0xff })
+    assert.Equal(t, m.Region, cpu.NTSC)
+    assert.Equal(t, m.FourScore, false)
+    assert.Equal(t, m.Frames, []Frame { { 0, [4]byte {} }, { RESET, [4]byte { 0x13, 0x08, 0, 0 } } })
+}
+
+func TestBK2RoundTrips(t *testing.T) {
+    m := &Movie { ROMName: "Test", SHA1: []byte { 1, 2, 3 }, Region: cpu.PAL, Rerecords: 3 }
+    m.State = []byte(state.MAGIC + "state")
+    m.Frames = []Frame { { 0, [4]byte { 0xff, 0x04 } }, { RESET | POWER, [4]byte {} } }
+
+    var out bytes.Buffer
+    assert.Equal(t, WriteBK2(&out, m), nil)
+
+    read, err := ReadBK2(bytes.NewReader(out.Bytes()), int64(out.Len()))
+    assert.Equal(t, err, nil)
+    assert.Equal(t, read, m)
+}
+
+func TestBK2RefusesBizHawkStates(t *testing.T) {
+    r := bk2Archive(map[string]string {
+        "Header.txt": "Platform NES\nSHA1 00FF\nStartsFromSavestate True\n",
+        "Input Log.txt": "[Input]\nLogKey:#Reset|Power|\n[/Input]\n",
+        "Core.bin": "BizHawk state",
+    })
+
+    _, err := ReadBK2(r, r.Size())
+    assert.NotEqual(t, err, nil)
+}
+
+func TestMovieChecksTheROMAndRegion(t *testing.T) {
+    m := &Movie { SHA1: []byte { 1 }, Region: cpu.NTSC }
+
+    assert.Equal(t, m.Check(nil, []byte { 1 }, cpu.NTSC), nil)
+    assert.NotEqual(t, m.Check(nil, []byte { 2 }, cpu.NTSC), nil)
+    assert.NotEqual(t, m.Check(nil, []byte { 1 }, cpu.PAL), nil)
+}
@@ -0,0 +1,186 @@
+package movie
+
+import (
+    "io"
+    "fmt"
+    "bufio"
+    "errors"
+    "strconv"
+    "strings"
+    "crypto/rand"
+    "encoding/base64"
+    "cpu"
+)
+
+// The buttons in an FM2 input field, from the highest bit to the lowest.
+const FM2_BUTTONS = "RLDUTSBA"
+
+// FCEUX's text movie format: a header of "key value" lines, then a line per
+// frame of "|commands|port0|port1|port2|", or with a Four Score
+// "|commands|p1|p2|p3|p4|". Binary movies, movies starting from FCEUX's
+// savestates rather than ours, and anything but gamepads aren't supported.
+//
+// See -- http://www.fceux.com/web/help/fm2.html
+func ReadFM2(r io.Reader) (*Movie, error) {
+    m := new(Movie)
+    ports := []string { "1", "1" }
+
+    scanner := bufio.NewScanner(r)
+    scanner.Buffer(nil, 1 << 20)
+
+    for scanner.Scan() {
+        line := strings.TrimRight(scanner.Text(), "\r")
+
+        if strings.HasPrefix(line, "|") {
+            frame, err := readFM2Frame(line, m.Players())
+            if err != nil {
+                return nil, err
+            }
+
+            m.Frames = append(m.Frames, frame)
+            continue
+        }
+
+        fields := strings.SplitN(line, " ", 2)
+        key, value := fields[0], ""
+        if len(fields) > 1 {
+            value = fields[1]
+        }
+
+        switch key {
+            case "binary":
+                if value != "0" && value != "false" {
+                    return nil, errors.New("binary FM2 movies aren't supported")
+                }
+            case "savestate":
+                data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, "base64:"))
+                if err != nil || !ourState(data) {
+                    return nil, errors.New("FM2 movies starting from FCEUX savestates aren't supported")
+                }
+                m.State = data
+            case "palFlag":
+                if value == "1" {
+                    m.Region = cpu.PAL
+                }
+            case "romFilename":
+                m.ROMName = value
+            case "romChecksum":
+                sum, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, "base64:"))
+                if err != nil {
+                    return nil, fmt.Errorf("bad ROM checksum: %s", value)
+                }
+                m.MD5 = sum
+            case "rerecordCount":
+                m.Rerecords, _ = strconv.Atoi(value)
+            case "fourscore":
+                m.FourScore = value == "1"
+            case "port0":
+                ports[0] = value
+            case "port1":
+                ports[1] = value
+        }
+    }
+
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+
+    // With a Four Score the ports are always gamepads.
+    if !m.FourScore {
+        for _, port := range ports {
+            if port != "0" && port != "1" {
+                return nil, errors.New("only gamepads are supported in FM2 movies")
+            }
+        }
+    }
+
+    return m, nil
+}
+
+func readFM2Frame(line string, players int) (Frame, error) {
+    var frame Frame
+
+    fields := strings.Split(line, "|")
+    if len(fields) < 2 + players {
+        return frame, fmt.Errorf("bad FM2 frame: %s", line)
+    }
+
+    commands, err := strconv.Atoi(fields[1])
+    if err != nil {
+        return frame, fmt.Errorf("bad FM2 frame: %s", line)
+    }
+
+    // Only resets and power cycles matter here; the rest are for the FDS
+    // and VS. System.
+    frame.Commands = byte(commands) & (RESET | POWER)
+
+    for player := 0; player < players; player++ {
+        for i, c := range fields[2 + player] {
+            if i < len(FM2_BUTTONS) && c != '.' && c != ' ' {
+                frame.Buttons[player] |= 0x80 >> uint(i)
+            }
+        }
+    }
+
+    return frame, nil
+}
+
+func WriteFM2(w io.Writer, m *Movie) error {
+    if m.Region == cpu.Dendy {
+        return errors.New("FM2 movies can't be recorded on a Dendy")
+    }
+
+    palFlag := 0
+    if m.Region == cpu.PAL {
+        palFlag = 1
+    }
+
+    fourScore, port := 0, 1
+    if m.FourScore {
+        fourScore, port = 1, 0
+    }
+
+    guid := make([]byte, 16)
+    if _, err := rand.Read(guid); err != nil {
+        return err
+    }
+
+    out := bufio.NewWriter(w)
+
+    // FCEUX wants an emulator version it knows.
+    fmt.Fprintf(out, "version 3\nemuVersion 22020\nrerecordCount %d\npalFlag %d\n", m.Rerecords, palFlag)
+    fmt.Fprintf(out, "romFilename %s\nromChecksum base64:%s\n", m.ROMName, base64.StdEncoding.EncodeToString(m.MD5))
+    fmt.Fprintf(out, "guid %X-%X-%X-%X-%X\n", guid[0:4], guid[4:6], guid[6:8], guid[8:10], guid[10:])
+    if m.State != nil {
+        fmt.Fprintf(out, "savestate base64:%s\n", base64.StdEncoding.EncodeToString(m.State))
+    }
+    fmt.Fprintf(out, "fourscore %d\nmicrophone 0\nport0 %d\nport1 %d\nport2 0\nFDS 0\nNewPPU 0\n", fourScore, port, port)
+
+    for _, frame := range m.Frames {
+        fmt.Fprintf(out, "|%d|", frame.Commands)
+
+        for player := 0; player < m.Players(); player++ {
+            out.WriteString(fm2Buttons(frame.Buttons[player]))
+            out.WriteByte('|')
+        }
+
+        if !m.FourScore {
+            out.WriteByte('|')
+        }
+        out.WriteByte('\n')
+    }
+
+    return out.Flush()
+}
+
+func fm2Buttons(buttons byte) string {
+    field := []byte(FM2_BUTTONS)
+
+    for i := range field {
+        if buttons & (0x80 >> uint(i)) == 0 {
+            field[i] = '.'
+        }
+    }
+
+    return string(field)
+}
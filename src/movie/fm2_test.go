@@ -0,0 +1,63 @@
+package movie
+
+import (
+    "bytes"
+    "strings"
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+    "cpu"
+    "state"
+)
+
+const fm2Movie = `version 3
+emuVersion 22020
+rerecordCount 12
+palFlag 1
+romFilename Test Game
+romChecksum base64:AAECAwQFBgcICQoLDA0ODw==
+guid 452DE2C3-EF43-2FA9-77AC-0677FC51543B
+fourscore 0
+port0 1
+port1 1
+port2 0
+comment author somebody
+|0|........|........||
+|1|R......A|....T...||
+|0|.L.U..B.|........||
+`
+
+func TestReadFM2(t *testing.T) {
+    m, err := ReadFM2(strings.NewReader(fm2Movie))
+    assert.Equal(t, err, nil)
+
+    assert.Equal(t, m.ROMName, "Test Game")
+    assert.Equal(t, m.Region, cpu.PAL)
+    assert.Equal(t, m.MD5, []byte { 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15 })
+    assert.Equal(t, m.Rerecords, 12)
+    assert.Equal(t, m.FourScore, false)
+
+    assert.Equal(t, len(m.Frames), 3)
+    assert.Equal(t, m.Frames[1], Frame { RESET, [4]byte { 0x81, 0x08, 0, 0 } })
+    assert.Equal(t, m.Frames[2], Frame { 0, [4]byte { 0x52, 0, 0, 0 } })
+}
+
+func TestFM2RoundTrips(t *testing.T) {
+    m := &Movie { ROMName: "Test", MD5: make([]byte, 16), FourScore: true, State: []byte(state.MAGIC) }
+    m.Frames = []Frame { { 0, [4]byte { 0x01, 0x02, 0x40, 0x80 } }, { RESET, [4]byte {} } }
+
+    var out bytes.Buffer
+    assert.Equal(t, WriteFM2(&out, m), nil)
+    assert.Equal(t, strings.Contains(out.String(), "\n|0|.......A|......B.|.L......|R.......|\n"), true)
+
+    read, err := ReadFM2(&out)
+    assert.Equal(t, err, nil)
+    assert.Equal(t, read, m)
+}
+
+func TestFM2RefusesOtherDevices(t *testing.T) {
+    _, err := ReadFM2(strings.NewReader("version 3\nport1 2\n"))
+    assert.NotEqual(t, err, nil)
+
+    _, err = ReadFM2(strings.NewReader("version 3\nsavestate base64:AAAA\n"))
+    assert.NotEqual(t, err, nil)
+}
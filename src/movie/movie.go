@@ -0,0 +1,118 @@
+package movie
+
+import (
+    "os"
+    "fmt"
+    "bytes"
+    "errors"
+    "strings"
+    "path/filepath"
+    "cpu"
+    "state"
+)
+
+// Console buttons pressed at the start of a frame.
+const (
+    RESET = 1 << iota
+    POWER
+)
+
+// What's pressed during one frame: the console's buttons, and each player's
+// controller in the order input.Ports numbers them.
+type Frame struct {
+    Commands byte
+    Buttons [4]byte
+}
+
+// A run of per-frame input, recorded from power-on or from a savestate, and
+// the ROM and region it was recorded against.
+type Movie struct {
+    ROMName string
+    Region cpu.Region
+
+    // Hashes of the ROM's program and graphics data, without the iNES
+    // header. FM2 movies carry an MD5 and BK2 movies a SHA-1; either may be
+    // missing.
+    MD5 []byte
+    SHA1 []byte
+
+    // Whether the players beyond the second are plugged in through a Four
+    // Score.
+    FourScore bool
+
+    // The savestate the movie starts from, or nil to start from power-on.
+    State []byte
+
+    Rerecords int
+    Frames []Frame
+}
+
+func (m *Movie) Players() int {
+    if m.FourScore {
+        return 4
+    }
+
+    return 2
+}
+
+// Checks the movie was recorded against this ROM and region.
+func (m *Movie) Check(md5 []byte, sha1 []byte, region cpu.Region) error {
+    if m.MD5 == nil && m.SHA1 == nil {
+        return errors.New("the movie doesn't say which ROM it's for")
+    }
+
+    if m.MD5 != nil && !bytes.Equal(m.MD5, md5) || m.SHA1 != nil && !bytes.Equal(m.SHA1, sha1) {
+        return fmt.Errorf("the movie was recorded with a different ROM (%s)", m.ROMName)
+    }
+
+    if m.Region != region {
+        return fmt.Errorf("the movie was recorded on %s, not %s", m.Region, region)
+    }
+
+    return nil
+}
+
+// Only our own savestates can be played back from.
+func ourState(data []byte) bool {
+    return bytes.HasPrefix(data, []byte(state.MAGIC))
+}
+
+// Reads an FM2 or, going by the extension, BK2 movie.
+func Load(path string) (*Movie, error) {
+    file, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    if !strings.EqualFold(filepath.Ext(path), ".bk2") {
+        return ReadFM2(file)
+    }
+
+    info, err := file.Stat()
+    if err != nil {
+        return nil, err
+    }
+
+    return ReadBK2(file, info.Size())
+}
+
+// Writes a movie as FM2 or, going by the extension, BK2.
+func Save(path string, m *Movie) error {
+    file, err := os.Create(path)
+    if err != nil {
+        return err
+    }
+
+    if strings.EqualFold(filepath.Ext(path), ".bk2") {
+        err = WriteBK2(file, m)
+    } else {
+        err = WriteFM2(file, m)
+    }
+
+    if closeErr := file.Close(); err == nil {
+        err = closeErr
+    }
+
+    return err
+}
@@ -0,0 +1,119 @@
+package nes
+
+import (
+    "cpu"
+    "ppu"
+)
+
+// The 2A03's DMA unit, which copies sprites into OAM when $4014 is written and
+// fetches samples for the DMC. Either halts the CPU on its next read cycle and
+// takes over the bus. The unit alternates between get (read) and put (write)
+// cycles, and can only read on a get cycle, so it may spend a cycle lining
+// up with them.
+//
+// See -- http://wiki.nesdev.com/w/index.php/DMA
+type DMA struct {
+    CPU *cpu.CPU
+    PPU *ppu.PPU
+
+    oam bool
+    page byte
+
+    dmc bool
+    dmcAddress cpu.Address
+    dmcDone func(byte)
+}
+
+func NewDMA(c *cpu.CPU, p *ppu.PPU) *DMA {
+    d := new(DMA)
+
+    d.CPU = c
+    d.PPU = p
+
+    return d
+}
+
+// Schedules a copy of $XX00-$XXFF to OAM.
+func (d *DMA) StartOAM(page byte) {
+    d.oam = true
+    d.page = page
+}
+
+// Schedules a fetch of a single DMC sample byte, which is handed to done.
+func (d *DMA) RequestDMC(location cpu.Address, done func(byte)) {
+    d.dmc = true
+    d.dmcAddress = location
+    d.dmcDone = done
+}
+
+func (d *DMA) Active() bool {
+    return d.oam || d.dmc
+}
+
+func (d *DMA) get() bool {
+    return d.CPU.Cycles() % 2 == 0
+}
+
+// Spends a cycle repeating the read the CPU was halted on, as the real CPU
+// does while it waits.
+func (d *DMA) wait(halted cpu.Address) {
+    d.CPU.Tick()
+    d.CPU.Memory.Read(halted)
+}
+
+func (d *DMA) read(location cpu.Address) byte {
+    d.CPU.Tick()
+    return d.CPU.Memory.Read(location)
+}
+
+func (d *DMA) fetchDMC() {
+    d.dmc = false
+    d.dmcDone(d.read(d.dmcAddress))
+}
+
+// Runs any pending DMA before the CPU's read of halted. Installed as the
+// CPU's DMA hook.
+func (d *DMA) Halt(halted cpu.Address) {
+    if !d.Active() {
+        return
+    }
+
+    // The halt cycle
+    d.wait(halted)
+
+    if d.oam {
+        d.oam = false
+
+        if !d.get() {
+            d.wait(halted)
+        }
+
+        base := cpu.Address(d.page) << 8
+        for i := 0; i < 256; {
+            // A DMC fetch in the middle of OAM DMA takes the get cycle, then
+            // the OAM copy needs a put cycle to get back in step.
+            if d.dmc && d.get() {
+                d.fetchDMC()
+                d.wait(halted)
+                continue
+            }
+
+            value := d.read(base + cpu.Address(i))
+
+            d.CPU.Tick()
+            d.PPU.Write(value, ppu.OAMDATA)
+
+            i++
+        }
+    }
+
+    if d.dmc {
+        // On its own the DMC needs a dummy cycle after the halt, then a get.
+        d.wait(halted)
+        if !d.get() {
+            d.wait(halted)
+        }
+
+        d.fetchDMC()
+    }
+}
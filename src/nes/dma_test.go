@@ -0,0 +1,111 @@
+package nes
+
+import (
+    "cpu"
+    "input"
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+// Writes $4014 then makes the read the DMA halts, returning the number of
+// cycles the read took.
+func runOAMDMA(m *Machine, page byte) int {
+    m.CPU.Write(page, 0x4014)
+
+    before := m.CPU.Cycles()
+    m.CPU.Read(0x0000)
+
+    return m.CPU.Cycles() - before
+}
+
+func TestOAMDMACopiesPageToOAM(t *testing.T) {
+    m := NewMachine()
+    for i := 0; i < 256; i++ {
+        m.CPU.Memory.Write(byte(i), cpu.Address(0x0200 + i))
+    }
+
+    m.PPU.OAMAddr = 0x10
+    runOAMDMA(m, 0x02)
+
+    assert.Equal(t, m.PPU.OAMRAM[0x10], byte(0x00))
+    assert.Equal(t, m.PPU.OAMRAM[0xff], byte(0xef))
+    assert.Equal(t, m.PPU.OAMRAM[0x00], byte(0xf0))
+    assert.Equal(t, m.PPU.OAMAddr, uint8(0x10))
+}
+
+func TestOAMDMAStallsForAlignment(t *testing.T) {
+    m := NewMachine()
+
+    // After the write and the halt cycle the DMA is already on a get cycle.
+    assert.Equal(t, runOAMDMA(m, 0x02), 513 + 1)
+
+    // The first DMA took an odd number of cycles, so this one has to wait a
+    // cycle to line up.
+    assert.Equal(t, runOAMDMA(m, 0x02), 514 + 1)
+}
+
+func TestOAMDMAKeepsThePPURunning(t *testing.T) {
+    m := NewMachine()
+    m.PPU.Scanline = 0
+    m.PPU.Cycle = 0
+
+    cycles := runOAMDMA(m, 0x02)
+    dots := m.PPU.Scanline * 341 + m.PPU.Cycle
+
+    // The write's dots are before the DMA's.
+    assert.Equal(t, dots, (cycles + 1) * 3)
+}
+
+func TestDMCFetchStealsCyclesFromOAMDMA(t *testing.T) {
+    m := NewMachine()
+    m.CPU.Memory.Write(0x42, 0x0300)
+
+    var sample byte
+    m.DMA.RequestDMC(0x0300, func(value byte) { sample = value })
+
+    assert.Equal(t, runOAMDMA(m, 0x02), 513 + 2 + 1)
+    assert.Equal(t, sample, byte(0x42))
+}
+
+func TestDMCFetchHaltsTheCPU(t *testing.T) {
+    m := NewMachine()
+
+    m.DMA.RequestDMC(0x0300, func(value byte) {})
+
+    before := m.CPU.Cycles()
+    m.CPU.Read(0x0000)
+    assert.Equal(t, m.CPU.Cycles() - before, 3 + 1)
+
+    m.CPU.Tick()
+    m.DMA.RequestDMC(0x0300, func(value byte) {})
+
+    before = m.CPU.Cycles()
+    m.CPU.Read(0x0000)
+    assert.Equal(t, m.CPU.Cycles() - before, 4 + 1)
+}
+
+func TestDMCFetchRepeatsTheHaltedRead(t *testing.T) {
+    m := NewMachine()
+    m.PPU.VRAMAddr = 0x2000
+
+    m.DMA.RequestDMC(0x0300, func(value byte) {})
+    m.CPU.Read(0x2007)
+
+    // Reading PPUDATA increments the address once for the CPU's read, and
+    // again for each time the DMA repeated it.
+    assert.Equal(t, m.PPU.VRAMAddr, cpu.Address(0x2003))
+}
+
+func TestDMCFetchDuringControllerReadDeletesABit(t *testing.T) {
+    m := NewMachine()
+    m.SetButtons(0, input.BUTTON_A | input.BUTTON_SELECT)
+    m.CPU.Write(0x01, 0x4016)
+    m.CPU.Write(0x00, 0x4016)
+
+    // The halted reads clock the controller once, then the read after the
+    // fetch clocks it again, so B is lost.
+    m.DMA.RequestDMC(0x0300, func(value byte) {})
+
+    assert.Equal(t, readController(m, 0x4016), byte(0x40))
+    assert.Equal(t, readController(m, 0x4016), byte(0x41))
+}
@@ -0,0 +1,145 @@
+package nes
+
+import (
+    "cpu"
+    "ppu"
+    "fmt"
+)
+
+type EventKind int
+
+const (
+    PPURegisterRead EventKind = iota
+    PPURegisterWrite
+    OAMDMAWrite
+    MapperWrite
+    NMIEvent
+    IRQEvent
+)
+
+var EventKindNames = map[EventKind]string {
+    PPURegisterRead:  "read",
+    PPURegisterWrite: "write",
+    OAMDMAWrite:      "dma",
+    MapperWrite:      "mapper",
+    NMIEvent:         "nmi",
+    IRQEvent:         "irq",
+}
+
+// Something that happened at a particular point in the frame. Address and
+// Value are the CPU address and byte of register accesses, and Value is the
+// interrupt kind of interrupts.
+type Event struct {
+    Kind EventKind
+    Address cpu.Address
+    Value byte
+
+    Frame int
+    Scanline int
+    Dot int
+}
+
+func (e Event) String() string {
+    var what string
+    switch e.Kind {
+        case PPURegisterRead:
+            what = fmt.Sprintf("read  $%04x = $%02x", e.Address, e.Value)
+        case NMIEvent, IRQEvent:
+            what = EventKindNames[e.Kind]
+        default:
+            what = fmt.Sprintf("write $%04x = $%02x", e.Address, e.Value)
+    }
+
+    return fmt.Sprintf("frame %d scanline %3d dot %3d: %s", e.Frame, e.Scanline, e.Dot, what)
+}
+
+// A log of the PPU register accesses, mapper register writes and interrupts
+// of the current and previous frames, timestamped with where the PPU was when
+// they happened.
+type EventLog struct {
+    PPU *ppu.PPU
+
+    frame int
+    current []Event
+    previous []Event
+
+    // Where interrupts are passed on to after they're logged.
+    bus cpu.Bus
+}
+
+func NewEventLog(p *ppu.PPU, bus cpu.Bus) *EventLog {
+    l := new(EventLog)
+
+    l.PPU = p
+    l.frame = p.Frame
+    l.bus = bus
+
+    return l
+}
+
+// Starts a new frame's log if the PPU has moved on since the last event.
+func (l *EventLog) sync() {
+    if l.PPU.Frame == l.frame {
+        return
+    }
+
+    if l.PPU.Frame == l.frame + 1 {
+        l.previous = l.current
+    } else {
+        l.previous = nil
+    }
+
+    l.current = nil
+    l.frame = l.PPU.Frame
+}
+
+func (l *EventLog) Record(kind EventKind, location cpu.Address, value byte) {
+    l.sync()
+
+    l.current = append(l.current, Event {
+        kind, location, value,
+        l.PPU.Frame, l.PPU.Scanline, l.PPU.Cycle,
+    })
+}
+
+// The events so far this frame.
+func (l *EventLog) Current() []Event {
+    l.sync()
+    return l.current
+}
+
+// The events of the whole of the last frame.
+func (l *EventLog) Previous() []Event {
+    l.sync()
+    return l.previous
+}
+
+// Classifies a CPU access, logging it if it's one of the interesting ones.
+func (l *EventLog) Access(location cpu.Address, value byte, write bool) {
+    switch {
+        case location >= 0x2000 && location < 0x4000:
+            if write {
+                l.Record(PPURegisterWrite, location, value)
+            } else {
+                l.Record(PPURegisterRead, location, value)
+            }
+        case location == 0x4014 && write:
+            l.Record(OAMDMAWrite, location, value)
+        case write && ((location >= 0x4020 && location < 0x6000) || location >= 0x8000):
+            l.Record(MapperWrite, location, value)
+    }
+}
+
+func (l *EventLog) Interrupt(kind int) {
+    if kind == cpu.NMI {
+        l.Record(NMIEvent, 0, byte(kind))
+    } else {
+        l.Record(IRQEvent, 0, byte(kind))
+    }
+
+    l.bus.Interrupt(kind)
+}
+
+func (l *EventLog) Cancel(kind int) {
+    l.bus.Cancel(kind)
+}
@@ -0,0 +1,81 @@
+package nes
+
+import (
+    "cpu"
+    "ppu"
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func TestEventLogRecordsBeamPosition(t *testing.T) {
+    m := NewMachine()
+    l := m.LogEvents()
+
+    m.PPU.Scanline = 100
+    m.PPU.Cycle = 200
+    l.Access(0x2005, 0x12, true)
+
+    e := l.Current()[0]
+    assert.Equal(t, e.Kind, PPURegisterWrite)
+    assert.Equal(t, e.Address, cpu.Address(0x2005))
+    assert.Equal(t, e.Value, byte(0x12))
+    assert.Equal(t, e.Scanline, 100)
+    assert.Equal(t, e.Dot, 200)
+}
+
+func TestEventLogClassifiesAccesses(t *testing.T) {
+    m := NewMachine()
+    l := m.LogEvents()
+
+    l.Access(0x2002, 0x80, false)
+    l.Access(0x4014, 0x02, true)
+    l.Access(0x8000, 0x01, true)
+    l.Access(0x8000, 0x01, false)
+    l.Access(0x0300, 0x01, true)
+
+    events := l.Current()
+    assert.Equal(t, len(events), 3)
+    assert.Equal(t, events[0].Kind, PPURegisterRead)
+    assert.Equal(t, events[1].Kind, OAMDMAWrite)
+    assert.Equal(t, events[2].Kind, MapperWrite)
+}
+
+func TestEventLogKeepsThePreviousFrame(t *testing.T) {
+    m := NewMachine()
+    l := m.LogEvents()
+
+    l.Access(0x2001, 0x1e, true)
+    m.PPU.Frame++
+    l.Access(0x2000, 0x80, true)
+
+    assert.Equal(t, len(l.Previous()), 1)
+    assert.Equal(t, l.Previous()[0].Address, cpu.Address(0x2001))
+    assert.Equal(t, len(l.Current()), 1)
+
+    m.PPU.Frame += 2
+    assert.Equal(t, len(l.Previous()), 0)
+    assert.Equal(t, len(l.Current()), 0)
+}
+
+func TestEventLogSeesNMIs(t *testing.T) {
+    m := NewMachine()
+    l := m.LogEvents()
+
+    m.PPU.Ctrl.Set(0x80)
+    for m.PPU.Scanline != m.PPU.VBlankScanline || m.PPU.Cycle != 2 {
+        m.PPU.Step()
+    }
+
+    events := l.Current()
+    assert.Equal(t, events[len(events)-1].Kind, NMIEvent)
+    assert.Equal(t, events[len(events)-1].Scanline, ppu.POSTRENDER_SCANLINE + 1)
+}
+
+func TestCPUAccessesAreLogged(t *testing.T) {
+    m := NewMachine()
+    l := m.LogEvents()
+
+    m.CPU.Write(0x00, 0x2003)
+
+    assert.Equal(t, l.Current()[0].Address, cpu.Address(0x2003))
+}
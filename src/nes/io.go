@@ -0,0 +1,113 @@
+package nes
+
+import (
+    "cpu"
+    "apu"
+    "vgm"
+    "input"
+)
+
+const (
+    OAMDMA = 0x0014
+    CONTROLLER1 = 0x0016
+    CONTROLLER2 = 0x0017
+)
+
+// The controller ports only drive the low data lines; the rest keep what
+// was last on the bus, which for an absolute read of $4016 or $4017 is the
+// high byte of the address.
+const OPEN_BUS = 0x40
+
+// The registers at $4000-$401F: the APU, OAM DMA and the controllers.
+type IO struct {
+    APU *apu.APU
+    DMA *DMA
+    Ports *input.Ports
+
+    // VGM is nil unless the APU's writes are being logged.
+    VGM *vgm.Writer
+
+    // The last value written to each of the APU's registers, so that logs
+    // started part way through a game know how it's set up.
+    registers [0x18]byte
+
+    // The last controller read, to tell whether the next one follows it.
+    lastPort int
+    lastCycle int
+}
+
+func NewIO(a *apu.APU, dma *DMA, ports *input.Ports) *IO {
+    io := new(IO)
+
+    io.APU = a
+    io.DMA = dma
+    io.Ports = ports
+    io.lastCycle = -1
+
+    return io
+}
+
+func (io *IO) Read(location cpu.Address) byte {
+    switch {
+        case location == CONTROLLER1 || location == CONTROLLER2:
+            return io.readPort(int(location - CONTROLLER1))
+        default:
+            return io.APU.Read(location)
+    }
+}
+
+// A port's devices are clocked as its enable line goes low at the start of
+// a read, and it stays low through reads on consecutive cycles, so the
+// repeated reads while the CPU's halted for DMA only clock them once. A DMC
+// fetch breaks that up though, costing the game a bit.
+//
+// See -- http://wiki.nesdev.com/w/index.php/DMA#Register_conflicts
+func (io *IO) readPort(port int) byte {
+    cycle := io.DMA.CPU.Cycles()
+    clock := port != io.lastPort || cycle != io.lastCycle + 1
+
+    io.lastPort = port
+    io.lastCycle = cycle
+
+    return OPEN_BUS | io.Ports.Read(port, clock)
+}
+
+// Memory hands debug reads the full address.
+func (io *IO) ReadDebug(location cpu.Address) byte {
+    location &= 0x1f
+
+    switch {
+        case location == CONTROLLER1 || location == CONTROLLER2:
+            return OPEN_BUS | io.Ports.Read(int(location - CONTROLLER1), false)
+        default:
+            return io.APU.ReadDebug(location)
+    }
+}
+
+func (io *IO) Write(val byte, location cpu.Address) {
+    switch {
+        case location == OAMDMA:
+            io.DMA.StartOAM(val)
+        case location == CONTROLLER1:
+            io.Ports.Write(val)
+        case location <= apu.FRAME_COUNTER:
+            io.registers[location] = val
+            io.APU.Write(val, location)
+
+            if io.VGM != nil {
+                io.VGM.Write(val, 0x4000 + location)
+            }
+    }
+}
+
+// Starts logging APU writes, first replaying the last write to each of its
+// registers.
+func (io *IO) Record(v *vgm.Writer) {
+    io.VGM = v
+
+    for location := cpu.Address(0x00); location <= apu.FRAME_COUNTER; location++ {
+        if location != OAMDMA && location != CONTROLLER1 {
+            v.Write(io.registers[location], 0x4000 + location)
+        }
+    }
+}
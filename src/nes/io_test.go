@@ -0,0 +1,81 @@
+package nes
+
+import (
+    "cpu"
+    "input"
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+// Reads a controller port the way LDA would, a few cycles after anything
+// else.
+func readController(m *Machine, location cpu.Address) byte {
+    m.CPU.Tick()
+    return m.CPU.Read(location)
+}
+
+func TestControllersAreReadThroughTheirPorts(t *testing.T) {
+    m := NewMachine()
+    m.SetButtons(0, input.BUTTON_A)
+    m.SetButtons(1, input.BUTTON_B)
+
+    m.CPU.Write(0x01, 0x4016)
+    m.CPU.Write(0x00, 0x4016)
+
+    assert.Equal(t, readController(m, 0x4016), byte(0x41))
+    assert.Equal(t, readController(m, 0x4016), byte(0x40))
+
+    assert.Equal(t, readController(m, 0x4017), byte(0x40))
+    assert.Equal(t, readController(m, 0x4017), byte(0x41))
+}
+
+func TestDebugReadsDontClockControllers(t *testing.T) {
+    m := NewMachine()
+    m.SetButtons(0, input.BUTTON_A)
+    m.CPU.Write(0x01, 0x4016)
+    m.CPU.Write(0x00, 0x4016)
+
+    assert.Equal(t, m.CPU.Memory.ReadDebug(0x4016), byte(0x41))
+    assert.Equal(t, readController(m, 0x4016), byte(0x41))
+}
+
+func TestUnpluggedPortsReadOpenBus(t *testing.T) {
+    m := NewMachine()
+    m.Plug(1, nil)
+
+    assert.Equal(t, readController(m, 0x4017), byte(0x40))
+}
+
+func TestZapperReadsThePPUsPicture(t *testing.T) {
+    m := NewMachine()
+    m.Plug(1, input.NewZapper(m.PPU))
+    m.SetPointer(1, 30, 40, true)
+
+    offset := (40 * 256 + 30) * 3
+    m.PPU.Display[offset], m.PPU.Display[offset+1], m.PPU.Display[offset+2] = 0xff, 0xff, 0xff
+
+    m.PPU.Scanline, m.PPU.Cycle = 45, 0
+    assert.Equal(t, readController(m, 0x4017), byte(0x50))
+
+    m.PPU.Display[offset] = 0x00
+    m.PPU.Display[offset+1] = 0x00
+    assert.Equal(t, readController(m, 0x4017), byte(0x58))
+}
+
+func TestFourScoreGivesFourPlayers(t *testing.T) {
+    m := NewMachine()
+    m.AttachMultitap(input.FOUR_SCORE)
+
+    assert.True(t, m.SetButtons(3, input.BUTTON_A))
+    m.CPU.Write(0x01, 0x4016)
+    m.CPU.Write(0x00, 0x4016)
+
+    for i := 0; i < 8; i++ {
+        readController(m, 0x4017)
+    }
+
+    assert.Equal(t, readController(m, 0x4017), byte(0x41))
+
+    m.AttachMultitap(input.NO_MULTITAP)
+    assert.False(t, m.SetButtons(3, input.BUTTON_A))
+}
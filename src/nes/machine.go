@@ -1,13 +1,57 @@
 package nes
 
 import (
+    "io"
     "cpu"
     "ppu"
+    "apu"
+    "vgm"
+    "audio"
+    "input"
+    "movie"
 )
 
 type Machine struct {
     CPU *cpu.CPU
     PPU *ppu.PPU
+    APU *apu.APU
+    DMA *DMA
+    IO *IO
+    Ports *input.Ports
+
+    Region cpu.Region
+
+    // The cartridge, once one's inserted.
+    ROM *ROM
+
+    // Battery backed or work RAM at $6000-$7FFF.
+    WRAM *cpu.RAM
+
+    // Events is nil until LogEvents is called.
+    Events *EventLog
+
+    // Audio is nil until PlayAudio is called.
+    Audio *audio.Pipeline
+
+    // Movie is nil unless one's being recorded or played back.
+    Movie *movie.Movie
+    playing bool
+    movieFrame int
+    movieStart int
+    rerecording bool
+    commands byte
+
+    // Rewind is nil unless EnableRewind has been called.
+    Rewind *Rewind
+
+    // RunAhead is nil unless EnableRunAhead has been called.
+    RunAhead *RunAhead
+
+    multitap int
+
+    // PPU dots owed to the PPU, in units of 1/CPUCycles dots, so that PAL's
+    // 3.2 dots per CPU cycle can be stepped a whole dot at a time.
+    dots int
 }
 
 func NewMachine() *Machine {
@@ -18,21 +62,195 @@ func NewMachine() *Machine {
 
     m.CPU.Memory.Mount(m.PPU, 0x2000, 0x3fff)
 
-    // Swallow anything to the APU right now
-    // TODO: Mount a real APU here.
-    m.CPU.Memory.Mount(cpu.NewRAM(0x0020), 0x4000, 0x401f)
+    m.DMA = NewDMA(m.CPU, m.PPU)
+    m.APU = apu.NewAPU()
+    m.Ports = input.NewPorts()
+    m.AttachMultitap(input.NO_MULTITAP)
+
+    m.IO = NewIO(m.APU, m.DMA, m.Ports)
+    m.CPU.Memory.Mount(m.IO, 0x4000, 0x401f)
+    m.CPU.DMA = m.DMA.Halt
+    m.APU.DMA = m.DMA.RequestDMC
 
     // Mount Battery Backed Save or Work RAM
     // TODO: Do some mappers do something with this?
-    m.CPU.Memory.Mount(cpu.NewRAM(0x2000), 0x6000, 0x7fff)
+    m.WRAM = cpu.NewRAM(0x2000)
+    m.CPU.Memory.Mount(m.WRAM, 0x6000, 0x7fff)
 
     // Setup the interrupt bus to call methods on the CPU
     m.PPU.Bus = m.CPU
+    m.APU.Bus = m.CPU
+
+    // Everything else is clocked off the CPU
+    m.CPU.Cycle = m.Cycle
+
+    m.SetRegion(cpu.NTSC)
 
     return m
 }
 
+func (m *Machine) SetRegion(region cpu.Region) {
+    m.Region = region
+    m.PPU.SetRegion(region)
+    m.APU.SetRegion(region)
+    m.dots = 0
+}
+
+// Starts logging PPU register accesses, mapper writes and interrupts.
+func (m *Machine) LogEvents() *EventLog {
+    if m.Events == nil {
+        m.Events = NewEventLog(m.PPU, m.CPU)
+
+        m.CPU.Access = m.Events.Access
+        m.PPU.Bus = m.Events
+        m.APU.Bus = m.Events
+    }
+
+    return m.Events
+}
+
+// Presses the reset button.
+func (m *Machine) Reset() {
+    m.commands |= movie.RESET
+
+    m.PPU.Reset()
+    m.APU.Reset()
+    m.CPU.SoftReset()
+}
+
+// Sends the APU's output to a sink at the given sample rate. The resampling
+// is based on the current region's clock rate, so set the region first.
+func (m *Machine) PlayAudio(rate int, sink audio.AudioSink) *audio.Pipeline {
+    m.Audio = audio.NewPipeline(m.Region.Timing().Clock, rate, sink)
+    return m.Audio
+}
+
+// Plugs a device into a controller port, numbered from 0. Safe to call
+// while the machine is running.
+func (m *Machine) Plug(port int, device input.InputDevice) {
+    m.Ports.Plug(port, device)
+}
+
+// Sets the buttons a player's holding, numbered from 0. Safe to call while
+// the machine is running.
+func (m *Machine) SetButtons(player int, buttons byte) bool {
+    return m.Ports.SetButtons(player, buttons)
+}
+
+// Sets the machine up for two players with a controller in each port, or
+// four through a multitap: a Four Score across both ports, or a Hori
+// adapter in the Famicom's expansion port, which leaves the ports empty.
+func (m *Machine) AttachMultitap(kind int) {
+    m.multitap = kind
+
+    switch kind {
+        case input.FOUR_SCORE:
+            tap := input.NewFourScore()
+            m.Ports.Plug(0, tap.Port(0))
+            m.Ports.Plug(1, tap.Port(1))
+            m.Ports.PlugExpansion(nil)
+        case input.HORI_ADAPTER:
+            m.Ports.Plug(0, nil)
+            m.Ports.Plug(1, nil)
+            m.Ports.PlugExpansion(input.NewHoriAdapter())
+        default:
+            m.Ports.Plug(0, input.NewController())
+            m.Ports.Plug(1, input.NewController())
+            m.Ports.PlugExpansion(nil)
+    }
+}
+
+// Plugs a device into the Famicom's expansion port. Safe to call while the
+// machine is running.
+func (m *Machine) PlugExpansion(device input.ExpansionDevice) {
+    m.Ports.PlugExpansion(device)
+}
+
+// Points a port's light gun or other pointing device, with port 2 being the
+// expansion port. Safe to call while the machine is running.
+func (m *Machine) SetPointer(port int, x int, y int, trigger bool) bool {
+    return m.Ports.SetPointer(port, x, y, trigger)
+}
+
+// Presses or releases a key on a plugged in keyboard. Safe to call while the
+// machine is running.
+func (m *Machine) SetKey(name string, pressed bool) bool {
+    return m.Ports.SetKey(name, pressed)
+}
+
+// Runs f while the machine can't read its input devices, for changing their
+// state directly.
+func (m *Machine) UpdateInput(f func()) {
+    m.Ports.Update(f)
+}
+
+// Starts logging the APU's register writes to a VGM file. Only one log can
+// be recorded at a time, and the cartridge's own sound isn't in it.
+func (m *Machine) RecordVGM(w io.WriteSeeker) *vgm.Writer {
+    m.StopVGM()
+
+    recorder := vgm.NewWriter(w, m.Region.Timing().Clock, m.CPU.Cycles, m.CPU.Memory.ReadDebug)
+    m.IO.Record(recorder)
+
+    return recorder
+}
+
+// Finishes the VGM log, if one is being recorded.
+func (m *Machine) StopVGM() error {
+    if m.IO.VGM == nil {
+        return nil
+    }
+
+    err := m.IO.VGM.Close()
+    m.IO.VGM = nil
+
+    return err
+}
+
+// Runs everything that happens during a single CPU cycle.
+func (m *Machine) Cycle() {
+    timing := m.Region.Timing()
+
+    m.APU.Step()
+    if m.Audio != nil {
+        m.Audio.Clock(m.APU)
+    }
+
+    m.dots += timing.PPUDots
+    for m.dots >= timing.CPUCycles {
+        m.PPU.Step()
+        m.dots -= timing.CPUCycles
+    }
+}
+
+// Runs the CPU until the PPU starts on the next frame.
+func (m *Machine) RunFrame() {
+    commands := m.stepMovie()
+
+    if m.Rewind != nil {
+        m.Rewind.record(m, m.frameInput(commands))
+    }
+
+    if m.RunAhead != nil {
+        m.runAhead()
+        return
+    }
+
+    m.runFrame()
+}
+
+func (m *Machine) runFrame() {
+    frame := m.PPU.Frame
+
+    for m.PPU.Frame == frame {
+        m.CPU.Step()
+    }
+}
+
 func (m *Machine) Insert(rom *ROM) {
+    m.ROM = rom
+    rom.Checksums()
+
     first := rom.Mapper.Patterntable(0)
     var err = m.PPU.Memory.Mount(first, 0x0000, 0x0fff)
     if err != nil { panic(err) }
@@ -46,6 +264,19 @@ func (m *Machine) Insert(rom *ROM) {
     err = m.CPU.Memory.Mount(rom.Mapper.Program(), 0x8000, 0xffff)
     if err != nil { panic(err) }
 
+    m.SetRegion(rom.Header.Region)
+
+    if watcher, ok := rom.Mapper.(BusWatcher); ok {
+        m.PPU.BusWatcher = watcher.WatchPPU
+    }
+
+    // Mappers with sound hardware of their own implement apu.Expansion, and
+    // are mixed in with the APU.
+    m.APU.Expansion = nil
+    if expansion, ok := rom.Mapper.(apu.Expansion); ok {
+        m.APU.Expansion = expansion
+    }
+
     m.CPU.PC = cpu.Address(m.CPU.Memory.Read(0xFFFC)) |
         (cpu.Address(m.CPU.Memory.Read(0xFFFD))<<8)
 }
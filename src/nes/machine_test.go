@@ -0,0 +1,108 @@
+package nes
+
+import (
+    "os"
+    "cpu"
+    "vgm"
+    "bytes"
+    "testing"
+    "io/ioutil"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func dotsAfter(m *Machine, cycles int) int {
+    dots := 0
+    for i := 0; i < cycles; i++ {
+        before := m.PPU.Cycle
+        m.Cycle()
+        dots += (m.PPU.Cycle - before + 341) % 341
+    }
+
+    return dots
+}
+
+func TestNTSCRunsThreeDotsPerCycle(t *testing.T) {
+    m := NewMachine()
+
+    assert.Equal(t, dotsAfter(m, 10), 30)
+}
+
+func TestPALRunsSixteenDotsPerFiveCycles(t *testing.T) {
+    m := NewMachine()
+    m.SetRegion(cpu.PAL)
+
+    assert.Equal(t, dotsAfter(m, 5), 16)
+    assert.Equal(t, dotsAfter(m, 50), 160)
+}
+
+func TestSetRegionConfiguresPPU(t *testing.T) {
+    m := NewMachine()
+    m.SetRegion(cpu.Dendy)
+
+    assert.Equal(t, m.PPU.Region, cpu.Dendy)
+    assert.Equal(t, m.PPU.VBlankScanline, 291)
+}
+
+func TestAPUFrameIRQReachesTheCPU(t *testing.T) {
+    m := NewMachine()
+
+    for i := 0; i < 29828; i++ {
+        m.Cycle()
+    }
+
+    assert.True(t, m.CPU.IRQ(cpu.FRAME_IRQ))
+
+    m.CPU.Read(0x4015)
+    assert.False(t, m.CPU.IRQ(cpu.FRAME_IRQ))
+}
+
+type soundNROM struct {
+    NROM
+}
+
+func (s *soundNROM) Step() {}
+func (s *soundNROM) Output() float64 { return 1 }
+func (s *soundNROM) Level() float64 { return 0.25 }
+
+func TestInsertHooksUpExpansionAudio(t *testing.T) {
+    rom := new(ROM)
+    rom.Header = new(Header)
+    rom.PrgBanks = [][]byte { make([]byte, PrgBankSize) }
+    rom.ChrBanks = [][]byte { make([]byte, ChrBankSize), make([]byte, ChrBankSize) }
+
+    mapper := &soundNROM { NROM { rom } }
+    rom.Mapper = mapper
+
+    m := NewMachine()
+    m.Insert(rom)
+
+    assert.Equal(t, m.APU.Expansion, mapper)
+
+    rom.Mapper = &NROM { rom }
+    m = NewMachine()
+    m.Insert(rom)
+
+    assert.Nil(t, m.APU.Expansion)
+}
+
+func TestRecordVGMStartsWithTheAPUsRegisters(t *testing.T) {
+    file, err := ioutil.TempFile("", "gones-vgm")
+    assert.Nil(t, err)
+    defer os.Remove(file.Name())
+
+    m := NewMachine()
+    m.CPU.Write(0xbf, 0x4000)
+
+    m.RecordVGM(file)
+    m.CPU.Write(0xfd, 0x4002)
+    m.CPU.Write(0x01, 0x4016)
+    assert.Nil(t, m.StopVGM())
+    assert.Nil(t, m.IO.VGM)
+
+    data, _ := ioutil.ReadFile(file.Name())
+    commands := data[vgm.HEADER_SIZE:]
+
+    assert.Equal(t, commands[:3], []byte { vgm.NES_APU, 0x00, 0xbf })
+    assert.True(t, bytes.Contains(commands, []byte { vgm.NES_APU, 0x02, 0xfd }))
+    assert.False(t, bytes.Contains(commands, []byte { vgm.NES_APU, 0x16 }))
+}
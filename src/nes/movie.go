@@ -0,0 +1,168 @@
+package nes
+
+import (
+    "errors"
+    "input"
+    "movie"
+)
+
+// Starts recording the buttons held and the resets pressed on each frame
+// from now on. Movies recorded once the machine's been running start from a
+// savestate of where it's got to. The movie formats only know the Four
+// Score, so there's no recording with the Hori adapter.
+func (m *Machine) RecordMovie() (*movie.Movie, error) {
+    if m.ROM == nil {
+        return nil, errors.New("there's no ROM to record a movie of")
+    }
+    if m.multitap == input.HORI_ADAPTER {
+        return nil, errors.New("movies can't be recorded with the Hori adapter attached")
+    }
+
+    m.Movie = new(movie.Movie)
+    m.Movie.MD5, m.Movie.SHA1 = m.ROM.Checksums()
+    m.Movie.Region = m.Region
+    m.Movie.FourScore = m.multitap == input.FOUR_SCORE
+
+    if m.CPU.Cycles() > 0 {
+        saved, err := m.SaveStateBytes()
+        if err != nil {
+            return nil, err
+        }
+
+        m.Movie.State = saved
+    } else {
+        // Nothing's run yet, so the movie starts from power on, as it will
+        // when it's played back.
+        m.CPU.Reset()
+    }
+
+    m.playing = false
+    m.commands = 0
+    m.movieStart = m.PPU.Frame
+
+    return m.Movie, nil
+}
+
+// Starts playing a movie back, which should be straight after the ROM it
+// was recorded with is inserted unless it starts from a savestate.
+// Controllers are plugged in to match it, and frontends should leave the
+// buttons alone while PlayingMovie.
+func (m *Machine) PlayMovie(mv *movie.Movie) error {
+    if m.ROM == nil {
+        return errors.New("there's no ROM to play the movie on")
+    }
+
+    md5, sha1 := m.ROM.Checksums()
+    if err := mv.Check(md5, sha1, m.Region); err != nil {
+        return err
+    }
+
+    // A movie without a savestate starts from power on, which the CPU's put
+    // back in below, so there's nothing to do for a power cycle on the first
+    // frame, but there's no emulating one later.
+    for i, frame := range mv.Frames {
+        if i > 0 && frame.Commands & movie.POWER != 0 {
+            return errors.New("movies which power cycle the console aren't supported")
+        }
+    }
+
+    if mv.FourScore {
+        m.AttachMultitap(input.FOUR_SCORE)
+    } else {
+        m.AttachMultitap(input.NO_MULTITAP)
+    }
+
+    if mv.State != nil {
+        if err := m.LoadStateBytes(mv.State); err != nil {
+            return err
+        }
+    } else {
+        m.CPU.Reset()
+    }
+
+    m.Movie = mv
+    m.playing = true
+    m.movieFrame = 0
+    m.movieStart = m.PPU.Frame
+
+    return nil
+}
+
+// Stops recording or playing back a movie.
+func (m *Machine) StopMovie() {
+    m.Movie = nil
+    m.playing = false
+}
+
+// Whether a movie's being played back and hasn't reached its end.
+func (m *Machine) PlayingMovie() bool {
+    return m.playing && m.movieFrame < len(m.Movie.Frames)
+}
+
+// Called at the start of each frame, before the CPU runs, returning the
+// console buttons pressed.
+func (m *Machine) stepMovie() byte {
+    commands := m.commands
+    m.commands = 0
+
+    switch {
+        case m.Movie == nil:
+        case m.playing:
+            if m.movieFrame >= len(m.Movie.Frames) {
+                break
+            }
+
+            frame := m.Movie.Frames[m.movieFrame]
+            m.movieFrame++
+
+            m.applyInput(frame)
+            commands = frame.Commands & movie.RESET
+        default:
+            if m.rerecording {
+                m.Movie.Rerecords++
+                m.rerecording = false
+            }
+
+            m.Movie.Frames = append(m.Movie.Frames, m.frameInput(commands))
+    }
+
+    return commands
+}
+
+// After rewinding, the movie carries on from wherever the machine's got
+// back to. A recording forgets what came after, and carrying on from there
+// counts as a rerecord.
+func (m *Machine) rewindMovie() {
+    if m.Movie == nil {
+        return
+    }
+
+    m.movieFrame = m.PPU.Frame - m.movieStart
+
+    if !m.playing && m.movieFrame < len(m.Movie.Frames) {
+        m.Movie.Frames = m.Movie.Frames[:m.movieFrame]
+        m.rerecording = true
+    }
+}
+
+// What's pressed at the start of a frame, in the form movies keep it.
+func (m *Machine) frameInput(commands byte) movie.Frame {
+    frame := movie.Frame { Commands: commands }
+
+    for player := range frame.Buttons {
+        frame.Buttons[player], _ = m.Ports.Buttons(player)
+    }
+
+    return frame
+}
+
+func (m *Machine) applyInput(frame movie.Frame) {
+    if frame.Commands & movie.RESET != 0 {
+        m.Reset()
+        m.commands = 0
+    }
+
+    for player := range frame.Buttons {
+        m.Ports.SetButtons(player, frame.Buttons[player])
+    }
+}
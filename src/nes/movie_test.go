@@ -0,0 +1,151 @@
+package nes
+
+import (
+    "testing"
+    "crypto/sha1"
+    "github.com/stretchrcom/testify/assert"
+    "input"
+    "movie"
+)
+
+// A machine running an NROM cartridge full of NOPs.
+func nopMachine() *Machine {
+    rom := new(ROM)
+    rom.Header = new(Header)
+    rom.PrgBanks = [][]byte { make([]byte, PrgBankSize) }
+    rom.ChrBanks = [][]byte { make([]byte, ChrBankSize), make([]byte, ChrBankSize) }
+
+    for i := range rom.PrgBanks[0] {
+        rom.PrgBanks[0][i] = 0xea
+    }
+    rom.Mapper = &NROM { rom }
+
+    m := NewMachine()
+    m.Insert(rom)
+
+    return m
+}
+
+func TestMovieRecordsButtonsAndResets(t *testing.T) {
+    m := nopMachine()
+    mv, err := m.RecordMovie()
+    assert.Equal(t, err, nil)
+
+    m.SetButtons(0, 0x01)
+    m.RunFrame()
+    m.SetButtons(1, 0x80)
+    m.Reset()
+    m.RunFrame()
+
+    assert.Equal(t, mv.Frames, []movie.Frame {
+        { Buttons: [4]byte { 0x01, 0x00 } },
+        { Commands: movie.RESET, Buttons: [4]byte { 0x01, 0x80 } },
+    })
+}
+
+func TestMoviePlaysBackButtons(t *testing.T) {
+    m := nopMachine()
+    mv, _ := m.RecordMovie()
+    mv.Frames = []movie.Frame { { Buttons: [4]byte { 0x01, 0x02 } }, { Buttons: [4]byte { 0x10 } } }
+
+    player := nopMachine()
+    assert.Equal(t, player.PlayMovie(mv), nil)
+
+    player.RunFrame()
+    buttons, _ := player.Ports.Buttons(1)
+    assert.Equal(t, buttons, byte(0x02))
+
+    player.RunFrame()
+    buttons, _ = player.Ports.Buttons(0)
+    assert.Equal(t, buttons, byte(0x10))
+    assert.False(t, player.PlayingMovie())
+}
+
+// Reads the first controller into $03 every frame, after noting where the
+// stack pointer and flags start out in $01 and $02.
+var controllerProgram = []byte {
+    0xa9, 0x80, 0x8d, 0x00, 0x20, // LDA #$80, STA $2000
+    0xba, 0x86, 0x01,             // TSX, STX $01
+    0x08, 0x68, 0x85, 0x02,       // PHP, PLA, STA $02
+    0x4c, 0x0c, 0x80,             // JMP $800C
+    0xa9, 0x01, 0x8d, 0x16, 0x40, // NMI: LDA #$01, STA $4016
+    0xa9, 0x00, 0x8d, 0x16, 0x40, // LDA #$00, STA $4016
+    0xa2, 0x08,                   // LDX #$08
+    0xad, 0x16, 0x40, 0x4a,       // LDA $4016, LSR A
+    0x26, 0x03, 0xca, 0xd0, 0xf7, // ROL $03, DEX, BNE
+    0x40,                         // RTI
+}
+
+// The RAM and picture, as gones play-movie hashes them.
+func hashes(m *Machine) ([20]byte, [20]byte) {
+    return sha1.Sum(m.CPU.Memory.Range(0x0000, 0x0800)), sha1.Sum(m.PPU.Display)
+}
+
+func TestMoviesPlayBackFromPowerOn(t *testing.T) {
+    // Frontends reset the CPU before recording; playing back shouldn't need
+    // to.
+    m := machineRunning(controllerProgram, 0x0f)
+    m.CPU.Reset()
+    mv, _ := m.RecordMovie()
+
+    for i := 0; i < 10; i++ {
+        m.SetButtons(0, byte(i * 17))
+        m.RunFrame()
+    }
+    m.StopMovie()
+    ram, picture := hashes(m)
+
+    player := machineRunning(controllerProgram, 0x0f)
+    assert.Equal(t, player.PlayMovie(mv), nil)
+    for player.PlayingMovie() {
+        player.RunFrame()
+    }
+
+    assert.Equal(t, player.CPU.Memory.Read(0x01), byte(0xfd))
+    assert.Equal(t, player.CPU.Memory.Read(0x03), byte(9 * 17))
+
+    playedRAM, playedPicture := hashes(player)
+    assert.Equal(t, playedRAM, ram)
+    assert.Equal(t, playedPicture, picture)
+}
+
+func TestMovieRefusesAnotherROM(t *testing.T) {
+    m := nopMachine()
+    mv, _ := m.RecordMovie()
+    mv.SHA1 = []byte { 1, 2, 3 }
+
+    assert.NotEqual(t, nopMachine().PlayMovie(mv), nil)
+}
+
+func TestMoviesCantBeRecordedWithTheHoriAdapter(t *testing.T) {
+    m := nopMachine()
+    m.AttachMultitap(input.HORI_ADAPTER)
+
+    mv, err := m.RecordMovie()
+    assert.NotEqual(t, err, nil)
+    assert.Equal(t, mv, (*movie.Movie)(nil))
+    assert.Equal(t, m.Movie, (*movie.Movie)(nil))
+
+    m.AttachMultitap(input.FOUR_SCORE)
+    mv, err = m.RecordMovie()
+    assert.Equal(t, err, nil)
+    assert.True(t, mv.FourScore)
+}
+
+func TestMovieStartsFromAState(t *testing.T) {
+    m := busyMachine()
+    runFrames(m, 5)
+
+    mv, _ := m.RecordMovie()
+    assert.NotEqual(t, mv.State, nil)
+    runFrames(m, 5)
+
+    player := busyMachine()
+    assert.Equal(t, player.PlayMovie(mv), nil)
+    for player.PlayingMovie() {
+        player.RunFrame()
+    }
+
+    assert.Equal(t, player.CPU.Memory.Range(0x0000, 0x0800), m.CPU.Memory.Range(0x0000, 0x0800))
+    assert.Equal(t, player.PPU.Frame, m.PPU.Frame)
+}
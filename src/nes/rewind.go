@@ -0,0 +1,233 @@
+package nes
+
+import (
+    "bytes"
+    "movie"
+    "state"
+)
+
+// Defaults for the rewind history: a snapshot every few frames, and 64MB
+// to keep them in.
+const (
+    REWIND_INTERVAL = 4
+    REWIND_BUDGET = 64 << 20
+)
+
+// Snapshots from one full snapshot to the next; the rest are deltas
+// against the last full one.
+const KEYFRAME_INTERVAL = 60
+
+// A history of the machine to step backwards through. A snapshot's taken
+// every Interval frames, each a keyframe or a delta against the last one,
+// with a keyframe every Keyframes snapshots. The input for every frame is
+// kept alongside so anything between snapshots can be emulated again. Once
+// the snapshots outgrow Budget bytes the oldest keyframe and its deltas are
+// thrown away.
+type Rewind struct {
+    Interval int
+    Keyframes int
+    Budget int
+
+    snapshots []snapshot
+    size int
+
+    // The newest keyframe's state, which deltas are taken against.
+    keyframe []byte
+    deltas int
+
+    // The input for each frame from the oldest snapshot on.
+    inputs []movie.Frame
+    first int
+}
+
+type snapshot struct {
+    frame int
+    keyframe bool
+    data []byte
+}
+
+func NewRewind(interval int, budget int) *Rewind {
+    if interval < 1 {
+        interval = 1
+    }
+
+    return &Rewind { Interval: interval, Keyframes: KEYFRAME_INTERVAL, Budget: budget }
+}
+
+// Starts keeping a rewind history. A budget of 0 keeps none.
+func (m *Machine) EnableRewind(interval int, budget int) {
+    m.Rewind = nil
+    if budget > 0 {
+        m.Rewind = NewRewind(interval, budget)
+    }
+}
+
+// Forgets everything.
+func (r *Rewind) Clear() {
+    r.snapshots = nil
+    r.size = 0
+    r.keyframe = nil
+    r.inputs = nil
+}
+
+// How many frames back the history goes.
+func (r *Rewind) Frames() int {
+    return len(r.inputs)
+}
+
+// Called at the start of each frame with the input it's about to run with.
+// Anything recorded after this frame is from before a rewind, and goes.
+func (r *Rewind) record(m *Machine, input movie.Frame) {
+    frame := m.PPU.Frame
+    r.truncate(frame)
+
+    if len(r.inputs) == 0 {
+        r.first = frame
+    }
+    r.inputs = append(r.inputs, input)
+
+    newest := len(r.snapshots) - 1
+    if frame % r.Interval != 0 || newest >= 0 && r.snapshots[newest].frame == frame {
+        return
+    }
+
+    var out bytes.Buffer
+    if m.saveState(&out, false) != nil {
+        return
+    }
+
+    s := snapshot { frame: frame, data: out.Bytes() }
+    if r.keyframe == nil || r.deltas >= r.Keyframes - 1 {
+        s.keyframe = true
+        r.keyframe = s.data
+        r.deltas = 0
+    } else {
+        s.data = state.Delta(r.keyframe, s.data)
+        r.deltas++
+    }
+
+    r.snapshots = append(r.snapshots, s)
+    r.size += len(s.data)
+
+    r.trim()
+}
+
+func (r *Rewind) truncate(frame int) {
+    if len(r.inputs) > 0 && frame < r.first {
+        r.Clear()
+        return
+    }
+
+    if frame - r.first < len(r.inputs) {
+        r.inputs = r.inputs[:frame - r.first]
+    }
+
+    kept := len(r.snapshots)
+    for kept > 0 && r.snapshots[kept - 1].frame > frame {
+        kept--
+        r.size -= len(r.snapshots[kept].data)
+    }
+    if kept == len(r.snapshots) {
+        return
+    }
+    r.snapshots = r.snapshots[:kept]
+
+    // Deltas carry on from the last keyframe still standing.
+    r.keyframe, r.deltas = nil, 0
+    for i := kept - 1; i >= 0; i-- {
+        if r.snapshots[i].keyframe {
+            r.keyframe = r.snapshots[i].data
+            break
+        }
+        r.deltas++
+    }
+}
+
+// Throws away the oldest keyframes and their deltas until everything fits,
+// always keeping the newest.
+func (r *Rewind) trim() {
+    for r.size > r.Budget {
+        next := 1
+        for next < len(r.snapshots) && !r.snapshots[next].keyframe {
+            next++
+        }
+        if next == len(r.snapshots) {
+            return
+        }
+
+        for _, s := range r.snapshots[:next] {
+            r.size -= len(s.data)
+        }
+        r.snapshots = r.snapshots[next:]
+
+        oldest := r.snapshots[0].frame
+        r.inputs = r.inputs[oldest - r.first:]
+        r.first = oldest
+    }
+}
+
+// The newest snapshot from at or before frame, or -1.
+func (r *Rewind) find(frame int) int {
+    for i := len(r.snapshots) - 1; i >= 0; i-- {
+        if r.snapshots[i].frame <= frame {
+            return i
+        }
+    }
+
+    return -1
+}
+
+func (r *Rewind) restore(i int) ([]byte, error) {
+    base := i
+    for !r.snapshots[base].keyframe {
+        base--
+    }
+
+    if base == i {
+        return r.snapshots[i].data, nil
+    }
+
+    return state.ApplyDelta(r.snapshots[base].data, r.snapshots[i].data)
+}
+
+// Steps back to the start of the previous frame, returning false once the
+// history runs out. The frame before that is emulated again where there's
+// a snapshot to do it from, so the picture is the one that was shown then.
+// Audio and VGM logging are left out of anything emulated again.
+func (m *Machine) RewindFrame() bool {
+    r := m.Rewind
+    if r == nil {
+        return false
+    }
+
+    target := m.PPU.Frame - 1
+    if m.Movie != nil && target < m.movieStart {
+        return false
+    }
+
+    i := r.find(target - 1)
+    if i < 0 {
+        i = r.find(target)
+    }
+    if i < 0 {
+        return false
+    }
+
+    data, err := r.restore(i)
+    if err != nil || m.loadState(bytes.NewReader(data)) != nil {
+        return false
+    }
+
+    audio, vgm := m.Audio, m.IO.VGM
+    m.Audio, m.IO.VGM = nil, nil
+
+    for m.PPU.Frame < target {
+        m.applyInput(r.inputs[m.PPU.Frame - r.first])
+        m.runFrame()
+    }
+
+    m.Audio, m.IO.VGM = audio, vgm
+
+    m.rewindMovie()
+    return true
+}
@@ -0,0 +1,96 @@
+package nes
+
+import (
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+type moment struct {
+    cycles int
+    ram []byte
+    picture []byte
+}
+
+func momentOf(m *Machine) moment {
+    return moment {
+        m.CPU.Cycles(),
+        m.CPU.Memory.Range(0x0000, 0x0800),
+        append([]byte {}, m.PPU.Display...),
+    }
+}
+
+func TestRewindStepsBackAFrameAtATime(t *testing.T) {
+    m := busyMachine()
+    m.EnableRewind(4, REWIND_BUDGET)
+
+    moments := map[int]moment {}
+    for i := 0; i < 20; i++ {
+        moments[m.PPU.Frame] = momentOf(m)
+        runFrames(m, 1)
+    }
+
+    for i := 0; i < 12; i++ {
+        assert.True(t, m.RewindFrame())
+        assert.Equal(t, momentOf(m), moments[m.PPU.Frame])
+    }
+}
+
+func TestRewindForgetsWhatsBeenRewound(t *testing.T) {
+    m := busyMachine()
+    m.EnableRewind(3, REWIND_BUDGET)
+
+    runFrames(m, 10)
+    m.RewindFrame()
+    m.RewindFrame()
+
+    // Different buttons this time.
+    moments := map[int]moment {}
+    for i := 0; i < 6; i++ {
+        moments[m.PPU.Frame] = momentOf(m)
+        m.SetButtons(0, 0xff)
+        m.RunFrame()
+    }
+
+    for i := 0; i < 6; i++ {
+        assert.True(t, m.RewindFrame())
+        assert.Equal(t, momentOf(m), moments[m.PPU.Frame])
+    }
+}
+
+func TestRewindKeepsToItsBudget(t *testing.T) {
+    m := busyMachine()
+    m.EnableRewind(1, 1)
+    m.Rewind.Keyframes = 5
+
+    runFrames(m, 13)
+
+    // Only the newest keyframe and its deltas are left.
+    assert.Equal(t, len(m.Rewind.snapshots), 3)
+    assert.True(t, m.Rewind.snapshots[0].keyframe)
+    assert.Equal(t, m.Rewind.Frames(), len(m.Rewind.snapshots))
+
+    frames := 0
+    for m.RewindFrame() {
+        frames++
+    }
+    assert.Equal(t, frames, m.Rewind.Frames())
+}
+
+func TestRewindingARecordingRerecords(t *testing.T) {
+    m := busyMachine()
+    m.EnableRewind(2, REWIND_BUDGET)
+    mv, _ := m.RecordMovie()
+
+    runFrames(m, 6)
+    assert.True(t, m.RewindFrame())
+    assert.True(t, m.RewindFrame())
+
+    assert.Equal(t, len(mv.Frames), 4)
+
+    runFrames(m, 1)
+    assert.Equal(t, len(mv.Frames), 5)
+    assert.Equal(t, mv.Rerecords, 1)
+
+    for m.RewindFrame() {}
+    assert.Equal(t, len(mv.Frames), 0)
+}
@@ -8,6 +8,8 @@ import (
     "io/ioutil"
     "bytes"
     "errors"
+    "crypto/md5"
+    "crypto/sha1"
 )
 
 var MapperNames = map[uint8]string {
@@ -69,6 +71,44 @@ type ROM struct {
     Mapper
 
     data []byte
+    md5, sha1 []byte
+}
+
+// Hashes of the program and graphics data, as movies and savestates
+// identify ROMs. Writable CHR changes as the game runs, so they're worked out
+// once, which Insert does before anything's run.
+func (r *ROM) Checksums() ([]byte, []byte) {
+    if r.md5 != nil {
+        return r.md5, r.sha1
+    }
+
+    md5Hash, sha1Hash := md5.New(), sha1.New()
+
+    for _, banks := range [][][]byte { r.PrgBanks, r.ChrBanks } {
+        for _, bank := range banks {
+            md5Hash.Write(bank)
+            sha1Hash.Write(bank)
+        }
+    }
+
+    r.md5, r.sha1 = md5Hash.Sum(nil), sha1Hash.Sum(nil)
+    return r.md5, r.sha1
+}
+
+// Another cartridge just like this one, with its own mapper and its own
+// copy of the CHR banks, which games can write to.
+func (r *ROM) Copy() *ROM {
+    c := &ROM { Header: r.Header, PrgBanks: r.PrgBanks, data: r.data }
+
+    c.ChrBanks = make([][]byte, len(r.ChrBanks))
+    for i, bank := range r.ChrBanks {
+        c.ChrBanks[i] = append([]byte {}, bank...)
+    }
+
+    c.md5, c.sha1 = r.Checksums()
+    c.Mapper = NewMapper(c)
+
+    return c
 }
 
 type MountableStruct struct {
@@ -90,6 +130,12 @@ type Mapper interface {
     Program() cpu.Mountable
 }
 
+// Mappers that need to see the PPU address bus, e.g. to clock a scanline
+// counter off A12, implement BusWatcher and are hooked up when inserted.
+type BusWatcher interface {
+    WatchPPU(cpu.Address)
+}
+
 const (
     PrgBankSize = 0x4000
     ChrBankSize = 0x1000
@@ -142,6 +188,9 @@ type Header struct {
     Flags7 byte
     PrgRamSize uint8
     Flags9 byte
+
+    NES2 bool
+    Region cpu.Region
 }
 
 var nes = []byte{0x4E, 0x45, 0x53, 0x1A}
@@ -156,6 +205,27 @@ func ParseHeader(raw []byte) (header *Header, err error) {
     header.PrgRomSize = int(raw[4])
     header.ChrRomSize = int(raw[5])
     header.Mapper = (raw[6] >> 4) | (raw[7] & 0xF0)
+    header.Flags6 = raw[6]
+    header.Flags7 = raw[7]
+    header.PrgRamSize = raw[8]
+    header.Flags9 = raw[9]
+
+    header.NES2 = (header.Flags7 & 0x0c) == 0x08
+
+    switch {
+        case header.NES2:
+            // Byte 12 holds the CPU/PPU timing: NTSC, PAL, multiple region,
+            // or Dendy. Multiple region ROMs run fine as NTSC.
+            switch raw[12] & 0x03 {
+                case 0x01: header.Region = cpu.PAL
+                case 0x03: header.Region = cpu.Dendy
+                default:   header.Region = cpu.NTSC
+            }
+        case header.Flags9 & 0x01 == 0x01:
+            header.Region = cpu.PAL
+        default:
+            header.Region = cpu.NTSC
+    }
 
     return
 }
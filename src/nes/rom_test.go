@@ -1,6 +1,7 @@
 package nes
 
 import (
+    "cpu"
     "testing"
     "github.com/stretchrcom/testify/assert"
 )
@@ -42,3 +43,39 @@ func TestParseHeaderMapper(t *testing.T) {
 
     assert.Equal(t, header.Mapper, uint8(1))
 }
+
+func TestParseHeaderDefaultsToNTSC(t *testing.T) {
+    header, _ := ParseHeader(example)
+
+    assert.False(t, header.NES2)
+    assert.Equal(t, header.Region, cpu.NTSC)
+}
+
+func TestParseHeaderINESPALFlag(t *testing.T) {
+    raw := make([]byte, 16)
+    copy(raw, example)
+    raw[9] = 0x01
+
+    header, _ := ParseHeader(raw)
+
+    assert.Equal(t, header.Region, cpu.PAL)
+}
+
+func TestParseHeaderNES2Timing(t *testing.T) {
+    raw := make([]byte, 16)
+    copy(raw, example)
+    raw[7] = 0x08
+
+    raw[12] = 0x01
+    header, _ := ParseHeader(raw)
+    assert.True(t, header.NES2)
+    assert.Equal(t, header.Region, cpu.PAL)
+
+    raw[12] = 0x03
+    header, _ = ParseHeader(raw)
+    assert.Equal(t, header.Region, cpu.Dendy)
+
+    raw[12] = 0x02
+    header, _ = ParseHeader(raw)
+    assert.Equal(t, header.Region, cpu.NTSC)
+}
@@ -0,0 +1,111 @@
+package nes
+
+import (
+    "bytes"
+    "input"
+    "state"
+)
+
+// Run-ahead hides the frames of lag between a game reading the controller
+// and drawing the result. Each frame is run for real, with its sound, then
+// the machine is saved, Frames more are run with the same input, and the
+// last of them is shown before going back to the save. In the second
+// instance mode the frames ahead are run on a copy of the machine instead,
+// so the one being heard is never loaded over and its sound can't glitch.
+type RunAhead struct {
+    Frames int
+
+    // The copy of the machine in the second instance mode, or nil.
+    second *Machine
+
+    state bytes.Buffer
+}
+
+// Starts running frames ahead of the one being shown, or stops with 0. The
+// ROM has to be inserted first for the second instance mode.
+func (m *Machine) EnableRunAhead(frames int, secondInstance bool) {
+    m.RunAhead = nil
+    if frames < 1 {
+        return
+    }
+
+    m.RunAhead = &RunAhead { Frames: frames }
+
+    if secondInstance && m.ROM != nil {
+        second := NewMachine()
+        second.Insert(m.ROM.Copy())
+
+        // It reads the same ports, which are put back afterwards.
+        second.Ports = m.Ports
+        second.IO.Ports = m.Ports
+
+        m.RunAhead.second = second
+    }
+}
+
+// Runs a frame for real, then shows the one Frames after it.
+func (m *Machine) runAhead() {
+    r := m.RunAhead
+
+    m.PPU.SkipDisplay = true
+    m.runFrame()
+    m.PPU.SkipDisplay = false
+
+    // Input arriving from here on would be undone along with the frames
+    // ahead, so it waits until they're done.
+    m.Ports.Hold()
+    defer m.Ports.Release()
+
+    r.state.Reset()
+    if m.saveState(&r.state, false) != nil {
+        return
+    }
+
+    if r.second != nil {
+        r.aheadOf(m)
+        return
+    }
+
+    audio, vgm := m.Audio, m.IO.VGM
+    m.Audio, m.IO.VGM = nil, nil
+
+    m.runFramesAhead(r.Frames)
+
+    m.Audio, m.IO.VGM = audio, vgm
+
+    // The picture isn't part of the state, so it stays as it was ahead.
+    phase := m.PPU.FramePhase
+    m.loadState(bytes.NewReader(r.state.Bytes()))
+    m.PPU.FramePhase = phase
+}
+
+// Runs the frames ahead of m on the second machine, and gives m their
+// picture. Light guns read m's picture, which is a frame behind.
+func (r *RunAhead) aheadOf(m *Machine) {
+    second := r.second
+
+    if second.loadState(bytes.NewReader(r.state.Bytes())) != nil {
+        return
+    }
+
+    second.runFramesAhead(r.Frames)
+
+    copy(m.PPU.Display, second.PPU.Display)
+    copy(m.PPU.Output, second.PPU.Output)
+    m.PPU.FramePhase = second.PPU.FramePhase
+
+    in, err := state.NewReader(bytes.NewReader(r.state.Bytes()))
+    if err == nil {
+        in.Chunk("INPT", input.STATE_VERSION, m.Ports.State)
+    }
+}
+
+// Runs frames without drawing any but the last.
+func (m *Machine) runFramesAhead(frames int) {
+    for i := 0; i < frames; i++ {
+        m.PPU.SkipDisplay = i < frames - 1
+        m.runFrame()
+    }
+
+    m.PPU.SkipDisplay = false
+}
@@ -0,0 +1,72 @@
+package nes
+
+import (
+    "bytes"
+    "audio"
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+// Changes the backdrop colour every frame.
+var flashingProgram = []byte {
+    0xa9, 0x80, 0x8d, 0x00, 0x20, // LDA #$80, STA $2000
+    0xa9, 0x0a, 0x8d, 0x01, 0x20, // LDA #$0A, STA $2001
+    0x4c, 0x0a, 0x80,             // JMP $800A
+    0xe6, 0x10,                   // NMI: INC $10
+    0xa9, 0x3f, 0x8d, 0x06, 0x20, // LDA #$3F, STA $2006
+    0xa9, 0x00, 0x8d, 0x06, 0x20, // LDA #$00, STA $2006
+    0xa5, 0x10, 0x8d, 0x07, 0x20, // LDA $10, STA $2007
+    0xa9, 0x00, 0x8d, 0x06, 0x20, // LDA #$00, STA $2006
+    0x8d, 0x06, 0x20,             // STA $2006
+    0x40,                         // RTI
+}
+
+func testRunAhead(t *testing.T, secondInstance bool) {
+    m := machineRunning(flashingProgram, 0x0d)
+    m.EnableRunAhead(2, secondInstance)
+    heard := new(audio.NullSink)
+    m.PlayAudio(44100, heard)
+
+    plain := machineRunning(flashingProgram, 0x0d)
+    expected := new(audio.NullSink)
+    plain.PlayAudio(44100, expected)
+
+    runFrames(m, 10)
+    runFrames(plain, 10)
+    shown := append([]uint16 {}, plain.PPU.Output...)
+
+    // What really happens, and what's heard, is just the same.
+    var ahead, behind bytes.Buffer
+    m.saveState(&ahead, false)
+    plain.saveState(&behind, false)
+    assert.Equal(t, ahead.Bytes(), behind.Bytes())
+
+    m.Audio.Close()
+    plain.Audio.Close()
+    assert.Equal(t, heard.Samples, expected.Samples)
+
+    // But the picture's from two frames on, with the same input.
+    plain.runFrame()
+    plain.runFrame()
+
+    assert.NotEqual(t, plain.PPU.Output, shown)
+    assert.Equal(t, m.PPU.Output, plain.PPU.Output)
+    assert.Equal(t, m.PPU.Display, plain.PPU.Display)
+    assert.Equal(t, m.PPU.FramePhase, plain.PPU.FramePhase)
+}
+
+func TestRunAheadShowsFramesAhead(t *testing.T) {
+    testRunAhead(t, false)
+}
+
+func TestRunAheadOnASecondInstance(t *testing.T) {
+    testRunAhead(t, true)
+}
+
+func TestRunAheadCanBeTurnedOff(t *testing.T) {
+    m := busyMachine()
+    m.EnableRunAhead(1, true)
+    m.EnableRunAhead(0, true)
+
+    assert.Equal(t, m.RunAhead, (*RunAhead)(nil))
+}
@@ -0,0 +1,62 @@
+package nes
+
+import (
+    "os"
+    "fmt"
+    "image"
+    "path/filepath"
+)
+
+// Numbered savestate slots, kept as files named after the game in a
+// directory.
+type Slots struct {
+    Dir string
+    Name string
+}
+
+func NewSlots(dir string, name string) *Slots {
+    return &Slots { dir, name }
+}
+
+func (s *Slots) Path(slot int) string {
+    return filepath.Join(s.Dir, fmt.Sprintf("%s.%d.state", s.Name, slot))
+}
+
+func (s *Slots) Save(m *Machine, slot int) error {
+    if err := os.MkdirAll(s.Dir, 0755); err != nil {
+        return err
+    }
+
+    file, err := os.Create(s.Path(slot))
+    if err != nil {
+        return err
+    }
+
+    err = m.SaveState(file)
+    if closeErr := file.Close(); err == nil {
+        err = closeErr
+    }
+
+    return err
+}
+
+func (s *Slots) Load(m *Machine, slot int) error {
+    file, err := os.Open(s.Path(slot))
+    if err != nil {
+        return err
+    }
+    defer file.Close()
+
+    return m.LoadState(file)
+}
+
+// The picture from when a slot was saved.
+func (s *Slots) Thumbnail(slot int) (*image.RGBA, error) {
+    file, err := os.Open(s.Path(slot))
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    return ReadThumbnail(file)
+}
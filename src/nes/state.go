@@ -0,0 +1,210 @@
+package nes
+
+import (
+    "io"
+    "bytes"
+    "errors"
+    "image"
+    "cpu"
+    "ppu"
+    "apu"
+    "input"
+    "state"
+)
+
+// Chunk versions for the parts of the machine outside the component
+// packages.
+const (
+    MACHINE_STATE_VERSION = 1
+    MAPPER_STATE_VERSION = 1
+)
+
+// The thumbnail kept with each state is the picture at half size.
+const (
+    THUMBNAIL_WIDTH = 128
+    THUMBNAIL_HEIGHT = 120
+)
+
+// Mappers with banking or IRQ state to save, which also covers any
+// expansion sound they have.
+type StateMapper interface {
+    State(s state.Stream)
+}
+
+// Saves the whole machine: the CPU, PPU and APU, every memory, the mapper
+// and what's plugged into the ports, with a thumbnail of the picture.
+// States should be saved between instructions, which they always are
+// between frames.
+func (m *Machine) SaveState(w io.Writer) error {
+    return m.saveState(w, true)
+}
+
+func (m *Machine) saveState(w io.Writer, thumbnail bool) error {
+    if m.ROM == nil {
+        return errors.New("there's no ROM to save the state of")
+    }
+
+    out := state.NewWriter(w)
+    m.state(out, out.Chunk)
+
+    if thumbnail {
+        out.Chunk("THMB", 1, func(s state.Stream) {
+            s.Bytes(m.thumbnail())
+        })
+    }
+
+    return out.Err()
+}
+
+// Loads a state saved by SaveState with the same ROM inserted. The rewind
+// history is forgotten, as it leads somewhere else.
+func (m *Machine) LoadState(r io.Reader) error {
+    err := m.loadState(r)
+
+    if err == nil && m.Rewind != nil {
+        m.Rewind.Clear()
+    }
+
+    return err
+}
+
+func (m *Machine) loadState(r io.Reader) error {
+    if m.ROM == nil {
+        return errors.New("there's no ROM to load the state into")
+    }
+
+    in, err := state.NewReader(r)
+    if err != nil {
+        return err
+    }
+
+    _, sha1 := m.ROM.Checksums()
+    saved := make([]byte, len(sha1))
+    err = in.Chunk("NES ", MACHINE_STATE_VERSION, func(s state.Stream) {
+        s.Bytes(saved)
+    })
+    if err != nil {
+        return err
+    }
+    if !bytes.Equal(saved, sha1) {
+        return errors.New("the state was saved with a different ROM")
+    }
+
+    m.state(in, func(id string, version int, load func(state.Stream)) {
+        if err == nil {
+            err = in.Chunk(id, version, load)
+        }
+    })
+
+    return err
+}
+
+func (m *Machine) SaveStateBytes() ([]byte, error) {
+    var out bytes.Buffer
+    err := m.SaveState(&out)
+
+    return out.Bytes(), err
+}
+
+func (m *Machine) LoadStateBytes(data []byte) error {
+    return m.LoadState(bytes.NewReader(data))
+}
+
+// Runs over every chunk, saving or loading them.
+func (m *Machine) state(s state.Stream, chunk func(string, int, func(state.Stream))) {
+    chunk("NES ", MACHINE_STATE_VERSION, func(s state.Stream) {
+        _, sha1 := m.ROM.Checksums()
+        s.Bytes(sha1)
+
+        region, dots := int(m.Region), m.dots
+        s.Int(&region)
+        s.Int(&dots)
+
+        if s.Loading() {
+            m.SetRegion(cpu.Region(region))
+            m.dots = dots
+        }
+    })
+
+    chunk("CPU ", cpu.STATE_VERSION, m.CPU.State)
+    chunk("RAM ", cpu.STATE_VERSION, m.CPU.RAM.State)
+    chunk("WRAM", cpu.STATE_VERSION, m.WRAM.State)
+
+    chunk("PPU ", ppu.STATE_VERSION, m.PPU.State)
+    chunk("CHR ", ppu.STATE_VERSION, func(s state.Stream) {
+        for _, patterntable := range m.PPU.Patterntables {
+            if patterntable != nil {
+                patterntable.State(s)
+            }
+        }
+    })
+
+    chunk("APU ", apu.STATE_VERSION, m.APU.State)
+    chunk("DMA ", MACHINE_STATE_VERSION, m.DMA.state)
+    chunk("IO  ", MACHINE_STATE_VERSION, m.IO.state)
+
+    if mapper, ok := m.ROM.Mapper.(StateMapper); ok {
+        chunk("MAPR", MAPPER_STATE_VERSION, mapper.State)
+    }
+
+    chunk("INPT", input.STATE_VERSION, m.Ports.State)
+
+    // A DMC fetch waiting on the DMA needs to know where to go.
+    if s.Loading() && m.DMA.dmc {
+        m.DMA.dmcDone = m.APU.SampleFetched
+    }
+}
+
+func (d *DMA) state(s state.Stream) {
+    s.Bool(&d.oam)
+    s.Byte(&d.page)
+    s.Bool(&d.dmc)
+    s.Uint16((*uint16)(&d.dmcAddress))
+}
+
+func (io *IO) state(s state.Stream) {
+    s.Bytes(io.registers[:])
+    s.Int(&io.lastPort)
+    s.Int(&io.lastCycle)
+}
+
+func (m *Machine) thumbnail() []byte {
+    thumbnail := make([]byte, THUMBNAIL_WIDTH * THUMBNAIL_HEIGHT * 3)
+
+    for y := 0; y < THUMBNAIL_HEIGHT; y++ {
+        for x := 0; x < THUMBNAIL_WIDTH; x++ {
+            from := (y * 2 * 256 + x * 2) * 3
+            copy(thumbnail[(y * THUMBNAIL_WIDTH + x) * 3:], m.PPU.Display[from:from + 3])
+        }
+    }
+
+    return thumbnail
+}
+
+// Reads the thumbnail from a saved state, for showing what's in a slot.
+func ReadThumbnail(r io.Reader) (*image.RGBA, error) {
+    in, err := state.NewReader(r)
+    if err != nil {
+        return nil, err
+    }
+
+    if !in.Has("THMB") {
+        return nil, errors.New("the state has no thumbnail")
+    }
+
+    pixels := make([]byte, THUMBNAIL_WIDTH * THUMBNAIL_HEIGHT * 3)
+    err = in.Chunk("THMB", 1, func(s state.Stream) {
+        s.Bytes(pixels)
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    thumbnail := image.NewRGBA(image.Rect(0, 0, THUMBNAIL_WIDTH, THUMBNAIL_HEIGHT))
+    for i := 0; i < THUMBNAIL_WIDTH * THUMBNAIL_HEIGHT; i++ {
+        copy(thumbnail.Pix[i * 4:], pixels[i * 3:i * 3 + 3])
+        thumbnail.Pix[i * 4 + 3] = 0xff
+    }
+
+    return thumbnail, nil
+}
@@ -0,0 +1,121 @@
+package nes
+
+import (
+    "bytes"
+    "testing"
+    "io/ioutil"
+    "github.com/stretchrcom/testify/assert"
+)
+
+// A program which keeps the PPU, APU and RAM busy, with rendering and NMIs
+// on.
+var busyProgram = []byte {
+    0xa9, 0x80, 0x8d, 0x00, 0x20, // LDA #$80, STA $2000
+    0xa9, 0x1e, 0x8d, 0x01, 0x20, // LDA #$1E, STA $2001
+    0xa9, 0x0f, 0x8d, 0x15, 0x40, // LDA #$0F, STA $4015
+    0xe6, 0x10, 0xa5, 0x10,       // INC $10, LDA $10
+    0x8d, 0x00, 0x40,             // STA $4000
+    0x8d, 0x03, 0x40,             // STA $4003
+    0x4c, 0x0f, 0x80,             // JMP $800F
+    0xe6, 0x11, 0xa5, 0x11,       // NMI: INC $11, LDA $11
+    0x8d, 0x06, 0x20,             // STA $2006
+    0x8d, 0x07, 0x20,             // STA $2007
+    0x40,                         // RTI
+}
+
+func busyMachine() *Machine {
+    return machineRunning(busyProgram, 0x1c)
+}
+
+// An NROM machine running a program from $8000, with its NMI handler at
+// the given offset into it.
+func machineRunning(code []byte, nmi byte) *Machine {
+    rom := new(ROM)
+    rom.Header = new(Header)
+    rom.PrgBanks = [][]byte { make([]byte, PrgBankSize) }
+    rom.ChrBanks = [][]byte { make([]byte, ChrBankSize), make([]byte, ChrBankSize) }
+
+    program := rom.PrgBanks[0]
+    copy(program, code)
+    copy(program[0x3ffa:], []byte { nmi, 0x80, 0x00, 0x80, nmi, 0x80 })
+
+    for i := range rom.ChrBanks[0] {
+        rom.ChrBanks[0][i] = byte(i)
+    }
+    rom.Mapper = &NROM { rom }
+
+    m := NewMachine()
+    m.Insert(rom)
+
+    return m
+}
+
+func runFrames(m *Machine, frames int) {
+    for i := 0; i < frames; i++ {
+        m.SetButtons(0, byte(m.PPU.Frame))
+        m.RunFrame()
+    }
+}
+
+func TestLoadedStatesRunTheSame(t *testing.T) {
+    m := busyMachine()
+    runFrames(m, 10)
+
+    saved, err := m.SaveStateBytes()
+    assert.Equal(t, err, nil)
+
+    runFrames(m, 20)
+    ram := m.CPU.Memory.Range(0x0000, 0x0800)
+    picture := append([]byte {}, m.PPU.Display...)
+
+    other := busyMachine()
+    assert.Equal(t, other.LoadStateBytes(saved), nil)
+    runFrames(other, 20)
+
+    assert.Equal(t, other.CPU.Memory.Range(0x0000, 0x0800), ram)
+    assert.Equal(t, other.PPU.Display, picture)
+    assert.Equal(t, other.CPU.Cycles(), m.CPU.Cycles())
+
+    again, _ := other.SaveStateBytes()
+    now, _ := m.SaveStateBytes()
+    assert.Equal(t, again, now)
+}
+
+func TestStatesRefuseAnotherROM(t *testing.T) {
+    saved, _ := busyMachine().SaveStateBytes()
+
+    m := nopMachine()
+    assert.NotEqual(t, m.LoadStateBytes(saved), nil)
+    assert.NotEqual(t, m.LoadStateBytes([]byte("nonsense")), nil)
+}
+
+func TestSlotsKeepThumbnails(t *testing.T) {
+    dir, _ := ioutil.TempDir("", "slots")
+    slots := NewSlots(dir, "busy")
+
+    m := busyMachine()
+    runFrames(m, 5)
+    m.PPU.Display[0], m.PPU.Display[1], m.PPU.Display[2] = 1, 2, 3
+
+    assert.Equal(t, slots.Save(m, 3), nil)
+
+    thumbnail, err := slots.Thumbnail(3)
+    assert.Equal(t, err, nil)
+    assert.Equal(t, thumbnail.Bounds().Dx(), THUMBNAIL_WIDTH)
+    assert.Equal(t, thumbnail.Pix[:4], []byte { 1, 2, 3, 0xff })
+
+    cycles := m.CPU.Cycles()
+    runFrames(m, 5)
+    assert.Equal(t, slots.Load(m, 3), nil)
+    assert.Equal(t, m.CPU.Cycles(), cycles)
+
+    assert.NotEqual(t, slots.Load(m, 4), nil)
+}
+
+func TestSaveStateWritesToAWriter(t *testing.T) {
+    m := busyMachine()
+
+    var out bytes.Buffer
+    assert.Equal(t, m.SaveState(&out), nil)
+    assert.Equal(t, m.LoadState(&out), nil)
+}
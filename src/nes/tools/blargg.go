@@ -10,6 +10,9 @@ import (
     "path/filepath"
 )
 
+// How many frames a test gets to start again after it's reset.
+const RESET_LIMIT = 60
+
 func run(filename string) {
     var file *os.File
     var err error
@@ -36,12 +39,6 @@ func run(filename string) {
         machine.CPU.Step()
     }
 
-    machine.CPU.Cycle = func() {
-        for i:=0; i < 3; i++ {
-            machine.PPU.Step()
-        }
-    }
-
     parts := strings.Split(filename, string(filepath.Separator))
     fmt.Printf("Running %s tests...\n", parts[len(parts)-1])
 
@@ -49,8 +46,26 @@ func run(filename string) {
     for machine.CPU.Memory.Read(0x6000) > 0x7f {
         machine.CPU.Step()
 
+        // The test wants reset pressed, at least 100ms from now.
         if machine.CPU.Memory.Read(0x6000) == 0x81 {
-            panic("Need to press reset, but isn't implemented")
+            for i := 0; i < 10; i++ {
+                machine.RunFrame()
+            }
+
+            machine.Reset()
+
+            // The status still reads $81 until the reset handler gets as
+            // far as changing it.
+            limit := machine.PPU.Frame + RESET_LIMIT
+            for machine.CPU.Memory.Read(0x6000) == 0x81 {
+                if machine.PPU.Frame >= limit {
+                    fmt.Printf("Results: FAIL\n")
+                    fmt.Printf("====\nThe test didn't start again after reset.\n====\n")
+                    return
+                }
+
+                machine.CPU.Step()
+            }
         }
     }
 
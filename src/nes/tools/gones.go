@@ -1,57 +1,571 @@
 package main
 
 import (
+    "cpu"
     "nes"
+    "nsf"
+    "ntsc"
+    "audio"
     "video"
+    "input"
+    "movie"
+    "keymap"
     "os"
     "log"
+    "fmt"
+    "flag"
+    "time"
+    "strings"
+    "syscall"
+    "crypto/sha1"
+    "os/signal"
+    "path/filepath"
 )
 
-func main() {
-    path := os.Args[1]
+// Savestate slots, picked between with the next-slot hotkey.
+const SLOTS = 10
+
+// Lengths for tracks whose files don't say.
+const (
+    DEFAULT_LENGTH = 150 * time.Second
+    DEFAULT_FADE = 8 * time.Second
+)
+
+// gones nsf [flags] file.nsf -- renders a track of an NSF or NSFe to WAV.
+// Only the VRC6, MMC5 and Namco 163 expansion chips are supported.
+func renderNSF(args []string) {
+    flags := flag.NewFlagSet("nsf", flag.ExitOnError)
+    flags.Usage = func() {
+        fmt.Fprintln(os.Stderr, "usage: gones nsf [flags] file.nsf")
+        fmt.Fprintln(os.Stderr, "Renders a track to WAV. The VRC6, MMC5 and Namco 163 are the only expansion")
+        fmt.Fprintln(os.Stderr, "chips supported; files needing the VRC7, FDS or Sunsoft 5B won't play.")
+        flags.PrintDefaults()
+    }
+    track := flags.Int("track", 0, "the track to play, numbered from 1 (defaults to the file's starting track)")
+    length := flags.Duration("length", 0, "how long to play for before fading out (defaults to the file's length)")
+    fade := flags.Duration("fade", -1, "how long to fade out for (defaults to the file's fade)")
+    region := flags.String("region", "", "force the console region (NTSC, PAL or Dendy)")
+    rate := flags.Int("rate", 44100, "the audio sample rate")
+    out := flags.String("out", "out.wav", "the WAV file to write")
+    flags.Parse(args)
 
     var file *os.File
     var err error
-    if file, err = os.Open(path); err != nil {
+    if file, err = os.Open(flags.Arg(0)); err != nil {
+        log.Fatal(err)
+        return
+    }
+
+    var music *nsf.NSF
+    if music, err = nsf.Read(file); err != nil {
+        log.Fatal(err)
+        return
+    }
+
+    var player *nsf.Player
+    if player, err = nsf.NewPlayer(music); err != nil {
         log.Fatal(err)
         return
     }
 
-    var rom *nes.ROM
-    rom, err = nes.ReadROM(file)
+    if *region != "" {
+        var r cpu.Region
+        if r, err = cpu.ParseRegion(*region); err != nil {
+            log.Fatal(err)
+            return
+        }
+
+        player.SetRegion(r)
+    }
+
+    if *track == 0 {
+        *track = music.StartingSong
+    }
+
+    known, knownFade, ok := music.Length(*track)
+    switch {
+        case *length > 0:
+        case ok:
+            *length = known
+        default:
+            *length = DEFAULT_LENGTH
+    }
+    switch {
+        case *fade >= 0:
+        case knownFade >= 0:
+            *fade = knownFade
+        default:
+            *fade = DEFAULT_FADE
+    }
+
+    fmt.Printf("%s - %s (%s)\n", music.Title, music.Artist, music.Copyright)
+    fmt.Printf("Track %d of %d %s, %v with a %v fade\n", *track, music.Songs, music.TrackName(*track), *length, *fade)
+
+    var wav *os.File
+    if wav, err = os.Create(*out); err != nil {
+        log.Fatal(err)
+        return
+    }
+
+    var sink *audio.WAVSink
+    if sink, err = audio.NewWAVSink(wav, *rate); err != nil {
+        log.Fatal(err)
+        return
+    }
+
+    if err = player.Render(*track, *length, *fade, *rate, sink); err != nil {
+        log.Fatal(err)
+    }
+}
+
+// Logs the machine's APU writes to VGM files, starting and stopping each
+// time it's toggled. Logs after the first are numbered.
+type vgmLog struct {
+    machine *nes.Machine
+    path string
+    count int
+}
+
+func (v *vgmLog) toggle() {
+    if v.machine.IO.VGM != nil {
+        if err := v.machine.StopVGM(); err != nil {
+            log.Print(err)
+        }
+        return
+    }
+
+    v.count++
+    path := v.path
+    if v.count > 1 {
+        ext := filepath.Ext(path)
+        path = fmt.Sprintf("%s.%d%s", strings.TrimSuffix(path, ext), v.count, ext)
+    }
+
+    out, err := os.Create(path)
+    if err != nil {
+        log.Print(err)
+        return
+    }
+
+    v.machine.RecordVGM(out)
+    log.Printf("Logging APU writes to %s", path)
+}
+
+// The keys standing in for the Power Pad's twelve buttons, in order.
+var powerPadKeys = []string { "1", "2", "3", "4", "Q", "W", "E", "R", "A", "S", "D", "F" }
+
+func pressPowerPad(machine *nes.Machine, pad *input.PowerPad, name string, pressed bool) {
+    for i, key := range powerPadKeys {
+        if key != name {
+            continue
+        }
+
+        machine.UpdateInput(func() {
+            if pressed {
+                pad.SetPressed(pad.Pressed | 1 << uint(i))
+            } else {
+                pad.SetPressed(pad.Pressed &^ (1 << uint(i)))
+            }
+        })
+    }
+}
+
+func readROM(path string) (*nes.ROM, error) {
+    file, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    return nes.ReadROM(file)
+}
+
+// gones play-movie [flags] rom movie -- plays an FM2 or BK2 movie back as
+// fast as possible with no window, then prints hashes of the RAM and the
+// last frame to compare against.
+func playMovie(args []string) {
+    flags := flag.NewFlagSet("play-movie", flag.ExitOnError)
+    region := flags.String("region", "", "force the console region (NTSC, PAL or Dendy)")
+    flags.Parse(args)
+
+    rom, err := readROM(flags.Arg(0))
     if err != nil {
         log.Fatal(err)
         return
     }
 
+    var mv *movie.Movie
+    if mv, err = movie.Load(flags.Arg(1)); err != nil {
+        log.Fatal(err)
+        return
+    }
+
     machine := nes.NewMachine()
     machine.Insert(rom)
 
+    if *region != "" {
+        var r cpu.Region
+        if r, err = cpu.ParseRegion(*region); err != nil {
+            log.Fatal(err)
+            return
+        }
+
+        machine.SetRegion(r)
+    }
+
+    if err = machine.PlayMovie(mv); err != nil {
+        log.Fatal(err)
+        return
+    }
+
+    for machine.PlayingMovie() {
+        machine.RunFrame()
+    }
+
+    ram := sha1.New()
+    for location := 0; location < 0x800; location++ {
+        ram.Write([]byte { machine.CPU.Memory.ReadDebug(cpu.Address(location)) })
+    }
+
+    fmt.Printf("frames %d ram %x frame %x\n", len(mv.Frames), ram.Sum(nil), sha1.Sum(machine.PPU.Display))
+}
+
+func main() {
+    if len(os.Args) > 1 && os.Args[1] == "nsf" {
+        renderNSF(os.Args[2:])
+        return
+    }
+
+    if len(os.Args) > 1 && os.Args[1] == "play-movie" {
+        playMovie(os.Args[2:])
+        return
+    }
+
+    region := flag.String("region", "", "force the console region (NTSC, PAL or Dendy)")
+    filter := flag.String("filter", "", "filter the picture like a TV (composite, svideo or rgb)")
+    wav := flag.String("wav", "", "record the audio to a WAV file")
+    rate := flag.Int("rate", 44100, "the audio sample rate")
+    vgmPath := flag.String("vgm", "", "log APU writes to a VGM file; SIGUSR1 stops and restarts logging")
+    keysPath := flag.String("keys", filepath.Join(os.Getenv("HOME"), ".gones", "keys.json"), "the key bindings file")
+    turbo := flag.Float64("turbo", 0, "turbo presses per second (defaults to the key bindings file's)")
+    port2 := flag.String("port2", "controller", "what to plug into the second port (controller, zapper, vaus or powerpad)")
+    expansion := flag.String("expansion", "none", "what to plug into the expansion port (none, vaus or keyboard)")
+    multitap := flag.String("multitap", "none", "attach a multitap for four players (fourscore or hori)")
+    record := flag.String("record", "", "record the input to an FM2 or BK2 movie")
+    play := flag.String("movie", "", "play back an FM2 or BK2 movie")
+    rewind := flag.Int("rewind", nes.REWIND_BUDGET >> 20, "megabytes of rewind history to keep (0 turns rewinding off)")
+    runAhead := flag.Int("runahead", 0, "frames to run ahead of the picture, hiding that many frames of the game's input lag")
+    secondInstance := flag.Bool("runahead-instance", false, "run ahead on a second copy of the machine, which keeps the sound from glitching")
+    statesDir := flag.String("states", filepath.Join(os.Getenv("HOME"), ".gones", "states"), "where savestate slots are kept")
+    flag.Parse()
+
+    path := flag.Arg(0)
+
+    rom, err := readROM(path)
+    if err != nil {
+        log.Fatal(err)
+        return
+    }
+
+    machine := nes.NewMachine()
+    machine.Insert(rom)
+
+    if *region != "" {
+        var r cpu.Region
+        if r, err = cpu.ParseRegion(*region); err != nil {
+            log.Fatal(err)
+            return
+        }
+
+        machine.SetRegion(r)
+    }
+
+    if *wav != "" {
+        var out *os.File
+        if out, err = os.Create(*wav); err != nil {
+            log.Fatal(err)
+            return
+        }
+
+        var sink *audio.WAVSink
+        if sink, err = audio.NewWAVSink(out, *rate); err != nil {
+            log.Fatal(err)
+            return
+        }
+
+        machine.PlayAudio(*rate, sink)
+        defer machine.Audio.Close()
+    }
+
     machine.CPU.Debug = false
     machine.CPU.Reset()
 
-    machine.CPU.Cycle = func() {
-        for i:=0; i < 3; i++ {
-            machine.PPU.Step()
+    toggleVGM := make(chan os.Signal, 1)
+    vgm := &vgmLog { machine: machine, path: *vgmPath }
+    if *vgmPath != "" {
+        vgm.toggle()
+        defer machine.StopVGM()
+
+        signal.Notify(toggleVGM, syscall.SIGUSR1)
+    }
+
+    var tv *ntsc.Filter
+    switch *filter {
+        case "":
+        case "composite":
+            tv = ntsc.NewFilter(ntsc.CompositeSettings)
+        case "svideo":
+            tv = ntsc.NewFilter(ntsc.SVideoSettings)
+        case "rgb":
+            tv = ntsc.NewFilter(ntsc.RGBSettings)
+        default:
+            log.Fatalf("unknown filter: %s", *filter)
+            return
+    }
+
+    attached := false
+    for kind, name := range input.MultitapNames {
+        if strings.EqualFold(*multitap, name) {
+            machine.AttachMultitap(kind)
+            attached = true
         }
     }
+    if !attached {
+        log.Fatalf("unknown multitap: %s", *multitap)
+        return
+    }
+
+    config, err := keymap.LoadConfig(*keysPath)
+    if err != nil {
+        log.Fatal(err)
+        return
+    }
+
+    keys := keymap.New(config)
+    keys.FrameRate = machine.Region.Timing().FrameRate
+    if *turbo > 0 {
+        keys.SetTurboRate(*turbo)
+    }
+
+    // Hotkeys for the emulation are handed over to its goroutine; rebinding
+    // happens straight away.
+    actions := make(chan string, 16)
 
     screen := video.NewVideo()
+    screen.OnKey = func(name string, pressed bool) {
+        if !pressed {
+            keys.KeyUp(name)
+            return
+        }
+
+        switch action := keys.KeyDown(name); action {
+            case "":
+            case keymap.REBIND_1:
+                keys.Rebind(0)
+            case keymap.REBIND_2:
+                keys.Rebind(1)
+            case keymap.REBIND_3:
+                keys.Rebind(2)
+            case keymap.REBIND_4:
+                keys.Rebind(3)
+            case keymap.REBOUND:
+                if err := keymap.SaveConfig(*keysPath, keys.Config()); err != nil {
+                    log.Print(err)
+                }
+                log.Printf("Saved key bindings to %s", *keysPath)
+            default:
+                select {
+                    case actions <- action:
+                    default:
+                }
+        }
+
+        if player, button, ok := keys.Rebinding(); ok {
+            log.Printf("Press a key for player %d's %s", player + 1, button)
+        }
+    }
+
+    // Pointing devices follow the mouse. The left button pulls the trigger,
+    // and for the Zapper the right shoots off the screen.
+    pointAt := func(port int) {
+        screen.OnMouse = func(x float64, y float64, left bool, right bool) {
+            if right {
+                machine.SetPointer(port, -1, -1, true)
+            } else {
+                machine.SetPointer(port, int(x * 256), int(y * 240), left)
+            }
+        }
+    }
+
+    switch strings.ToLower(*port2) {
+        case "controller":
+        case "zapper":
+            machine.Plug(1, input.NewZapper(machine.PPU))
+            pointAt(1)
+        case "vaus":
+            machine.Plug(1, input.NewVaus())
+            pointAt(1)
+        case "powerpad":
+            pad := input.NewPowerPad()
+            machine.Plug(1, pad)
+            onKey := screen.OnKey
+            screen.OnKey = func(name string, pressed bool) {
+                onKey(name, pressed)
+                pressPowerPad(machine, pad, name, pressed)
+            }
+        default:
+            log.Fatalf("unknown device for the second port: %s", *port2)
+            return
+    }
+
+    switch strings.ToLower(*expansion) {
+        case "none":
+        case "vaus":
+            machine.PlugExpansion(input.NewFamicomVaus())
+            pointAt(2)
+        case "keyboard":
+            machine.PlugExpansion(input.NewKeyboard())
+            onKey := screen.OnKey
+            screen.OnKey = func(name string, pressed bool) {
+                onKey(name, pressed)
+                machine.SetKey(name, pressed)
+            }
+        default:
+            log.Fatalf("unknown device for the expansion port: %s", *expansion)
+            return
+    }
+
+    name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+    var recording *movie.Movie
+    switch {
+        case *play != "":
+            var mv *movie.Movie
+            if mv, err = movie.Load(*play); err != nil {
+                log.Fatal(err)
+                return
+            }
+
+            if err = machine.PlayMovie(mv); err != nil {
+                log.Fatal(err)
+                return
+            }
+        case *record != "":
+            if recording, err = machine.RecordMovie(); err != nil {
+                log.Fatal(err)
+                return
+            }
+            recording.ROMName = name
+    }
+
+    slots := nes.NewSlots(*statesDir, name)
+    machine.EnableRewind(nes.REWIND_INTERVAL, *rewind << 20)
+    machine.EnableRunAhead(*runAhead, *secondInstance)
+
     screen.Init(640, 600)
 
+    stop := make(chan bool)
+    stopped := make(chan bool)
+
     go func() {
+        frameTime := time.Duration(float64(time.Second) / machine.Region.Timing().FrameRate)
+        ticker := time.NewTicker(frameTime)
+
+        paused := false
+        frames := 0
+        slot := 0
+
         for {
-            machine.CPU.Step()
+            select {
+                case <-stop:
+                    ticker.Stop()
+                    close(stopped)
+                    return
+                case <-ticker.C:
+            }
+
+            advance := false
+
+            for pending := true; pending; {
+                select {
+                    case <-toggleVGM:
+                        vgm.toggle()
+                    case action := <-actions:
+                        switch action {
+                            case keymap.PAUSE:
+                                paused = !paused
+                            case keymap.RESET:
+                                machine.Reset()
+                            case keymap.FRAME_ADVANCE:
+                                paused = true
+                                advance = true
+                            case keymap.SAVE_STATE:
+                                if err := slots.Save(machine, slot); err != nil {
+                                    log.Print(err)
+                                } else {
+                                    log.Printf("Saved slot %d", slot)
+                                }
+                            case keymap.LOAD_STATE:
+                                if err := slots.Load(machine, slot); err != nil {
+                                    log.Print(err)
+                                } else {
+                                    log.Printf("Loaded slot %d", slot)
+                                }
+                            case keymap.NEXT_SLOT:
+                                slot = (slot + 1) % SLOTS
+                                log.Printf("Slot %d", slot)
+                        }
+                    default:
+                        pending = false
+                }
+            }
+
+            switch {
+                // Rewinding goes back a frame a tick, paused or not.
+                case keys.Holding(keymap.REWIND):
+                    if !machine.RewindFrame() {
+                        continue
+                    }
+                case paused && !advance:
+                    continue
+                default:
+                    // A movie being played back presses the buttons itself.
+                    if !machine.PlayingMovie() {
+                        for player := 0; player < keymap.PLAYERS; player++ {
+                            machine.SetButtons(player, keys.Buttons(player, frames))
+                        }
+                    }
+                    frames++
+
+                    machine.RunFrame()
+            }
 
             frame := new(video.Frame)
             frame.Data = machine.PPU.Display
             frame.Width = 256
             frame.Height = 240
 
-            screen.Frames <- frame
+            if tv != nil {
+                frame.Data = tv.Render(machine.PPU.Output, machine.PPU.FramePhase)
+                frame.Width = tv.Width
+            }
+
+            select {
+                case screen.Frames <- frame:
+                case <-stop:
+            }
         }
     }()
 
     screen.Loop()
-}
 
+    // Let the emulation finish the frame it's on before saving anything.
+    close(stop)
+    <-stopped
+
+    if recording != nil {
+        if err = movie.Save(*record, recording); err != nil {
+            log.Fatal(err)
+        }
+    }
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+    "nes"
+    "ppu"
+    "viewer"
+    "os"
+    "log"
+    "fmt"
+    "flag"
+    "image"
+    "image/png"
+    "path/filepath"
+)
+
+func writePNG(path string, img image.Image) {
+    var file *os.File
+    var err error
+    if file, err = os.Create(path); err != nil {
+        log.Fatal(err)
+        return
+    }
+    defer file.Close()
+
+    if err = png.Encode(file, img); err != nil {
+        log.Fatal(err)
+    }
+}
+
+// Runs a ROM headlessly for a number of frames, then writes images of the
+// PPU's nametables, sprites, palettes and pattern tables.
+func main() {
+    frames := flag.Int("frame", 60, "the number of frames to run before capturing")
+    palette := flag.Int("palette", 0, "the palette to colour the pattern tables with (0-7)")
+    out := flag.String("out", ".", "the directory to write the images to")
+    hideBackground := flag.Bool("hide-background", false, "leave the background out of the screen")
+    hideSprites := flag.Bool("hide-sprites", false, "leave the sprites out of the screen")
+    ignorePriority := flag.Bool("ignore-priority", false, "draw every sprite in front of the background")
+    showLeft := flag.Bool("show-left", false, "draw the left column even when the game clips it")
+    events := flag.Bool("events", false, "list the events of the last frame instead of the sprites")
+    flag.Parse()
+
+    path := flag.Arg(0)
+
+    var file *os.File
+    var err error
+    if file, err = os.Open(path); err != nil {
+        log.Fatal(err)
+        return
+    }
+
+    var rom *nes.ROM
+    rom, err = nes.ReadROM(file)
+    if err != nil {
+        log.Fatal(err)
+        return
+    }
+
+    machine := nes.NewMachine()
+    machine.Insert(rom)
+
+    machine.PPU.RenderOptions = ppu.RenderOptions {
+        HideBackground: *hideBackground,
+        HideSprites: *hideSprites,
+        IgnorePriority: *ignorePriority,
+        ShowLeftColumn: *showLeft,
+    }
+
+    eventLog := machine.LogEvents()
+
+    machine.CPU.Debug = false
+    machine.CPU.Reset()
+
+    for i := 0; i < *frames; i++ {
+        machine.RunFrame()
+    }
+
+    p := machine.PPU
+
+    writePNG(filepath.Join(*out, "screen.png"), viewer.Screen(p))
+    writePNG(filepath.Join(*out, "nametables.png"), viewer.Nametables(p))
+    writePNG(filepath.Join(*out, "sprites.png"), viewer.Sprites(p))
+    writePNG(filepath.Join(*out, "sprite-layout.png"), viewer.SpriteLayout(p))
+    writePNG(filepath.Join(*out, "palettes.png"), viewer.Palettes(p))
+    writePNG(filepath.Join(*out, "patterntables.png"), viewer.Patterntables(p, *palette))
+    writePNG(filepath.Join(*out, "events.png"), viewer.EventMap(p, eventLog.Previous()))
+
+    if *events {
+        for _, e := range eventLog.Previous() {
+            fmt.Println(e)
+        }
+    } else {
+        for _, s := range viewer.OAM(p) {
+            fmt.Println(s)
+        }
+    }
+}
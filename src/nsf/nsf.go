@@ -0,0 +1,291 @@
+package nsf
+
+import (
+    "io"
+    "fmt"
+    "cpu"
+    "time"
+    "bytes"
+    "errors"
+    "strings"
+    "io/ioutil"
+    "encoding/binary"
+)
+
+// Expansion sound chips, as flagged in the header.
+const (
+    VRC6 = 0x01
+    VRC7 = 0x02
+    FDS = 0x04
+    MMC5 = 0x08
+    N163 = 0x10
+    S5B = 0x20
+)
+
+var ChipNames = map[byte]string {
+    VRC6: "VRC6",
+    VRC7: "VRC7",
+    FDS:  "FDS",
+    MMC5: "MMC5",
+    N163: "Namco 163",
+    S5B:  "Sunsoft 5B",
+}
+
+// The play routine's default rates, in microseconds, for NSFe files which
+// don't give one.
+const (
+    NTSC_SPEED = 16639
+    PAL_SPEED = 19997
+)
+
+// A ripped music driver and its songs, from an NSF or NSFe file.
+//
+// See -- http://wiki.nesdev.com/w/index.php/NSF
+// See -- http://wiki.nesdev.com/w/index.php/NSFe
+type NSF struct {
+    Songs int
+
+    // Numbered from 1.
+    StartingSong int
+
+    LoadAddress cpu.Address
+    InitAddress cpu.Address
+    PlayAddress cpu.Address
+
+    Title string
+    Artist string
+    Copyright string
+
+    // How often to call the play routine, in microseconds.
+    NTSCSpeed uint16
+    PALSpeed uint16
+
+    // The initial bank for each 4K of $8000-$FFFF. Bankswitching is only used
+    // if any of them are non-zero.
+    Banks [8]byte
+
+    // The region the music was written for.
+    Region cpu.Region
+
+    Chips byte
+
+    Data []byte
+
+    // Only NSFe files have these. Lengths and fades of -1 are unknown.
+    Lengths []time.Duration
+    Fades []time.Duration
+    TrackNames []string
+}
+
+func (n *NSF) Bankswitched() bool {
+    for _, bank := range n.Banks {
+        if bank != 0 {
+            return true
+        }
+    }
+
+    return false
+}
+
+// The play routine's period for a region, in microseconds.
+func (n *NSF) Speed(region cpu.Region) uint16 {
+    if region == cpu.NTSC {
+        return n.NTSCSpeed
+    }
+
+    return n.PALSpeed
+}
+
+// How long a track lasts and fades out for, if the file says.
+func (n *NSF) Length(track int) (length time.Duration, fade time.Duration, ok bool) {
+    length, fade = -1, -1
+
+    if track >= 1 && track <= len(n.Lengths) {
+        length = n.Lengths[track - 1]
+    }
+    if track >= 1 && track <= len(n.Fades) {
+        fade = n.Fades[track - 1]
+    }
+
+    return length, fade, length >= 0
+}
+
+func (n *NSF) TrackName(track int) string {
+    if track >= 1 && track <= len(n.TrackNames) {
+        return n.TrackNames[track - 1]
+    }
+
+    return ""
+}
+
+var (
+    nsfMagic = []byte { 0x4E, 0x45, 0x53, 0x4D, 0x1A }
+    nsfeMagic = []byte { 0x4E, 0x53, 0x46, 0x45 }
+)
+
+// Reads either an NSF or an NSFe file.
+func Read(r io.Reader) (*NSF, error) {
+    raw, err := ioutil.ReadAll(r)
+    if err != nil {
+        return nil, err
+    }
+
+    switch {
+        case bytes.HasPrefix(raw, nsfMagic):
+            return parseNSF(raw)
+        case bytes.HasPrefix(raw, nsfeMagic):
+            return parseNSFe(raw)
+    }
+
+    return nil, errors.New("NSF header invalid. Is this really an NSF file?")
+}
+
+func word(raw []byte) uint16 {
+    return binary.LittleEndian.Uint16(raw)
+}
+
+func text(raw []byte) string {
+    if end := bytes.IndexByte(raw, 0); end >= 0 {
+        raw = raw[:end]
+    }
+
+    return strings.TrimSpace(string(raw))
+}
+
+func region(flags byte) cpu.Region {
+    // Dual region tunes are played as NTSC.
+    if flags & 0x03 == 0x01 {
+        return cpu.PAL
+    }
+
+    return cpu.NTSC
+}
+
+func parseNSF(raw []byte) (*NSF, error) {
+    if len(raw) < 0x80 {
+        return nil, errors.New("NSF header truncated")
+    }
+
+    n := new(NSF)
+
+    n.Songs = int(raw[0x06])
+    n.StartingSong = int(raw[0x07])
+    n.LoadAddress = cpu.Address(word(raw[0x08:]))
+    n.InitAddress = cpu.Address(word(raw[0x0a:]))
+    n.PlayAddress = cpu.Address(word(raw[0x0c:]))
+    n.Title = text(raw[0x0e:0x2e])
+    n.Artist = text(raw[0x2e:0x4e])
+    n.Copyright = text(raw[0x4e:0x6e])
+    n.NTSCSpeed = word(raw[0x6e:])
+    copy(n.Banks[:], raw[0x70:0x78])
+    n.PALSpeed = word(raw[0x78:])
+    n.Region = region(raw[0x7a])
+    n.Chips = raw[0x7b]
+
+    n.Data = raw[0x80:]
+
+    // NSF2 gives the program's length, so that metadata can follow it.
+    length := int(raw[0x7d]) | int(raw[0x7e]) << 8 | int(raw[0x7f]) << 16
+    if raw[0x05] >= 2 && length > 0 && length < len(n.Data) {
+        n.Data = n.Data[:length]
+    }
+
+    return n, nil
+}
+
+// NSFe files are a series of chunks, each a length, a four letter ID and
+// the data. Chunks with an upper case first letter must be understood.
+func parseNSFe(raw []byte) (*NSF, error) {
+    n := new(NSF)
+    n.Songs = 1
+    n.StartingSong = 1
+    n.NTSCSpeed = NTSC_SPEED
+    n.PALSpeed = PAL_SPEED
+
+    var info, data bool
+
+    for offset := 4; ; {
+        if offset + 8 > len(raw) {
+            return nil, errors.New("NSFe file ends without an NEND chunk")
+        }
+
+        length := int(binary.LittleEndian.Uint32(raw[offset:]))
+        id := string(raw[offset + 4:offset + 8])
+        offset += 8
+
+        if length < 0 || offset + length > len(raw) {
+            return nil, fmt.Errorf("NSFe chunk %q truncated", id)
+        }
+        chunk := raw[offset:offset + length]
+        offset += length
+
+        switch id {
+            case "INFO":
+                if length < 9 {
+                    return nil, errors.New("NSFe INFO chunk truncated")
+                }
+
+                n.LoadAddress = cpu.Address(word(chunk[0:]))
+                n.InitAddress = cpu.Address(word(chunk[2:]))
+                n.PlayAddress = cpu.Address(word(chunk[4:]))
+                n.Region = region(chunk[6])
+                n.Chips = chunk[7]
+                n.Songs = int(chunk[8])
+
+                // Unlike NSF, NSFe numbers the starting song from 0.
+                if length > 9 {
+                    n.StartingSong = int(chunk[9]) + 1
+                }
+                info = true
+            case "DATA":
+                n.Data = chunk
+                data = true
+            case "BANK":
+                copy(n.Banks[:], chunk)
+            case "RATE":
+                if length >= 2 { n.NTSCSpeed = word(chunk[0:]) }
+                if length >= 4 { n.PALSpeed = word(chunk[2:]) }
+            case "auth":
+                fields := strings.Split(string(chunk), "\x00")
+                for i, field := range fields {
+                    switch i {
+                        case 0: n.Title = field
+                        case 1: n.Artist = field
+                        case 2: n.Copyright = field
+                    }
+                }
+            case "time":
+                n.Lengths = durations(chunk)
+            case "fade":
+                n.Fades = durations(chunk)
+            case "tlbl":
+                n.TrackNames = strings.Split(strings.TrimSuffix(string(chunk), "\x00"), "\x00")
+            case "NEND":
+                if !info || !data {
+                    return nil, errors.New("NSFe file is missing its INFO or DATA chunk")
+                }
+
+                return n, nil
+            default:
+                if id[0] >= 'A' && id[0] <= 'Z' {
+                    return nil, fmt.Errorf("NSFe chunk %q isn't supported", id)
+                }
+        }
+    }
+}
+
+func durations(chunk []byte) []time.Duration {
+    results := make([]time.Duration, len(chunk) / 4)
+
+    for i := range results {
+        ms := int32(binary.LittleEndian.Uint32(chunk[i * 4:]))
+
+        if ms < 0 {
+            results[i] = -1
+        } else {
+            results[i] = time.Duration(ms) * time.Millisecond
+        }
+    }
+
+    return results
+}
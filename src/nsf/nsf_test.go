@@ -0,0 +1,115 @@
+package nsf
+
+import (
+    "cpu"
+    "time"
+    "bytes"
+    "testing"
+    "encoding/binary"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func header(songs byte, load, init, play uint16) []byte {
+    raw := make([]byte, 0x80)
+
+    copy(raw, nsfMagic)
+    raw[0x05] = 1
+    raw[0x06] = songs
+    raw[0x07] = 1
+    binary.LittleEndian.PutUint16(raw[0x08:], load)
+    binary.LittleEndian.PutUint16(raw[0x0a:], init)
+    binary.LittleEndian.PutUint16(raw[0x0c:], play)
+    copy(raw[0x0e:], "Title")
+    copy(raw[0x2e:], "Artist")
+    copy(raw[0x4e:], "2015 Someone")
+    binary.LittleEndian.PutUint16(raw[0x6e:], NTSC_SPEED)
+    binary.LittleEndian.PutUint16(raw[0x78:], PAL_SPEED)
+
+    return raw
+}
+
+func TestReadNSF(t *testing.T) {
+    raw := header(5, 0x8000, 0x8001, 0x8002)
+    raw[0x71] = 0x03
+    raw[0x7a] = 0x01
+    raw[0x7b] = VRC6
+    raw = append(raw, 0xde, 0xad)
+
+    n, err := Read(bytes.NewReader(raw))
+    assert.Nil(t, err)
+
+    assert.Equal(t, n.Songs, 5)
+    assert.Equal(t, n.StartingSong, 1)
+    assert.Equal(t, n.LoadAddress, cpu.Address(0x8000))
+    assert.Equal(t, n.InitAddress, cpu.Address(0x8001))
+    assert.Equal(t, n.PlayAddress, cpu.Address(0x8002))
+    assert.Equal(t, n.Title, "Title")
+    assert.Equal(t, n.Artist, "Artist")
+    assert.Equal(t, n.Copyright, "2015 Someone")
+    assert.Equal(t, n.Banks, [8]byte { 0, 3, 0, 0, 0, 0, 0, 0 })
+    assert.True(t, n.Bankswitched())
+    assert.Equal(t, n.Region, cpu.PAL)
+    assert.Equal(t, n.Speed(cpu.PAL), uint16(PAL_SPEED))
+    assert.Equal(t, n.Chips, byte(VRC6))
+    assert.Equal(t, n.Data, []byte { 0xde, 0xad })
+}
+
+func TestReadRejectsOtherFiles(t *testing.T) {
+    _, err := Read(bytes.NewReader([]byte("NES\x1a")))
+
+    assert.NotNil(t, err)
+}
+
+func chunk(id string, data []byte) []byte {
+    raw := make([]byte, 8)
+    binary.LittleEndian.PutUint32(raw, uint32(len(data)))
+    copy(raw[4:], id)
+
+    return append(raw, data...)
+}
+
+func TestReadNSFe(t *testing.T) {
+    info := []byte { 0x00, 0x80, 0x01, 0x80, 0x02, 0x80, 0x00, 0x00, 3, 1 }
+    times := make([]byte, 8)
+    binary.LittleEndian.PutUint32(times, 90000)
+    binary.LittleEndian.PutUint32(times[4:], 0xffffffff)
+
+    raw := append([]byte(nil), nsfeMagic...)
+    raw = append(raw, chunk("INFO", info)...)
+    raw = append(raw, chunk("DATA", []byte { 0x60 })...)
+    raw = append(raw, chunk("auth", []byte("Song\x00Composer\x00\x00Ripper\x00"))...)
+    raw = append(raw, chunk("time", times)...)
+    raw = append(raw, chunk("tlbl", []byte("Intro\x00Boss\x00"))...)
+    raw = append(raw, chunk("junk", []byte { 1, 2, 3 })...)
+    raw = append(raw, chunk("NEND", nil)...)
+
+    n, err := Read(bytes.NewReader(raw))
+    assert.Nil(t, err)
+
+    assert.Equal(t, n.Songs, 3)
+    assert.Equal(t, n.StartingSong, 2)
+    assert.Equal(t, n.InitAddress, cpu.Address(0x8001))
+    assert.Equal(t, n.Title, "Song")
+    assert.Equal(t, n.Artist, "Composer")
+    assert.Equal(t, n.NTSCSpeed, uint16(NTSC_SPEED))
+    assert.Equal(t, n.Data, []byte { 0x60 })
+    assert.Equal(t, n.TrackName(2), "Boss")
+
+    length, _, ok := n.Length(1)
+    assert.True(t, ok)
+    assert.Equal(t, length, 90 * time.Second)
+
+    _, _, ok = n.Length(2)
+    assert.False(t, ok)
+}
+
+func TestReadNSFeRejectsUnknownRequiredChunks(t *testing.T) {
+    raw := append([]byte(nil), nsfeMagic...)
+    raw = append(raw, chunk("INFO", make([]byte, 9))...)
+    raw = append(raw, chunk("WHAT", nil)...)
+    raw = append(raw, chunk("NEND", nil)...)
+
+    _, err := Read(bytes.NewReader(raw))
+
+    assert.NotNil(t, err)
+}
@@ -0,0 +1,324 @@
+package nsf
+
+import (
+    "fmt"
+    "cpu"
+    "apu"
+    "time"
+    "audio"
+)
+
+// Where routines return to. Nothing is ever run from here; the player stops
+// stepping the CPU when it gets there.
+const RETURN = 0x4100
+
+// The expansion chips the player has. Files which need the VRC7, the FDS
+// or the Sunsoft 5B won't load.
+const SUPPORTED_CHIPS = VRC6 | MMC5 | N163
+
+// The longest INIT or PLAY can run for before the player gives up on them.
+const CALL_LIMIT = 2000000
+
+// A stand-in for a console with the music driver's cartridge in it: just the
+// CPU, the APU and any expansion audio, with a player calling INIT once and
+// then PLAY at the rate the file asks for.
+//
+// See -- http://wiki.nesdev.com/w/index.php/NSF#Initializing_a_tune
+type Player struct {
+    NSF *NSF
+
+    CPU *cpu.CPU
+    APU *apu.APU
+    VRC6 *apu.VRC6
+    MMC5 *apu.MMC5
+    N163 *apu.N163
+
+    Region cpu.Region
+
+    // Audio is nil until PlayAudio is called.
+    Audio *audio.Pipeline
+
+    // The track playing, numbered from 1.
+    Track int
+
+    // The program, padded so that it starts at the right place in its first
+    // bank, and the bank mapped in to each 4K of $8000-$FFFF.
+    program []byte
+    banks [8]int
+
+    // The cycle the next PLAY is due on.
+    next float64
+}
+
+func NewPlayer(n *NSF) (*Player, error) {
+    if unsupported := n.Chips &^ SUPPORTED_CHIPS; unsupported != 0 {
+        return nil, fmt.Errorf("Unsupported expansion audio %s", chipNames(unsupported))
+    }
+
+    p := new(Player)
+    p.NSF = n
+
+    pad := int(n.LoadAddress & 0x0fff)
+    if !n.Bankswitched() {
+        if n.LoadAddress < 0x8000 {
+            return nil, fmt.Errorf("Can't load NSF data at %#04x", n.LoadAddress)
+        }
+
+        pad = int(n.LoadAddress - 0x8000)
+    }
+    p.program = append(make([]byte, pad), n.Data...)
+
+    p.CPU = cpu.NewCPU()
+    p.APU = apu.NewAPU()
+
+    p.CPU.Memory.Mount(new(openBus), 0x2000, 0x3fff)
+    p.CPU.Memory.Mount(&registers { p }, 0x4000, 0x5fff)
+    p.CPU.Memory.Mount(cpu.NewRAM(0x2000), 0x6000, 0x7fff)
+    p.CPU.Memory.Mount(&program { p }, 0x8000, 0xffff)
+
+    chips := apu.Mix {}
+    if n.Chips & VRC6 == VRC6 {
+        p.VRC6 = apu.NewVRC6()
+        chips = append(chips, p.VRC6)
+    }
+    if n.Chips & MMC5 == MMC5 {
+        p.MMC5 = apu.NewMMC5()
+        chips = append(chips, p.MMC5)
+    }
+    if n.Chips & N163 == N163 {
+        p.N163 = apu.NewN163()
+        chips = append(chips, p.N163)
+    }
+
+    switch len(chips) {
+        case 0:
+        case 1:
+            p.APU.Expansion = chips[0]
+        default:
+            p.APU.Expansion = chips
+    }
+
+    p.APU.Bus = p.CPU
+    p.CPU.Cycle = p.cycle
+
+    // There's no DMA unit to steal cycles, so samples arrive straight away.
+    p.APU.DMA = func(location cpu.Address, done func(byte)) {
+        done(p.CPU.Memory.Read(location))
+    }
+
+    p.SetRegion(n.Region)
+
+    return p, nil
+}
+
+func chipNames(chips byte) string {
+    names := ""
+
+    for chip := byte(0x01); chip != 0; chip <<= 1 {
+        if chips & chip == 0 {
+            continue
+        }
+
+        name, ok := ChipNames[chip]
+        if !ok {
+            name = fmt.Sprintf("%#02x", chip)
+        }
+
+        if names != "" {
+            names += ", "
+        }
+        names += name
+    }
+
+    return names
+}
+
+func (p *Player) SetRegion(region cpu.Region) {
+    p.Region = region
+    p.APU.SetRegion(region)
+}
+
+// Sends the APU's output to a sink at the given sample rate. The resampling
+// is based on the current region's clock rate, so set the region first.
+func (p *Player) PlayAudio(rate int, sink audio.AudioSink) *audio.Pipeline {
+    p.Audio = audio.NewPipeline(p.Region.Timing().Clock, rate, sink)
+    return p.Audio
+}
+
+func (p *Player) cycle() {
+    p.APU.Step()
+    if p.Audio != nil {
+        p.Audio.Clock(p.APU)
+    }
+}
+
+// Sets everything up the way drivers expect and calls INIT for a track.
+func (p *Player) Play(track int) error {
+    if track < 1 || track > p.NSF.Songs {
+        return fmt.Errorf("There's no track %d, only %d", track, p.NSF.Songs)
+    }
+    p.Track = track
+
+    p.CPU.Reset()
+
+    for location := cpu.Address(0x0000); location < 0x0800; location++ {
+        p.CPU.Memory.Write(0x00, location)
+    }
+    for location := cpu.Address(0x6000); location < 0x8000; location++ {
+        p.CPU.Memory.Write(0x00, location)
+    }
+
+    for location := cpu.Address(0x4000); location < 0x4014; location++ {
+        p.CPU.Memory.Write(0x00, location)
+    }
+    p.CPU.Memory.Write(0x00, 0x4015)
+    p.CPU.Memory.Write(0x0f, 0x4015)
+    p.CPU.Memory.Write(0x40, 0x4017)
+
+    for i := range p.banks {
+        if p.NSF.Bankswitched() {
+            p.banks[i] = int(p.NSF.Banks[i])
+        } else {
+            p.banks[i] = i
+        }
+    }
+
+    p.CPU.A = byte(track - 1)
+    p.CPU.X = 0x00
+    if p.Region != cpu.NTSC {
+        p.CPU.X = 0x01
+    }
+
+    if err := p.call(p.NSF.InitAddress); err != nil {
+        return err
+    }
+
+    p.next = float64(p.CPU.Cycles())
+
+    return nil
+}
+
+// The number of CPU cycles between calls to PLAY.
+func (p *Player) Period() float64 {
+    return float64(p.NSF.Speed(p.Region)) * p.Region.Timing().Clock / 1000000
+}
+
+// Calls PLAY, then waits until the next one is due.
+func (p *Player) RunFrame() error {
+    if err := p.call(p.NSF.PlayAddress); err != nil {
+        return err
+    }
+
+    p.next += p.Period()
+    for float64(p.CPU.Cycles()) < p.next {
+        p.CPU.Tick()
+    }
+
+    return nil
+}
+
+// Runs a routine until it returns, by JSRing to it from RETURN.
+func (p *Player) call(routine cpu.Address) error {
+    ret := cpu.Address(RETURN - 1)
+
+    p.CPU.Memory.Write(byte(ret >> 8), 0x0100 | cpu.Address(p.CPU.SP))
+    p.CPU.SP--
+    p.CPU.Memory.Write(byte(ret), 0x0100 | cpu.Address(p.CPU.SP))
+    p.CPU.SP--
+
+    p.CPU.PC = routine
+
+    start := p.CPU.Cycles()
+    for p.CPU.PC != RETURN {
+        if p.CPU.Cycles() - start > CALL_LIMIT {
+            return fmt.Errorf("The routine at %#04x never returned", routine)
+        }
+
+        p.CPU.Step()
+    }
+
+    return nil
+}
+
+// Plays a track into a sink for length, then fades it out over fade.
+func (p *Player) Render(track int, length time.Duration, fade time.Duration, rate int, sink audio.AudioSink) error {
+    samples := func(d time.Duration) int {
+        return int(d.Seconds() * float64(rate))
+    }
+
+    fader := audio.NewFadeSink(sink, samples(length), samples(fade))
+    p.PlayAudio(rate, fader)
+
+    var err = p.Play(track)
+    for err == nil && p.Audio.Err == nil && !fader.Done() {
+        err = p.RunFrame()
+    }
+
+    if closeErr := p.Audio.Close(); err == nil {
+        err = closeErr
+    }
+
+    return err
+}
+
+// Reads of the PPU's registers, which drivers sometimes poll anyway.
+type openBus struct {}
+
+func (o *openBus) Read(location cpu.Address) byte { return 0x00 }
+func (o *openBus) Write(val byte, location cpu.Address) {}
+
+// The APU's registers, the bank registers at $5FF8-$5FFF, and the MMC5's
+// and 163's registers in between.
+type registers struct {
+    p *Player
+}
+
+func (r *registers) Read(location cpu.Address) byte {
+    switch {
+        case location <= apu.FRAME_COUNTER:
+            return r.p.APU.Read(location)
+        case location >= 0x1ff8:
+        case location >= 0x1000 && r.p.MMC5 != nil:
+            return r.p.MMC5.Read(0x4000 + location)
+        case location >= 0x0800 && location < 0x1000 && r.p.N163 != nil:
+            return r.p.N163.Read(0x4000 + location)
+    }
+
+    return 0x00
+}
+
+func (r *registers) Write(val byte, location cpu.Address) {
+    switch {
+        case location <= apu.FRAME_COUNTER:
+            r.p.APU.Write(val, location)
+        case location >= 0x1ff8:
+            r.p.banks[location - 0x1ff8] = int(val)
+        case location >= 0x1000 && r.p.MMC5 != nil:
+            r.p.MMC5.Write(val, 0x4000 + location)
+        case location >= 0x0800 && location < 0x1000 && r.p.N163 != nil:
+            r.p.N163.Write(val, 0x4000 + location)
+    }
+}
+
+// $8000-$FFFF, through the banks. Writes go to the expansion audio.
+type program struct {
+    p *Player
+}
+
+func (m *program) Read(location cpu.Address) byte {
+    offset := m.p.banks[location >> 12] * 0x1000 + int(location & 0x0fff)
+    if offset >= len(m.p.program) {
+        return 0x00
+    }
+
+    return m.p.program[offset]
+}
+
+func (m *program) Write(val byte, location cpu.Address) {
+    if m.p.VRC6 != nil {
+        m.p.VRC6.Write(val, 0x8000 + location)
+    }
+    if m.p.N163 != nil {
+        m.p.N163.Write(val, 0x8000 + location)
+    }
+}
@@ -0,0 +1,124 @@
+package nsf
+
+import (
+    "time"
+    "bytes"
+    "testing"
+    "apu"
+    "audio"
+    "github.com/stretchrcom/testify/assert"
+)
+
+// INIT stores the song number in $00, PLAY counts its calls in $01.
+var counter = []byte {
+    0x85, 0x00, 0x60,
+    0xe6, 0x01, 0x60,
+}
+
+func player(t *testing.T, raw []byte) *Player {
+    n, err := Read(bytes.NewReader(raw))
+    assert.Nil(t, err)
+
+    p, err := NewPlayer(n)
+    assert.Nil(t, err)
+
+    return p
+}
+
+func TestPlayerCallsInitWithTheSongNumber(t *testing.T) {
+    p := player(t, append(header(3, 0x8000, 0x8000, 0x8003), counter...))
+
+    assert.Nil(t, p.Play(3))
+    assert.Equal(t, p.CPU.Memory.Read(0x0000), byte(2))
+    assert.Equal(t, p.APU.Read(0x15) & 0x0f, byte(0x00))
+}
+
+func TestPlayerCallsPlayAtItsRate(t *testing.T) {
+    p := player(t, append(header(1, 0x8000, 0x8000, 0x8003), counter...))
+    p.Play(1)
+
+    start := p.CPU.Cycles()
+    for i := 0; i < 60; i++ {
+        assert.Nil(t, p.RunFrame())
+    }
+
+    assert.Equal(t, p.CPU.Memory.Read(0x0001), byte(60))
+
+    // 60 calls at 16639us each take almost exactly a second.
+    assert.InDelta(t, float64(p.CPU.Cycles() - start), 60 * p.Period(), 1)
+    assert.InDelta(t, p.Period(), 29780.5, 1)
+}
+
+func TestPlayerGivesUpOnRoutinesThatNeverReturn(t *testing.T) {
+    loop := []byte { 0x4c, 0x00, 0x80 }
+    p := player(t, append(header(1, 0x8000, 0x8000, 0x8000), loop...))
+
+    assert.NotNil(t, p.Play(1))
+}
+
+func TestPlayerSwitchesBanks(t *testing.T) {
+    raw := header(1, 0x8010, 0x8010, 0x8013)
+    raw[0x70] = 0
+    raw[0x71] = 2
+
+    data := make([]byte, 0x3000 - 0x10)
+    copy(data, counter)
+    data[0x1000 - 0x10] = 0xaa
+    data[0x2000 - 0x10] = 0xbb
+    p := player(t, append(raw, data...))
+
+    assert.Nil(t, p.Play(1))
+    assert.Equal(t, p.CPU.Memory.Read(0x9000), byte(0xbb))
+
+    p.CPU.Memory.Write(1, 0x5ff9)
+    assert.Equal(t, p.CPU.Memory.Read(0x9000), byte(0xaa))
+}
+
+func TestPlayerRejectsUnsupportedChips(t *testing.T) {
+    raw := header(1, 0x8000, 0x8000, 0x8003)
+    raw[0x7b] = VRC6 | N163 | FDS
+
+    n, _ := Read(bytes.NewReader(append(raw, counter...)))
+    _, err := NewPlayer(n)
+
+    assert.Equal(t, err.Error(), "Unsupported expansion audio FDS")
+}
+
+func TestPlayerMixesEveryChipItNeeds(t *testing.T) {
+    raw := header(1, 0x8000, 0x8000, 0x8003)
+    raw[0x7b] = MMC5 | N163
+    p := player(t, append(raw, counter...))
+
+    assert.Equal(t, p.APU.Expansion, apu.Mix { p.MMC5, p.N163 })
+
+    p.CPU.Memory.Write(0x0f, 0x5015)
+    p.CPU.Memory.Write(0x12, 0x5c34)
+    assert.Equal(t, p.CPU.Memory.Read(0x5015), byte(0x00))
+    assert.Equal(t, p.CPU.Memory.Read(0x5c34), byte(0x12))
+
+    p.CPU.Memory.Write(0x80 | 0x7f, 0xf800)
+    p.CPU.Memory.Write(0x70, 0x4800)
+    assert.Equal(t, p.N163.Channels(), 8)
+    assert.Equal(t, p.N163.Address, byte(0x00))
+}
+
+func TestPlayerSendsWritesToTheVRC6(t *testing.T) {
+    raw := header(1, 0x8000, 0x8000, 0x8003)
+    raw[0x7b] = VRC6
+    p := player(t, append(raw, counter...))
+
+    p.CPU.Memory.Write(0x3f, 0xb000)
+
+    assert.Equal(t, p.APU.Expansion, p.VRC6)
+    assert.Equal(t, p.VRC6.Saw.Rate, byte(0x3f))
+}
+
+func TestRenderWritesTheLengthAndFade(t *testing.T) {
+    p := player(t, append(header(1, 0x8000, 0x8000, 0x8003), counter...))
+
+    sink := new(audio.NullSink)
+    err := p.Render(1, time.Second, 500 * time.Millisecond, 44100, sink)
+
+    assert.Nil(t, err)
+    assert.Equal(t, sink.Samples, 44100 + 22050)
+}
@@ -0,0 +1,310 @@
+package ntsc
+
+import "math"
+
+// An NTSC filter turns the PPU's palette output into the composite signal
+// the NES would put on the wire, then decodes it the way a TV would. The
+// artifacts of doing so -- dot crawl, colour fringing on sharp edges and the
+// rainbows some games rely on -- come out of the decoding for free.
+//
+// The PPU produces twelve phases of colour subcarrier per cycle, and each
+// dot lasts eight of them, so the signal is generated at eight samples per
+// pixel. See -- http://wiki.nesdev.com/w/index.php/NTSC_video
+
+const (
+    SAMPLES_PER_PIXEL = 8
+    PHASES = 12
+
+    INPUT_WIDTH = 256
+    INPUT_HEIGHT = 240
+
+    // Roughly the width a 4:3 TV shows the picture at, relative to its
+    // height, once the overscan is accounted for.
+    OUTPUT_WIDTH = 602
+
+    SAMPLES = INPUT_WIDTH * SAMPLES_PER_PIXEL
+)
+
+type Mode int
+
+const (
+    Composite Mode = iota
+    SVideo
+    RGB
+)
+
+type Settings struct {
+    Mode Mode
+
+    // Rotation of the decoded hue, in degrees.
+    Hue float64
+
+    // 1 is normal, 0 is black and white.
+    Saturation float64
+
+    // -1 is soft, 0 is as the signal decodes, 1 is sharpened.
+    Sharpness float64
+
+    // How much luma and chroma bleed into each other, from 0 (none) to 1 (as
+    // much as composite video really does). Only composite video has them.
+    Artifacts float64
+}
+
+var CompositeSettings = Settings { Composite, 0, 1, 0, 1 }
+var SVideoSettings = Settings { SVideo, 0, 1, 0.2, 0 }
+var RGBSettings = Settings { RGB, 0, 1, 0, 0 }
+
+// Signal voltages for the low and high halves of the waveform of each of the
+// four brightness levels, and those of black and white.
+var lowLevels = [4]float64 { 0.350, 0.518, 0.962, 1.550 }
+var highLevels = [4]float64 { 1.094, 1.506, 1.962, 1.962 }
+
+const (
+    BLACK = 0.518
+    WHITE = 1.962
+
+    EMPHASIS_ATTENUATION = 0.746
+
+    // Lines up the decoder's idea of the colour burst with the PPU's, so that
+    // hue 0 comes out as the PPU's palette.
+    HUE_OFFSET = 3.9
+
+    GAMMA = 2.2 / 1.8
+)
+
+func inColorPhase(color int, phase int) bool {
+    return (color + phase) % PHASES < 6
+}
+
+// The signal level, between black at 0 and white at 1, of a pixel given as a
+// PPU Output entry at a particular subcarrier phase.
+func Signal(pixel uint16, phase int) float64 {
+    color := int(pixel & 0x0f)
+    level := int(pixel >> 4) & 0x03
+    emphasis := int(pixel >> 6) & 0x07
+
+    // Columns $e and $f are black
+    if color > 13 {
+        level = 1
+    }
+
+    low, high := lowLevels[level], highLevels[level]
+
+    // Column 0 is a flat gray, and column $d only the low half of one.
+    if color == 0 {
+        low = high
+    }
+    if color > 12 {
+        high = low
+    }
+
+    signal := low
+    if inColorPhase(color, phase) {
+        signal = high
+    }
+
+    // Emphasis attenuates the signal during the phases of red (colour $0),
+    // green ($4) and blue ($8).
+    if color < 14 &&
+        ((emphasis & 0x01 != 0 && inColorPhase(0, phase)) ||
+         (emphasis & 0x02 != 0 && inColorPhase(4, phase)) ||
+         (emphasis & 0x04 != 0 && inColorPhase(8, phase))) {
+
+        signal *= EMPHASIS_ATTENUATION
+    }
+
+    return (signal - BLACK) / (WHITE - BLACK)
+}
+
+type Filter struct {
+    Settings
+
+    Width int
+    Height int
+
+    // The decoded picture, as Width by Height RGB triples.
+    Frame []byte
+
+    composite []float64
+    luma []float64
+
+    // The average level of each Output entry over a whole subcarrier cycle,
+    // i.e. its luma with the chroma filtered out perfectly.
+    levels [512]float64
+
+    // The decoder's carriers for each phase, with the hue setting applied.
+    cos [PHASES]float64
+    sin [PHASES]float64
+
+    // The colours RGB mode outputs, decoded from a perfectly flat signal.
+    palette [512][3]byte
+}
+
+func NewFilter(settings Settings) *Filter {
+    f := new(Filter)
+
+    f.Width = OUTPUT_WIDTH
+    f.Height = INPUT_HEIGHT
+    f.Frame = make([]byte, f.Width * f.Height * 3)
+
+    // A little room either side so the decoder's window doesn't need to
+    // check the edges.
+    f.composite = make([]float64, SAMPLES + 4 * PHASES)
+    f.luma = make([]float64, SAMPLES + 4 * PHASES)
+
+    f.Configure(settings)
+
+    return f
+}
+
+func (f *Filter) Configure(settings Settings) {
+    f.Settings = settings
+
+    for p := 0; p < PHASES; p++ {
+        angle := math.Pi * (float64(p) + HUE_OFFSET) / 6 - settings.Hue * math.Pi / 180
+
+        f.cos[p] = math.Cos(angle)
+        f.sin[p] = math.Sin(angle)
+    }
+
+    for pixel := 0; pixel < 512; pixel++ {
+        var y, i, q float64
+
+        for p := 0; p < PHASES; p++ {
+            level := Signal(uint16(pixel), p) / PHASES
+
+            y += level
+            i += level * f.cos[p]
+            q += level * f.sin[p]
+        }
+
+        f.levels[pixel] = y
+        f.palette[pixel] = f.rgb(y, i, q)
+    }
+}
+
+func gammaCorrect(c float64) float64 {
+    if c <= 0 {
+        return 0
+    }
+
+    return math.Pow(c, GAMMA)
+}
+
+func clamp(c float64) byte {
+    switch {
+        case c < 0:
+            return 0
+        case c > 1:
+            return 255
+    }
+
+    return byte(c * 255 + 0.5)
+}
+
+func (f *Filter) rgb(y float64, i float64, q float64) [3]byte {
+    i *= f.Saturation * 2
+    q *= f.Saturation * 2
+
+    r := y + 0.946882 * i + 0.623557 * q
+    g := y - 0.274788 * i - 0.635691 * q
+    b := y - 1.108545 * i + 1.709007 * q
+
+    return [3]byte {
+        clamp(gammaCorrect(r)),
+        clamp(gammaCorrect(g)),
+        clamp(gammaCorrect(b)),
+    }
+}
+
+// Filters a frame of PPU Output entries. phase is the PPU's FramePhase for
+// the frame; as it cycles, so does the dot crawl.
+func (f *Filter) Render(pixels []uint16, phase int) []byte {
+    for y := 0; y < f.Height; y++ {
+        line := pixels[y * INPUT_WIDTH:(y + 1) * INPUT_WIDTH]
+        out := f.Frame[y * f.Width * 3:(y + 1) * f.Width * 3]
+
+        if f.Mode == RGB {
+            f.renderRGB(line, out)
+            continue
+        }
+
+        // Each scanline is 341 dots, which moves the phase along by a third
+        // of a subcarrier cycle. Dot 1 draws the first pixel.
+        dot := (phase + y * 341 + 1) % 3
+        f.renderLine(line, out, (dot * SAMPLES_PER_PIXEL) % PHASES)
+    }
+
+    return f.Frame
+}
+
+func (f *Filter) renderRGB(line []uint16, out []byte) {
+    for x := 0; x < f.Width; x++ {
+        color := f.palette[line[x * INPUT_WIDTH / f.Width] & 0x1ff]
+        copy(out[x * 3:x * 3 + 3], color[:])
+    }
+}
+
+func (f *Filter) renderLine(line []uint16, out []byte, phase int) {
+    const margin = 2 * PHASES
+
+    // The edges of the picture are the backdrop colour, as they would be in
+    // the overscan.
+    for s := range f.composite {
+        x := (s - margin) / SAMPLES_PER_PIXEL
+        switch {
+            case s < margin:
+                x = 0
+            case x >= INPUT_WIDTH:
+                x = INPUT_WIDTH - 1
+        }
+
+        pixel := line[x] & 0x1ff
+        f.composite[s] = Signal(pixel, (phase + s - margin + 4 * PHASES) % PHASES)
+        f.luma[s] = f.levels[pixel]
+    }
+
+    artifacts := f.Artifacts
+    if f.Mode == SVideo {
+        artifacts = 0
+    }
+
+    for x := 0; x < f.Width; x++ {
+        center := margin + (x * SAMPLES + SAMPLES / 2) / f.Width
+
+        // Luma is the signal averaged over one subcarrier cycle, which
+        // cancels out the chroma everywhere but at its edges. A separated
+        // luma signal doesn't have the chroma to begin with.
+        var composite, clean, soft float64
+        for s := center - PHASES / 2; s < center + PHASES / 2; s++ {
+            composite += f.composite[s]
+            clean += f.luma[s]
+        }
+        for s := center - PHASES; s < center + PHASES; s++ {
+            soft += f.luma[s]
+        }
+        composite /= PHASES
+        clean /= PHASES
+        soft /= 2 * PHASES
+
+        y := clean + artifacts * (composite - clean)
+        y += f.Sharpness * (clean - soft)
+
+        // Chroma comes from demodulating against the carrier over two cycles,
+        // so it's blurrier than luma. Composite video demodulates the luma
+        // along with it, so hard luma edges pick up colour.
+        var i, q float64
+        for s := center - PHASES; s < center + PHASES; s++ {
+            chroma := f.composite[s] - f.luma[s] + artifacts * f.luma[s]
+
+            p := (phase + s - margin + 4 * PHASES) % PHASES
+            i += chroma * f.cos[p]
+            q += chroma * f.sin[p]
+        }
+        i /= 2 * PHASES
+        q /= 2 * PHASES
+
+        color := f.rgb(y, i, q)
+        copy(out[x * 3:x * 3 + 3], color[:])
+    }
+}
@@ -0,0 +1,100 @@
+package ntsc
+
+import (
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func flatFrame(pixel uint16) []uint16 {
+    pixels := make([]uint16, INPUT_WIDTH * INPUT_HEIGHT)
+    for i := range pixels {
+        pixels[i] = pixel
+    }
+
+    return pixels
+}
+
+func TestSignalLevels(t *testing.T) {
+    assert.Equal(t, Signal(0x0f, 0), 0.0)
+    assert.Equal(t, Signal(0x20, 0), 1.0)
+    assert.Equal(t, Signal(0x30, 5), 1.0)
+}
+
+func TestSignalEmphasisAttenuates(t *testing.T) {
+    assert.True(t, Signal(0x30 | 0x07 << 6, 0) < Signal(0x30, 0))
+
+    // Except in the black columns
+    assert.Equal(t, Signal(0x0f | 0x07 << 6, 0), Signal(0x0f, 0))
+}
+
+func TestFilterOutputIsWide(t *testing.T) {
+    f := NewFilter(CompositeSettings)
+
+    assert.Equal(t, f.Width, OUTPUT_WIDTH)
+    assert.Equal(t, len(f.Render(flatFrame(0x16), 0)), OUTPUT_WIDTH * INPUT_HEIGHT * 3)
+}
+
+func TestFilterGraysHaveNoColour(t *testing.T) {
+    f := NewFilter(CompositeSettings)
+    frame := f.Render(flatFrame(0x10), 0)
+
+    assert.Equal(t, frame[300 * 3], frame[300 * 3 + 1])
+    assert.Equal(t, frame[300 * 3], frame[300 * 3 + 2])
+}
+
+func TestFilterFlatFieldIsTheSameInEveryPhase(t *testing.T) {
+    f := NewFilter(CompositeSettings)
+    pixels := flatFrame(0x16)
+
+    first := append([]byte {}, f.Render(pixels, 0)...)
+    second := f.Render(pixels, 1)
+
+    center := (100 * OUTPUT_WIDTH + 300) * 3
+    for i := 0; i < 3; i++ {
+        delta := int(first[center + i]) - int(second[center + i])
+        assert.True(t, delta >= -1 && delta <= 1)
+    }
+}
+
+func TestFilterDotCrawl(t *testing.T) {
+    f := NewFilter(CompositeSettings)
+
+    // A vertical edge between white and black picks up colour that moves
+    // with the frame phase.
+    pixels := flatFrame(0x0f)
+    for y := 0; y < INPUT_HEIGHT; y++ {
+        for x := 128; x < INPUT_WIDTH; x++ {
+            pixels[y * INPUT_WIDTH + x] = 0x30
+        }
+    }
+
+    edge := (100 * OUTPUT_WIDTH + OUTPUT_WIDTH / 2) * 3
+    first := append([]byte {}, f.Render(pixels, 0)[edge:edge + 3]...)
+    second := f.Render(pixels, 1)[edge:edge + 3]
+
+    assert.NotEqual(t, first, second)
+}
+
+func TestFilterSVideoHasNoArtifacts(t *testing.T) {
+    f := NewFilter(SVideoSettings)
+
+    pixels := flatFrame(0x0f)
+    for y := 0; y < INPUT_HEIGHT; y++ {
+        for x := 128; x < INPUT_WIDTH; x++ {
+            pixels[y * INPUT_WIDTH + x] = 0x30
+        }
+    }
+
+    edge := (100 * OUTPUT_WIDTH + OUTPUT_WIDTH / 2) * 3
+    first := append([]byte {}, f.Render(pixels, 0)[edge:edge + 3]...)
+    second := f.Render(pixels, 1)[edge:edge + 3]
+
+    assert.Equal(t, first, []byte(second))
+}
+
+func TestFilterRGBUsesThePalette(t *testing.T) {
+    f := NewFilter(RGBSettings)
+    frame := f.Render(flatFrame(0x0f), 0)
+
+    assert.Equal(t, frame[0:3], []byte { 0, 0, 0 })
+}
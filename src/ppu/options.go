@@ -0,0 +1,45 @@
+package ppu
+
+// Switches for debugging graphics. They only change the picture the PPU
+// draws: sprite 0 hit, sprite overflow and every other bit of state a game
+// could see behave as the game set them up.
+type RenderOptions struct {
+    HideBackground bool
+    HideSprites bool
+
+    // A bit for each sprite in OAM which shouldn't be drawn.
+    HiddenSprites uint64
+
+    // Draws sprites in front of the background even when they're flagged to
+    // go behind it.
+    IgnorePriority bool
+
+    // Draws the leftmost eight pixels even when PPUMASK clips them.
+    ShowLeftColumn bool
+}
+
+func (o *RenderOptions) HideSprite(index int, hidden bool) {
+    if hidden {
+        o.HiddenSprites |= 1 << uint(index)
+    } else {
+        o.HiddenSprites &^= 1 << uint(index)
+    }
+}
+
+// The background and sprite pixels at x as the render options would have
+// them drawn.
+func (p *PPU) debugPixels(x int) (byte, byte, int) {
+    clip := !p.ShowLeftColumn
+
+    var bg = byte(0)
+    if !p.HideBackground {
+        bg = p.backgroundPixel(x, clip)
+    }
+
+    var fg, slot = byte(0), -1
+    if !p.HideSprites {
+        fg, slot = p.spritePixel(x, clip, p.HiddenSprites)
+    }
+
+    return bg, fg, slot
+}
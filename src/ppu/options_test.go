@@ -0,0 +1,89 @@
+package ppu
+
+import (
+    "cpu"
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+// A PPU with a solid background of colour $21 and a solid sprite of colour
+// $16 in the top left, with both left columns clipped.
+func layeredPPU() *PPU {
+    p := renderingPPU()
+
+    for i := 0; i < 16; i++ {
+        p.Memory.Write(0xff, cpu.Address(0x10 + i))
+    }
+    for i := 0; i < 0x3c0; i++ {
+        p.Memory.Write(0x01, cpu.Address(0x2000 + i))
+    }
+    p.Memory.Write(0x21, 0x3f03)
+    p.Memory.Write(0x16, 0x3f13)
+    p.Memory.Write(0x0f, 0x3f00)
+
+    p.OAMRAM[0] = 0
+    p.OAMRAM[1] = 0x01
+    p.OAMRAM[3] = 4
+
+    for i := 1; i < 64; i++ {
+        p.OAMRAM[i*4] = 0xff
+    }
+
+    p.Masks.Set(0x18)
+
+    return p
+}
+
+func TestHideBackgroundKeepsSprite0Hit(t *testing.T) {
+    p := layeredPPU()
+    p.HideBackground = true
+
+    stepTo(p, 2, 0)
+
+    assert.True(t, p.Status.Sprite0Hit)
+    assert.Equal(t, p.Output[256 + 20], uint16(0x0f))
+    assert.Equal(t, p.Output[256 + 10], uint16(0x16))
+}
+
+func TestHideSprites(t *testing.T) {
+    p := layeredPPU()
+    p.HideSprites = true
+
+    stepTo(p, 2, 0)
+
+    assert.Equal(t, p.Output[256 + 10], uint16(0x21))
+}
+
+func TestHiddenSpriteRevealsTheOneBehind(t *testing.T) {
+    p := layeredPPU()
+    p.Memory.Write(0x2a, 0x3f17)
+    copy(p.OAMRAM[4:8], []byte { 0x00, 0x01, 0x01, 0x04 })
+
+    p.HideSprite(0, true)
+    stepTo(p, 2, 0)
+
+    assert.Equal(t, p.Output[256 + 10], uint16(0x2a))
+    assert.True(t, p.Status.Sprite0Hit)
+}
+
+func TestIgnorePriority(t *testing.T) {
+    p := layeredPPU()
+    p.OAMRAM[2] = SPRITE_BEHIND_BACKGROUND
+
+    stepTo(p, 2, 0)
+    assert.Equal(t, p.Output[256 + 10], uint16(0x21))
+
+    p.IgnorePriority = true
+    stepTo(p, 3, 0)
+    assert.Equal(t, p.Output[512 + 10], uint16(0x16))
+}
+
+func TestShowLeftColumn(t *testing.T) {
+    p := layeredPPU()
+    p.ShowLeftColumn = true
+
+    stepTo(p, 2, 0)
+
+    assert.Equal(t, p.Output[256 + 2], uint16(0x21))
+    assert.Equal(t, p.Output[256 + 5], uint16(0x16))
+}
@@ -0,0 +1,114 @@
+package ppu
+
+import (
+    "cpu"
+    "math"
+)
+
+// The colours the 2C02 produces for each of its 64 palette entries, as 24 bit
+// RGB values.
+var Palette = [64]uint32 {
+    0x666666, 0x002a88, 0x1412a7, 0x3b00a4, 0x5c007e, 0x6e0040, 0x6c0600, 0x561d00,
+    0x333500, 0x0b4800, 0x005200, 0x004f08, 0x00404d, 0x000000, 0x000000, 0x000000,
+    0xadadad, 0x155fd9, 0x4240ff, 0x7527fe, 0xa01acc, 0xb71e7b, 0xb53120, 0x994e00,
+    0x6b6d00, 0x388700, 0x0c9300, 0x008f32, 0x007c8d, 0x000000, 0x000000, 0x000000,
+    0xfffeff, 0x64b0ff, 0x9290ff, 0xc676ff, 0xf36aff, 0xfe6ecc, 0xfe8170, 0xea9e22,
+    0xbcbe00, 0x88d800, 0x5ce430, 0x45e082, 0x48cdde, 0x4f4f4f, 0x000000, 0x000000,
+    0xfffeff, 0xc0dfff, 0xd3d2ff, 0xe8c8ff, 0xfbc2ff, 0xfec4ea, 0xfeccc5, 0xf7d8a5,
+    0xe4e594, 0xcfef96, 0xbdf4ab, 0xb3f3cc, 0xb5ebf2, 0xb8b8b8, 0x000000, 0x000000,
+}
+
+// The 2C07 generates the same twelve hues as the 2C02, but a PAL decoder sees
+// each of them rotated a little compared to an NTSC one.
+const PAL_HUE_SHIFT = -15.0
+
+var PALPalette = RotateHue(&Palette, PAL_HUE_SHIFT)
+
+// Rotates the hue of every colour in a palette, keeping its luma.
+func RotateHue(palette *[64]uint32, degrees float64) [64]uint32 {
+    var rotated [64]uint32
+
+    sin, cos := math.Sincos(degrees * math.Pi / 180)
+
+    for i, rgb := range palette {
+        r := float64((rgb >> 16) & 0xff)
+        g := float64((rgb >> 8) & 0xff)
+        b := float64(rgb & 0xff)
+
+        y := 0.299 * r + 0.587 * g + 0.114 * b
+        u := 0.492 * (b - y)
+        v := 0.877 * (r - y)
+
+        u, v = u * cos - v * sin, u * sin + v * cos
+
+        r = y + 1.140 * v
+        g = y - 0.395 * u - 0.581 * v
+        b = y + 2.032 * u
+
+        rotated[i] = clamp(r) << 16 | clamp(g) << 8 | clamp(b)
+    }
+
+    return rotated
+}
+
+func clamp(c float64) uint32 {
+    switch {
+        case c < 0:
+            return 0
+        case c > 255:
+            return 255
+    }
+
+    return uint32(c + 0.5)
+}
+
+// Each emphasis bit darkens the two colour channels it doesn't emphasise.
+const EMPHASIS_ATTENUATION = 0.816328
+
+// Builds the colour of every Output entry: each of the 64 palette colours
+// under each of the eight combinations of emphasis bits. PAL and Dendy PPUs
+// swap the red and green emphasis bits.
+func EmphasisColors(palette *[64]uint32, region cpu.Region) []uint32 {
+    colors := make([]uint32, 512)
+
+    for emphasis := 0; emphasis < 8; emphasis++ {
+        var red, green, blue = 1.0, 1.0, 1.0
+
+        redBit, greenBit := 0x01, 0x02
+        if region != cpu.NTSC {
+            redBit, greenBit = 0x02, 0x01
+        }
+
+        if emphasis & redBit != 0 {
+            green *= EMPHASIS_ATTENUATION
+            blue *= EMPHASIS_ATTENUATION
+        }
+        if emphasis & greenBit != 0 {
+            red *= EMPHASIS_ATTENUATION
+            blue *= EMPHASIS_ATTENUATION
+        }
+        if emphasis & 0x04 != 0 {
+            red *= EMPHASIS_ATTENUATION
+            green *= EMPHASIS_ATTENUATION
+        }
+
+        for i := 0; i < 64; i++ {
+            rgb := palette[i]
+
+            // The blacks in columns $e and $f are generated separately, and
+            // aren't affected by emphasis.
+            if i & 0x0e == 0x0e {
+                colors[emphasis << 6 | i] = rgb
+                continue
+            }
+
+            r := uint32(float64((rgb >> 16) & 0xff) * red)
+            g := uint32(float64((rgb >> 8) & 0xff) * green)
+            b := uint32(float64(rgb & 0xff) * blue)
+
+            colors[emphasis << 6 | i] = r << 16 | g << 8 | b
+        }
+    }
+
+    return colors
+}
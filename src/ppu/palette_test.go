@@ -0,0 +1,55 @@
+package ppu
+
+import (
+    "cpu"
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func TestEmphasisColorsWithoutEmphasisMatchPalette(t *testing.T) {
+    colors := EmphasisColors(&Palette, cpu.NTSC)
+
+    for i := 0; i < 64; i++ {
+        assert.Equal(t, colors[i], Palette[i])
+    }
+}
+
+func TestRedEmphasisAttenuatesGreenAndBlue(t *testing.T) {
+    colors := EmphasisColors(&Palette, cpu.NTSC)
+    white := colors[0x01 << 6 | 0x30]
+
+    assert.Equal(t, white >> 16, uint32(0xff))
+    assert.True(t, (white >> 8) & 0xff < 0xfe)
+    assert.True(t, white & 0xff < 0xff)
+}
+
+func TestPALSwapsRedAndGreenEmphasis(t *testing.T) {
+    ntsc := EmphasisColors(&Palette, cpu.NTSC)
+    pal := EmphasisColors(&Palette, cpu.PAL)
+
+    assert.Equal(t, pal[0x01 << 6 | 0x30], ntsc[0x02 << 6 | 0x30])
+    assert.Equal(t, pal[0x02 << 6 | 0x30], ntsc[0x01 << 6 | 0x30])
+    assert.Equal(t, pal[0x04 << 6 | 0x30], ntsc[0x04 << 6 | 0x30])
+}
+
+func TestEmphasisDoesntAffectBlacks(t *testing.T) {
+    colors := EmphasisColors(&Palette, cpu.NTSC)
+
+    assert.Equal(t, colors[0x07 << 6 | 0x0f], Palette[0x0f])
+}
+
+func TestRotatingHueByZeroKeepsPalette(t *testing.T) {
+    rotated := RotateHue(&Palette, 0)
+
+    for i := 0; i < 64; i++ {
+        assert.InDelta(t, float64(rotated[i] >> 16), float64(Palette[i] >> 16), 2)
+        assert.InDelta(t, float64(rotated[i] & 0xff), float64(Palette[i] & 0xff), 2)
+    }
+}
+
+func TestRotatingHueKeepsGrays(t *testing.T) {
+    rotated := RotateHue(&Palette, PAL_HUE_SHIFT)
+
+    assert.Equal(t, rotated[0x30], Palette[0x30])
+    assert.Equal(t, rotated[0x0f], Palette[0x0f])
+}
@@ -27,6 +27,18 @@ func (c *Ctrl) Set(val byte) {
     c.GenerateNMIOnVBlank = (val & 0x80) == 0x80
 }
 
+func (c *Ctrl) Value() byte {
+    var value = byte((c.BaseNametableAddress - 0x2000) / 0x400) & 0x03
+
+    value |= c.VRAMAddressInc << 2
+    if c.SpriteTableAddress == 0x1000 { value |= 0x08 }
+    if c.BackgroundTableAddress == 0x1000 { value |= 0x10 }
+    value |= c.SpriteSize << 5
+    if c.GenerateNMIOnVBlank { value |= 0x80 }
+
+    return value
+}
+
 type Masks struct {
     Grayscale bool
     ShowBackgroundLeft bool
@@ -49,6 +61,30 @@ func (m *Masks) Set(val byte) {
     m.IntenseBlues = (val & 0x80) == 0x80
 }
 
+func (m *Masks) Value() byte {
+    var value = m.Emphasis() << 5
+
+    if m.Grayscale { value |= 0x01 }
+    if m.ShowBackgroundLeft { value |= 0x02 }
+    if m.ShowSpritesLeft { value |= 0x04 }
+    if m.ShowBackground { value |= 0x08 }
+    if m.ShowSprites { value |= 0x10 }
+
+    return value
+}
+
+// The three emphasis bits in the order they're written to PPUMASK. On PAL
+// the first two are green and red respectively rather than red and green.
+func (m *Masks) Emphasis() byte {
+    var value = byte(0x00)
+
+    if m.IntenseReds { value |= 0x01 }
+    if m.IntenseGreens { value |= 0x02 }
+    if m.IntenseBlues { value |= 0x04 }
+
+    return value
+}
+
 type Status struct {
     SpriteOverflow bool
     Sprite0Hit bool
@@ -71,7 +107,12 @@ type PPU struct {
 
     Status
 
+    // VRAMAddr and TempAddr are the "loopy" v and t registers, and FineX the
+    // three bit fine horizontal scroll. During rendering v doubles as the
+    // scroll position, so writes to PPUSCROLL and PPUADDR share them.
     VRAMAddr cpu.Address
+    TempAddr cpu.Address
+    FineX uint8
 
     OAMAddr uint8
     OAMRAM [0x100]byte
@@ -82,6 +123,25 @@ type PPU struct {
     Memory *cpu.Memory
     Display []byte
 
+    // Output holds the same picture as Display, but as the raw palette entry
+    // of each pixel in the low six bits, with the emphasis bits above them.
+    Output []uint16
+
+    // SkipDisplay leaves Display alone, drawing only Output, for frames
+    // nobody's going to see.
+    SkipDisplay bool
+
+    // FramePhase is where the colour subcarrier was at the first dot of the
+    // current frame, as a count of dots modulo three. A dot lasts two thirds
+    // of a subcarrier cycle, so this is all an NTSC filter needs to place
+    // every pixel of the frame on the subcarrier.
+    FramePhase int
+    dotPhase int
+
+    Region cpu.Region
+    FrameTiming
+    colors []uint32
+
     Cycle int
     Frame int
     Scanline int
@@ -89,7 +149,21 @@ type PPU struct {
 
     Bus cpu.Bus
 
+    // BusAddress is the last address the PPU put on its address bus, either
+    // for a rendering fetch or a PPUDATA access. BusWatcher, if set, is called
+    // with every address as it is put on the bus so mappers can watch A12.
+    BusAddress cpu.Address
+    BusWatcher func(cpu.Address)
+
+    // Debugging switches which change what's drawn, but nothing the game can
+    // observe.
+    RenderOptions
+
+    background
+    sprites
+
     vram *VRAM
+    readBuffer byte
     suppressVBlankStarted bool
     suppressNMI bool
 }
@@ -107,14 +181,24 @@ func NewPPU() *PPU {
         upper := cpu.Address((i + 1) * 0x400 - 1)
 
         p.Memory.Mount(nametable, 0x2000 + lower, 0x2000 + upper)
+
+        // $3000-$3eff mirrors the nametables, but the palette sits on top of
+        // the last 256 bytes.
+        if i == 3 {
+            upper -= 0x100
+        }
         p.Memory.Mount(nametable, 0x3000 + lower, 0x3000 + upper)
     }
 
-    p.Memory.Mount(NewVRAM(), 0x3f00, 0x3fff)
+    p.vram = NewVRAM()
+    p.Memory.Mount(p.vram, 0x3f00, 0x3fff)
 
     // 256 pixels per scanline, and 240 scanlines, each pixel with three RGB
     // components
     p.Display = make([]byte, 256 * 3 * 240)
+    p.Output = make([]uint16, 256 * 240)
+
+    p.SetRegion(cpu.NTSC)
 
     p.Frame = 0
     p.Scanline = PRERENDER_SCANLINE
@@ -122,26 +206,113 @@ func NewPPU() *PPU {
     return p
 }
 
+// The reset button clears PPUCTRL, PPUMASK and the scroll, and resets the
+// latch so the next write is a first write, but leaves VRAM, OAM and the
+// status alone.
+func (p *PPU) Reset() {
+    p.Ctrl.Set(0x00)
+    p.Masks.Set(0x00)
+
+    p.TempAddr = 0x0000
+    p.FineX = 0
+    p.AddressLatch = true
+    p.readBuffer = 0x00
+}
+
+// The shape of a frame, which differs between the NTSC 2C02, the PAL 2C07
+// and the Dendy's UA6538. LastScanline is the final scanline before the
+// pre-render scanline, and VBlankScanline the one vblank starts on.
+type FrameTiming struct {
+    LastScanline int
+    VBlankScanline int
+    SkipsOddDot bool
+}
+
+var FrameTimings = map[cpu.Region]FrameTiming {
+    cpu.NTSC:  FrameTiming { VBLANK_SCANLINE, POSTRENDER_SCANLINE + 1, true },
+    cpu.PAL:   FrameTiming { 310, POSTRENDER_SCANLINE + 1, false },
+    cpu.Dendy: FrameTiming { 310, POSTRENDER_SCANLINE + 51, false },
+}
+
+func (p *PPU) SetRegion(region cpu.Region) {
+    p.Region = region
+    p.FrameTiming = FrameTimings[region]
+
+    if region == cpu.NTSC {
+        p.colors = EmphasisColors(&Palette, region)
+    } else {
+        p.colors = EmphasisColors(&PALPalette, region)
+    }
+}
+
 func (p *PPU) WriteVRAMAddr(val byte) {
     if p.AddressLatch {
-        p.VRAMAddr = cpu.Address(val) << 8 | (0x00ff & p.VRAMAddr)
+        // Only 14 bits of the address are writable, and the first write
+        // clears the top bit of the 15 bit register.
+        p.TempAddr = cpu.Address(val & 0x3f) << 8 | (0x00ff & p.TempAddr)
     } else {
-        p.VRAMAddr = cpu.Address(val) | (0xff00 & p.VRAMAddr)
+        p.TempAddr = cpu.Address(val) | (0xff00 & p.TempAddr)
+        p.VRAMAddr = p.TempAddr
+    }
+
+    p.AddressLatch = !p.AddressLatch
+}
+
+func (p *PPU) WriteScroll(val byte) {
+    if p.AddressLatch {
+        p.TempAddr = (p.TempAddr & 0xffe0) | cpu.Address(val >> 3)
+        p.FineX = val & 0x07
+    } else {
+        p.TempAddr = (p.TempAddr & 0x8c1f) |
+            cpu.Address(val & 0x07) << 12 |
+            cpu.Address(val & 0xf8) << 2
     }
 
     p.AddressLatch = !p.AddressLatch
 }
 
 func (p *PPU) VRAMAddrInc() {
+    // While rendering, v is being used for scrolling, and a PPUDATA access
+    // bumps both coarse X and Y instead of incrementing it normally.
+    if p.Rendering() &&
+        p.Scanline >= PRERENDER_SCANLINE &&
+        p.Scanline < VISIBLE_SCANLINES {
+
+        p.incrementX()
+        p.incrementY()
+        return
+    }
+
     if p.Ctrl.VRAMAddressInc == VRAM_INC_ACROSS {
         p.VRAMAddr += 1
     } else {
         p.VRAMAddr += 32
     }
+
+    p.VRAMAddr &= 0x7fff
 }
 
+// The address PPUDATA accesses, which wraps at $3fff.
+func (p *PPU) dataAddr() cpu.Address {
+    return p.VRAMAddr & 0x3fff
+}
+
+// Reads below the palette return the contents of an internal buffer, which
+// is then filled from VRAM, so the first read after setting the address
+// returns stale data. Palette reads come back immediately, but still fill the
+// buffer with the nametable byte "underneath" the palette.
 func (p *PPU) ReadData() byte {
-    value := p.Memory.Read(p.VRAMAddr)
+    location := p.dataAddr()
+    p.setBusAddress(location)
+
+    var value byte
+    if location >= 0x3f00 {
+        value = p.grayscale(p.Memory.Read(location))
+        p.readBuffer = p.Memory.Read(location - 0x1000)
+    } else {
+        value = p.readBuffer
+        p.readBuffer = p.Memory.Read(location)
+    }
 
     p.VRAMAddrInc()
 
@@ -182,37 +353,39 @@ func (p *PPU) CancelNMI() {
 }
 
 func (p *PPU) Step() {
-    if p.Scanline == VBLANK_SCANLINE && p.Cycle == LAST_CYCLE {
+    p.dotPhase = (p.dotPhase + 1) % 3
+
+    if p.Scanline == p.LastScanline && p.Cycle == LAST_CYCLE {
         p.Frame++
         p.Cycle = 0
         p.Scanline = PRERENDER_SCANLINE
     } else {
-        switch {
-            case p.Scanline == FIRST_VISIBLE_SCANLINE &&
-                p.Cycle == 0 &&
-                p.shortPrerender():
-
+        if p.Scanline == FIRST_VISIBLE_SCANLINE && p.Cycle == 0 {
+            // The idle first dot of the first visible scanline is skipped on
+            // odd frames while rendering, so the next dot is processed in its
+            // place.
+            if p.shortPrerender() {
                 p.Cycle++
+            }
+
+            p.FramePhase = (p.dotPhase + 3 - p.Cycle) % 3
+        }
+
+        switch {
             case p.Scanline == PRERENDER_SCANLINE && p.Cycle == 1:
                 p.Status.SpriteOverflow = false
                 p.Status.Sprite0Hit = false
                 p.Status.VBlankStarted = false
-            case p.Scanline > PRERENDER_SCANLINE &&
-                p.Scanline < VISIBLE_SCANLINES &&
-                p.Cycle == LAST_CYCLE:
-
-                if p.Masks.ShowBackground {
-                    p.RenderScanline()
-                }
-                // TODO Do rendering
-            case p.Scanline == POSTRENDER_SCANLINE + 1 && p.Cycle == 1:
+            case p.Scanline == p.VBlankScanline && p.Cycle == 1:
                 if !p.suppressVBlankStarted {
                     p.Status.VBlankStarted = true
                     p.GenerateNMI()
                 }
         }
 
-        if p.Scanline == POSTRENDER_SCANLINE + 1 && p.Cycle >= 3 {
+        p.render()
+
+        if p.Scanline == p.VBlankScanline && p.Cycle >= 3 {
             p.suppressVBlankStarted = false
         }
 
@@ -225,58 +398,33 @@ func (p *PPU) Step() {
     }
 }
 
+func (p *PPU) Rendering() bool {
+    return p.Masks.ShowBackground || p.Masks.ShowSprites
+}
+
 func (p *PPU) shortPrerender() bool {
-    return p.Frame % 2 == 1 &&
-        (p.Masks.ShowBackground || p.Masks.ShowSprites)
+    return p.SkipsOddDot && p.Frame % 2 == 1 && p.Rendering()
 }
 
 func (p *PPU) normalize(location cpu.Address) cpu.Address {
     return location & 0x7
 }
 
-func (p *PPU) CurrentPatterntable() *Patterntable {
-    if p.Ctrl.BackgroundTableAddress == 0x1000 {
-        return p.Patterntables[1]
-    } else {
-        return p.Patterntables[0]
-    }
-}
+func (p *PPU) setBusAddress(location cpu.Address) {
+    p.BusAddress = location
 
-func (p *PPU) DrawPixel(x uint, y uint, color uint8) {
-    offset := (y * 256 + x) * 3
-    switch color {
-        case 3:
-            p.Display[offset] = 0x00
-            p.Display[offset+1] = 0x00
-            p.Display[offset+2] = 0xff
-        case 2:
-            p.Display[offset] = 0xff
-            p.Display[offset+1] = 0x00
-            p.Display[offset+2] = 0x00
-        case 1:
-            p.Display[offset] = 0x00
-            p.Display[offset+1] = 0xff
-            p.Display[offset+2] = 0x00
-        default:
-            p.Display[offset] = 0x00
-            p.Display[offset+1] = 0x00
-            p.Display[offset+2] = 0x00
+    if p.BusWatcher != nil {
+        p.BusWatcher(location)
     }
 }
 
-func (p *PPU) RenderScanline() {
-    for i:=0; i < 32; i++ {
-        tile_index := p.Nametables[0].TileIndex(i, p.Scanline / 8)
-        pattern_table := p.CurrentPatterntable()
-        tile := pattern_table.Tile(uint(tile_index))
-
-        y := uint(p.Scanline % 8)
-        for x:=uint(0); x < 8; x++ {
-            pixel := tile.Pixel(x, y)
+// Every fetch made while rendering goes through here, so that the address
+// shows up on the bus exactly as the real PPU would drive it.
+func (p *PPU) fetch(location cpu.Address) byte {
+    location &= 0x3fff
+    p.setBusAddress(location)
 
-            p.DrawPixel(uint(i) * 8 + x, uint(p.Scanline), pixel)
-        }
-    }
+    return p.Memory.Read(location)
 }
 
 func (p *PPU) Write(val byte, location cpu.Address) {
@@ -284,6 +432,7 @@ func (p *PPU) Write(val byte, location cpu.Address) {
         case PPUCTRL:
             generateAlreadySet := p.Ctrl.GenerateNMIOnVBlank
             p.Ctrl.Set(val)
+            p.TempAddr = (p.TempAddr & 0xf3ff) | cpu.Address(val & 0x03) << 10
 
             if !generateAlreadySet {
                 p.GenerateNMI()
@@ -296,11 +445,12 @@ func (p *PPU) Write(val byte, location cpu.Address) {
             p.OAMRAM[p.OAMAddr] = val
             p.OAMAddr++
         case PPUSCROLL:
-            // TODO
+            p.WriteScroll(val)
         case PPUADDR:
             p.WriteVRAMAddr(val)
         case PPUDATA:
-            p.Memory.Write(val, p.VRAMAddr)
+            p.setBusAddress(p.dataAddr())
+            p.Memory.Write(val, p.dataAddr())
             p.VRAMAddrInc()
     }
 }
@@ -312,7 +462,11 @@ func (p *PPU) ReadDebug(location cpu.Address) byte {
         case OAMDATA:
             return p.OAMRAM[p.OAMAddr]
         case PPUDATA:
-            return p.Memory.ReadDebug(p.VRAMAddr)
+            if p.dataAddr() >= 0x3f00 {
+                return p.Memory.ReadDebug(p.dataAddr())
+            }
+
+            return p.readBuffer
         default:
             return 0
     }
@@ -329,11 +483,11 @@ func (p *PPU) Read(location cpu.Address) byte {
             // it as set, clears it, and suppresses the NMI for that frame.
             //
             // -- http://wiki.nesdev.com/w/index.php/PPU_frame_timing
-            if p.Scanline == POSTRENDER_SCANLINE + 1 && p.Cycle == 1 {
+            if p.Scanline == p.VBlankScanline && p.Cycle == 1 {
                 p.suppressVBlankStarted = true
             }
 
-            if p.Scanline == POSTRENDER_SCANLINE + 1 {
+            if p.Scanline == p.VBlankScanline {
                 if p.Cycle == 2 {
                     p.Status.VBlankStarted = true
                     p.suppressVBlankStarted = true
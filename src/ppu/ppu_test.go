@@ -125,11 +125,21 @@ func TestSetMasks(t *testing.T) {
 func TestWriteVRAMAddr(t *testing.T) {
     p := NewPPU()
 
+    p.AddressLatch = true
+    p.WriteVRAMAddr(0x3e)
+    p.WriteVRAMAddr(0xef)
+
+    assert.Equal(t, p.VRAMAddr, cpu.Address(0x3eef))
+}
+
+func TestWriteVRAMAddrOnlyKeeps14Bits(t *testing.T) {
+    p := NewPPU()
+
     p.AddressLatch = true
     p.WriteVRAMAddr(0xbe)
     p.WriteVRAMAddr(0xef)
 
-    assert.Equal(t, p.VRAMAddr, cpu.Address(0xbeef))
+    assert.Equal(t, p.VRAMAddr, cpu.Address(0x3eef))
 }
 
 func TestPPUWriteCtrl(t *testing.T) {
@@ -173,10 +183,10 @@ func TestPPUWritePPUAddr(t *testing.T) {
     p := NewPPU()
 
     p.AddressLatch = true
-    p.Write(0xbe, PPUADDR)
+    p.Write(0x3e, PPUADDR)
     p.Write(0xef, PPUADDR)
 
-    assert.Equal(t, p.VRAMAddr, cpu.Address(0xbeef))
+    assert.Equal(t, p.VRAMAddr, cpu.Address(0x3eef))
 }
 
 func TestPPUWritePPUData(t *testing.T) {
@@ -237,8 +247,46 @@ func TestPPUReadAt2007ReadsData(t *testing.T) {
 
     p.VRAMAddr = cpu.Address(0x0000)
     p.Memory.Write(0xbe, p.VRAMAddr)
+    p.Memory.Write(0xef, 0x0001)
 
+    // The first read only fills the buffer.
+    assert.Equal(t, p.Read(PPUDATA), byte(0x00))
     assert.Equal(t, p.Read(PPUDATA), byte(0xbe))
+    assert.Equal(t, p.Read(PPUDATA), byte(0xef))
+}
+
+func TestPPUReadAt2007ReadsPaletteImmediately(t *testing.T) {
+    p := NewPPU()
+
+    p.Memory.Write(0x2a, 0x3f01)
+    p.Memory.Write(0xbe, 0x2f01)
+
+    p.VRAMAddr = cpu.Address(0x3f01)
+    assert.Equal(t, p.Read(PPUDATA), byte(0x2a))
+    assert.Equal(t, p.readBuffer, byte(0xbe))
+}
+
+func TestPPUDATAWrapsAt3FFF(t *testing.T) {
+    p := NewPPU()
+    p.Memory.Mount(NewPatterntable(make([]byte, 0x1000)), 0x0000, 0x0fff)
+
+    p.VRAMAddr = cpu.Address(0x3fff)
+    p.Write(0xbe, PPUDATA)
+    p.Write(0xef, PPUDATA)
+
+    assert.Equal(t, p.Memory.Read(0x0000), byte(0xef))
+    assert.Equal(t, p.VRAMAddr, cpu.Address(0x4001))
+}
+
+func TestPPUDATAIncrementsCoarseXAndYWhileRendering(t *testing.T) {
+    p := NewPPU()
+    p.Masks.ShowBackground = true
+    p.Scanline = 10
+
+    p.VRAMAddr = cpu.Address(0x2000)
+    p.Write(0xbe, PPUDATA)
+
+    assert.Equal(t, p.VRAMAddr, cpu.Address(0x3001))
 }
 
 func TestPPUDATAReadIncrementsVRAMAddrCorrectly(t *testing.T) {
@@ -508,3 +556,65 @@ func TestReadingPPUSTATUSOnSameCycleAsVBlank(t *testing.T) {
     assert.Equal(t, p.suppressVBlankStarted, true)
     bus.Mock.AssertCalled(t, "Cancel", cpu.NMI)
 }
+
+func TestPALFramesHave312Scanlines(t *testing.T) {
+    p := NewPPU()
+    p.SetRegion(cpu.PAL)
+    p.Scanline = 260
+    p.Cycle = LAST_CYCLE
+
+    p.Step()
+    assert.Equal(t, p.Scanline, 261)
+
+    p.Scanline = 310
+    p.Cycle = LAST_CYCLE
+
+    p.Step()
+    assert.Equal(t, p.Scanline, PRERENDER_SCANLINE)
+}
+
+func TestPALDoesntShortenOddFrames(t *testing.T) {
+    p := NewPPU()
+    p.SetRegion(cpu.PAL)
+    p.Scanline = FIRST_VISIBLE_SCANLINE
+    p.Cycle = 0
+    p.Frame = 1
+    p.Masks.ShowBackground = true
+
+    p.Step()
+    assert.Equal(t, p.Cycle, 1)
+}
+
+func TestDendyStartsVBlankLate(t *testing.T) {
+    p := NewPPU()
+    p.SetRegion(cpu.Dendy)
+    p.Scanline = POSTRENDER_SCANLINE + 1
+    p.Cycle = 1
+
+    p.Step()
+    assert.False(t, p.Status.VBlankStarted)
+
+    p.Scanline = 291
+    p.Cycle = 1
+
+    p.Step()
+    assert.True(t, p.Status.VBlankStarted)
+}
+
+func TestFramePhaseCyclesThroughThreeFramesWhenNotRendering(t *testing.T) {
+    p := NewPPU()
+    phases := make([]int, 0)
+
+    for i := 0; i < 3; i++ {
+        frame := p.Frame
+        for p.Frame == frame || p.Scanline != 1 {
+            p.Step()
+        }
+        phases = append(phases, p.FramePhase)
+    }
+
+    // 262 * 341 dots is 2 modulo 3, so each frame starts a third of the way
+    // further round.
+    assert.Equal(t, phases[1], (phases[0] + 2) % 3)
+    assert.Equal(t, phases[2], (phases[1] + 2) % 3)
+}
@@ -0,0 +1,444 @@
+package ppu
+
+import "cpu"
+
+// The background pipeline. Every eight dots the PPU fetches a nametable byte,
+// an attribute byte and two pattern bytes, then loads them into the low half
+// of the shift registers, which are shifted once per dot.
+type background struct {
+    nametableByte byte
+    attributeByte byte
+    patternLow byte
+    patternHigh byte
+
+    shiftPatternLow uint16
+    shiftPatternHigh uint16
+    shiftAttributeLow uint16
+    shiftAttributeHigh uint16
+}
+
+type sprite struct {
+    patternLow byte
+    patternHigh byte
+    attributes byte
+    x byte
+    index byte
+}
+
+// The sprites found during evaluation of the previous scanline and the
+// pattern data fetched for them during dots 257-320.
+type sprites struct {
+    spriteCount int
+    evaluated [8]sprite
+
+    activeCount int
+    active [8]sprite
+}
+
+const (
+    SPRITE_PALETTE = 0x03
+    SPRITE_BEHIND_BACKGROUND = 0x20
+    SPRITE_FLIP_HORIZONTAL = 0x40
+    SPRITE_FLIP_VERTICAL = 0x80
+)
+
+func (p *PPU) render() {
+    visible := p.Scanline >= FIRST_VISIBLE_SCANLINE &&
+        p.Scanline < VISIBLE_SCANLINES
+    prerender := p.Scanline == PRERENDER_SCANLINE
+
+    if visible && p.Cycle >= 1 && p.Cycle <= 256 {
+        p.renderPixel()
+    }
+
+    if !p.Rendering() || !(visible || prerender) {
+        return
+    }
+
+    dot := p.Cycle
+
+    if (dot >= 1 && dot <= 256) || (dot >= 321 && dot <= 336) {
+        p.shiftBackground()
+
+        switch dot % 8 {
+            case 1:
+                p.fetchNametableByte()
+            case 3:
+                p.fetchAttributeByte()
+            case 5:
+                p.fetchPatternLow()
+            case 7:
+                p.fetchPatternHigh()
+            case 0:
+                p.loadBackground()
+                p.incrementX()
+        }
+    }
+
+    switch {
+        case dot == 256:
+            p.incrementY()
+        case dot == 257:
+            p.copyX()
+        case dot == 337 || dot == 339:
+            p.fetchNametableByte()
+        case prerender && dot >= 280 && dot <= 304:
+            p.copyY()
+    }
+
+    if dot == 257 {
+        if visible {
+            p.evaluateSprites()
+        } else {
+            p.spriteCount = 0
+        }
+    }
+
+    if dot >= 257 && dot <= 320 {
+        p.OAMAddr = 0
+        p.fetchSprite((dot - 257) / 8, (dot - 257) % 8)
+    }
+}
+
+func (p *PPU) fetchNametableByte() {
+    p.nametableByte = p.fetch(0x2000 | (p.VRAMAddr & 0x0fff))
+}
+
+func (p *PPU) fetchAttributeByte() {
+    v := p.VRAMAddr
+    location := 0x23c0 | (v & 0x0c00) | ((v >> 4) & 0x38) | ((v >> 2) & 0x07)
+
+    shift := ((v >> 4) & 0x04) | (v & 0x02)
+    p.attributeByte = (p.fetch(location) >> shift) & 0x03
+}
+
+func (p *PPU) backgroundPatternAddress() cpu.Address {
+    fineY := (p.VRAMAddr >> 12) & 0x07
+    return cpu.Address(p.Ctrl.BackgroundTableAddress) +
+        cpu.Address(p.nametableByte) * 16 + fineY
+}
+
+func (p *PPU) fetchPatternLow() {
+    p.patternLow = p.fetch(p.backgroundPatternAddress())
+}
+
+func (p *PPU) fetchPatternHigh() {
+    p.patternHigh = p.fetch(p.backgroundPatternAddress() + 8)
+}
+
+func (p *PPU) loadBackground() {
+    b := &p.background
+
+    b.shiftPatternLow = (b.shiftPatternLow & 0xff00) | uint16(b.patternLow)
+    b.shiftPatternHigh = (b.shiftPatternHigh & 0xff00) | uint16(b.patternHigh)
+
+    b.shiftAttributeLow &= 0xff00
+    b.shiftAttributeHigh &= 0xff00
+    if b.attributeByte & 0x01 == 0x01 {
+        b.shiftAttributeLow |= 0x00ff
+    }
+    if b.attributeByte & 0x02 == 0x02 {
+        b.shiftAttributeHigh |= 0x00ff
+    }
+}
+
+func (p *PPU) shiftBackground() {
+    b := &p.background
+
+    b.shiftPatternLow <<= 1
+    b.shiftPatternHigh <<= 1
+    b.shiftAttributeLow <<= 1
+    b.shiftAttributeHigh <<= 1
+}
+
+// Coarse X lives in the low five bits of v; overflowing it switches to the
+// horizontally adjacent nametable.
+func (p *PPU) incrementX() {
+    if p.VRAMAddr & 0x001f == 0x001f {
+        p.VRAMAddr &^= 0x001f
+        p.VRAMAddr ^= 0x0400
+    } else {
+        p.VRAMAddr++
+    }
+}
+
+// Fine Y lives in the top three bits of v and carries into coarse Y, which
+// wraps at row 29 into the vertically adjacent nametable (rows 30 and 31 are
+// the attribute table, and wrap without switching).
+func (p *PPU) incrementY() {
+    if p.VRAMAddr & 0x7000 != 0x7000 {
+        p.VRAMAddr += 0x1000
+        return
+    }
+
+    p.VRAMAddr &^= 0x7000
+
+    y := (p.VRAMAddr & 0x03e0) >> 5
+    switch y {
+        case 29:
+            y = 0
+            p.VRAMAddr ^= 0x0800
+        case 31:
+            y = 0
+        default:
+            y++
+    }
+
+    p.VRAMAddr = (p.VRAMAddr &^ 0x03e0) | (y << 5)
+}
+
+func (p *PPU) copyX() {
+    p.VRAMAddr = (p.VRAMAddr &^ 0x041f) | (p.TempAddr & 0x041f)
+}
+
+func (p *PPU) copyY() {
+    p.VRAMAddr = (p.VRAMAddr &^ 0x7be0) | (p.TempAddr & 0x7be0)
+}
+
+func (p *PPU) spriteHeight() int {
+    if p.Ctrl.SpriteSize == 1 {
+        return 16
+    }
+
+    return 8
+}
+
+// Finds the first eight sprites in OAM which are on the next scanline. The
+// hardware does this over dots 65-256, but nothing can observe the secondary
+// OAM before the sprite fetches start at dot 257, so it's done all at once.
+func (p *PPU) evaluateSprites() {
+    height := p.spriteHeight()
+    count := 0
+
+    for i := 0; i < 64; i++ {
+        y := int(p.OAMRAM[i*4])
+        row := p.Scanline - y
+
+        if row < 0 || row >= height {
+            continue
+        }
+
+        if count == 8 {
+            p.Status.SpriteOverflow = true
+            break
+        }
+
+        p.evaluated[count] = sprite {
+            attributes: p.OAMRAM[i*4+2],
+            x: p.OAMRAM[i*4+3],
+            index: byte(i),
+        }
+        count++
+    }
+
+    p.spriteCount = count
+}
+
+func (p *PPU) spritePatternAddress(slot int) cpu.Address {
+    // Empty slots still fetch, from tile $ff, which matters to mappers
+    // watching A12.
+    var tile = byte(0xff)
+    var row = 0
+    var attributes = byte(0x00)
+
+    if slot < p.spriteCount {
+        s := p.evaluated[slot]
+        i := int(s.index)
+
+        tile = p.OAMRAM[i*4+1]
+        row = p.Scanline - int(p.OAMRAM[i*4])
+        attributes = s.attributes
+    }
+
+    height := p.spriteHeight()
+    if attributes & SPRITE_FLIP_VERTICAL == SPRITE_FLIP_VERTICAL {
+        row = height - 1 - row
+    }
+
+    if height == 16 {
+        table := cpu.Address(tile & 0x01) * 0x1000
+        tile &^= 0x01
+        if row > 7 {
+            tile++
+            row -= 8
+        }
+
+        return table + cpu.Address(tile) * 16 + cpu.Address(row)
+    }
+
+    return cpu.Address(p.Ctrl.SpriteTableAddress) +
+        cpu.Address(tile) * 16 + cpu.Address(row)
+}
+
+func (p *PPU) fetchSprite(slot int, step int) {
+    switch step {
+        case 0, 2:
+            // Garbage nametable fetches
+            p.fetchNametableByte()
+        case 4:
+            p.active[slot] = p.evaluated[slot]
+            p.active[slot].patternLow = p.fetchSpritePattern(slot, 0)
+        case 6:
+            p.active[slot].patternHigh = p.fetchSpritePattern(slot, 8)
+
+            if slot == 7 {
+                p.activeCount = p.spriteCount
+            }
+    }
+}
+
+func (p *PPU) fetchSpritePattern(slot int, plane cpu.Address) byte {
+    value := p.fetch(p.spritePatternAddress(slot) + plane)
+
+    if slot >= p.spriteCount {
+        return 0x00
+    }
+
+    if p.evaluated[slot].attributes & SPRITE_FLIP_HORIZONTAL == SPRITE_FLIP_HORIZONTAL {
+        value = reverse(value)
+    }
+
+    return value
+}
+
+func reverse(b byte) byte {
+    b = (b & 0xf0) >> 4 | (b & 0x0f) << 4
+    b = (b & 0xcc) >> 2 | (b & 0x33) << 2
+    b = (b & 0xaa) >> 1 | (b & 0x55) << 1
+
+    return b
+}
+
+func (p *PPU) backgroundPixel(x int, clip bool) byte {
+    if !p.Masks.ShowBackground || (clip && x < 8 && !p.Masks.ShowBackgroundLeft) {
+        return 0
+    }
+
+    b := &p.background
+    shift := 15 - uint(p.FineX)
+
+    pixel := byte((b.shiftPatternLow >> shift) & 0x01) |
+        byte((b.shiftPatternHigh >> shift) & 0x01) << 1
+
+    if pixel == 0 {
+        return 0
+    }
+
+    palette := byte((b.shiftAttributeLow >> shift) & 0x01) |
+        byte((b.shiftAttributeHigh >> shift) & 0x01) << 1
+
+    return palette << 2 | pixel
+}
+
+// Returns the palette entry of the first opaque sprite at x, along with the
+// slot it was found in. Sprites in hidden are skipped over, as if they were
+// transparent.
+func (p *PPU) spritePixel(x int, clip bool, hidden uint64) (byte, int) {
+    if !p.Masks.ShowSprites || (clip && x < 8 && !p.Masks.ShowSpritesLeft) {
+        return 0, -1
+    }
+
+    for i := 0; i < p.activeCount; i++ {
+        s := &p.active[i]
+        offset := x - int(s.x)
+
+        if offset < 0 || offset > 7 || hidden & (1 << s.index) != 0 {
+            continue
+        }
+
+        shift := uint(7 - offset)
+        pixel := (s.patternLow >> shift) & 0x01 |
+            ((s.patternHigh >> shift) & 0x01) << 1
+
+        if pixel == 0 {
+            continue
+        }
+
+        return 0x10 | (s.attributes & SPRITE_PALETTE) << 2 | pixel, i
+    }
+
+    return 0, -1
+}
+
+func (p *PPU) renderPixel() {
+    x := p.Cycle - 1
+    y := p.Scanline
+
+    var entry byte
+
+    if p.Rendering() {
+        bg := p.backgroundPixel(x, true)
+        fg, slot := p.spritePixel(x, true, 0)
+
+        if bg != 0 && fg != 0 && p.active[slot].index == 0 && x != 255 {
+            p.Status.Sprite0Hit = true
+        }
+
+        if p.RenderOptions != (RenderOptions {}) {
+            bg, fg, slot = p.debugPixels(x)
+        }
+
+        switch {
+            case fg == 0:
+                entry = bg
+            case bg == 0:
+                entry = fg
+            case p.IgnorePriority:
+                entry = fg
+            case p.active[slot].attributes & SPRITE_BEHIND_BACKGROUND == SPRITE_BEHIND_BACKGROUND:
+                entry = bg
+            default:
+                entry = fg
+        }
+    } else if p.VRAMAddr & 0x3f00 == 0x3f00 {
+        // With rendering off, the backdrop is whatever palette entry v points
+        // at, if it points into the palette.
+        entry = byte(p.VRAMAddr & 0x1f)
+    }
+
+    color := p.grayscale(p.Memory.Read(0x3f00 + cpu.Address(entry)) & 0x3f)
+    p.DrawPixel(x, y, uint16(color) | uint16(p.Masks.Emphasis()) << 6)
+}
+
+// In grayscale mode only the brightness column of the palette survives.
+func (p *PPU) grayscale(color byte) byte {
+    if p.Masks.Grayscale {
+        return color & 0x30
+    }
+
+    return color
+}
+
+// Draws a pixel given as an Output entry: a palette colour with emphasis.
+func (p *PPU) DrawPixel(x int, y int, color uint16) {
+    p.Output[y * 256 + x] = color
+    if p.SkipDisplay {
+        return
+    }
+
+    offset := (y * 256 + x) * 3
+    rgb := p.colors[color & 0x1ff]
+
+    p.Display[offset] = byte(rgb >> 16)
+    p.Display[offset+1] = byte(rgb >> 8)
+    p.Display[offset+2] = byte(rgb)
+}
+
+// Where the beam is, for light guns.
+func (p *PPU) Beam() (scanline int, dot int) {
+    return p.Scanline, p.Cycle
+}
+
+// How bright a pixel of Display is, from 0 to 1. While Display's skipped
+// it's worked out from Output instead, so light guns still see the frame.
+func (p *PPU) Brightness(x int, y int) float64 {
+    offset := (y * 256 + x) * 3
+    r, g, b := float64(p.Display[offset]), float64(p.Display[offset+1]), float64(p.Display[offset+2])
+
+    if p.SkipDisplay {
+        rgb := p.colors[p.Output[y * 256 + x] & 0x1ff]
+        r, g, b = float64(byte(rgb >> 16)), float64(byte(rgb >> 8)), float64(byte(rgb))
+    }
+
+    return (0.299 * r + 0.587 * g + 0.114 * b) / 255
+}
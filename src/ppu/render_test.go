@@ -0,0 +1,211 @@
+package ppu
+
+import (
+    "cpu"
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func renderingPPU() *PPU {
+    p := NewPPU()
+    p.Memory.Mount(NewPatterntable(make([]byte, 0x1000)), 0x0000, 0x0fff)
+    p.Memory.Mount(NewPatterntable(make([]byte, 0x1000)), 0x1000, 0x1fff)
+
+    return p
+}
+
+func stepTo(p *PPU, scanline int, cycle int) {
+    for p.Scanline != scanline || p.Cycle != cycle {
+        p.Step()
+    }
+}
+
+func TestPPUWriteScrollSetsTempAddrAndFineX(t *testing.T) {
+    p := NewPPU()
+    p.AddressLatch = true
+
+    p.Write(0x7d, PPUSCROLL)
+    assert.Equal(t, p.TempAddr, cpu.Address(0x000f))
+    assert.Equal(t, p.FineX, uint8(0x05))
+
+    p.Write(0x5e, PPUSCROLL)
+    assert.Equal(t, p.TempAddr, cpu.Address(0x616f))
+}
+
+func TestPPUWriteCtrlSetsTempAddrNametable(t *testing.T) {
+    p := NewPPU()
+
+    p.Write(0x03, PPUCTRL)
+
+    assert.Equal(t, p.TempAddr, cpu.Address(0x0c00))
+}
+
+func TestIncrementXSwitchesNametable(t *testing.T) {
+    p := NewPPU()
+    p.VRAMAddr = 0x001f
+
+    p.incrementX()
+
+    assert.Equal(t, p.VRAMAddr, cpu.Address(0x0400))
+}
+
+func TestIncrementYWrapsAtRow29(t *testing.T) {
+    p := NewPPU()
+    p.VRAMAddr = 0x7000 | (29 << 5)
+
+    p.incrementY()
+
+    assert.Equal(t, p.VRAMAddr, cpu.Address(0x0800))
+}
+
+func TestRenderingDrawsBackgroundTiles(t *testing.T) {
+    p := renderingPPU()
+
+    // Tile 1 is solid colour 1, and the second tile on the screen uses it.
+    for i := 0; i < 8; i++ {
+        p.Memory.Write(0xff, cpu.Address(0x10 + i))
+    }
+    p.Memory.Write(0x01, 0x2001)
+    p.Memory.Write(0x21, 0x3f01)
+    p.Memory.Write(0x0f, 0x3f00)
+
+    p.Masks.Set(0x0a)
+    stepTo(p, 1, 0)
+
+    assert.Equal(t, p.Display[7 * 3], byte(Palette[0x0f] >> 16))
+    assert.Equal(t, p.Display[8 * 3], byte(Palette[0x21] >> 16))
+    assert.Equal(t, p.Display[8 * 3 + 2], byte(Palette[0x21]))
+}
+
+func TestSkippingTheDisplayStillDrawsOutput(t *testing.T) {
+    p := renderingPPU()
+
+    for i := 0; i < 8; i++ {
+        p.Memory.Write(0xff, cpu.Address(0x10 + i))
+    }
+    p.Memory.Write(0x01, 0x2001)
+    p.Memory.Write(0x30, 0x3f01)
+
+    p.Masks.Set(0x0a)
+    p.SkipDisplay = true
+    stepTo(p, 1, 0)
+
+    assert.Equal(t, p.Output[8], uint16(0x30))
+    assert.Equal(t, p.Display[8 * 3], byte(0x00))
+    assert.Equal(t, p.Brightness(8, 0) > 0.9, true)
+    assert.Equal(t, p.Brightness(7, 0) < 0.5, true)
+}
+
+func TestRenderingHonoursFineXScroll(t *testing.T) {
+    p := renderingPPU()
+
+    for i := 0; i < 8; i++ {
+        p.Memory.Write(0xff, cpu.Address(0x10 + i))
+    }
+    p.Memory.Write(0x01, 0x2001)
+    p.Memory.Write(0x21, 0x3f01)
+
+    p.Masks.Set(0x0a)
+    p.AddressLatch = true
+    p.Write(0x03, PPUSCROLL)
+    p.Write(0x00, PPUSCROLL)
+    stepTo(p, 1, 0)
+
+    assert.Equal(t, p.Display[5 * 3 + 2], byte(Palette[0x21]))
+    assert.Equal(t, p.Display[4 * 3 + 2], byte(Palette[0x00]))
+}
+
+func TestSpriteZeroHit(t *testing.T) {
+    p := renderingPPU()
+
+    for i := 0; i < 16; i++ {
+        p.Memory.Write(0xff, cpu.Address(0x10 + i))
+    }
+    for i := 0; i < 0x3c0; i++ {
+        p.Memory.Write(0x01, cpu.Address(0x2000 + i))
+    }
+
+    p.OAMRAM[0] = 10
+    p.OAMRAM[1] = 0x01
+    p.OAMRAM[2] = 0x00
+    p.OAMRAM[3] = 20
+
+    p.Masks.Set(0x1e)
+    stepTo(p, 11, 0)
+    assert.False(t, p.Status.Sprite0Hit)
+
+    stepTo(p, 11, 22)
+    assert.True(t, p.Status.Sprite0Hit)
+}
+
+func TestSpriteOverflow(t *testing.T) {
+    p := renderingPPU()
+
+    for i := 0; i < 9; i++ {
+        p.OAMRAM[i*4] = 0
+    }
+    for i := 9; i < 64; i++ {
+        p.OAMRAM[i*4] = 0xff
+    }
+
+    p.Masks.Set(0x10)
+    stepTo(p, 0, 258)
+
+    assert.True(t, p.Status.SpriteOverflow)
+}
+
+func TestBusWatcherSeesPatternFetches(t *testing.T) {
+    p := renderingPPU()
+    p.Ctrl.Set(0x08)
+    p.Masks.Set(0x18)
+
+    var rises = 0
+    var last = cpu.Address(0x0000)
+    p.BusWatcher = func(location cpu.Address) {
+        if last & 0x1000 == 0 && location & 0x1000 == 0x1000 {
+            rises++
+        }
+        last = location
+    }
+
+    stepTo(p, 0, 0)
+
+    // With background from $0000 and sprites from $1000, A12 rises once for
+    // each of the eight sprite fetches, between the garbage nametable fetches.
+    assert.Equal(t, rises, 8)
+}
+
+func TestRenderingWritesEmphasisToOutput(t *testing.T) {
+    p := renderingPPU()
+    p.Memory.Write(0x21, 0x3f00)
+
+    p.Masks.Set(0xa8)
+    stepTo(p, 1, 0)
+
+    assert.Equal(t, p.Output[0], uint16(0x21 | 0x05 << 6))
+}
+
+func TestGrayscaleMasksPaletteEntries(t *testing.T) {
+    p := renderingPPU()
+    p.Memory.Write(0x21, 0x3f00)
+
+    p.Masks.Set(0x09)
+    stepTo(p, 1, 0)
+
+    assert.Equal(t, p.Output[0], uint16(0x20))
+
+    p.VRAMAddr = 0x3f00
+    assert.Equal(t, p.ReadData(), byte(0x20))
+}
+
+func TestEmphasisIsMidFrameAccurate(t *testing.T) {
+    p := renderingPPU()
+
+    p.Masks.Set(0x08)
+    stepTo(p, 0, 129)
+    p.Write(0x28, PPUMASK)
+    stepTo(p, 1, 0)
+
+    assert.Equal(t, p.Output[127], uint16(0x00))
+    assert.Equal(t, p.Output[128], uint16(0x01 << 6))
+}
@@ -0,0 +1,74 @@
+package ppu
+
+import "state"
+
+const STATE_VERSION = 1
+
+// Saves or loads the registers, where the PPU is in the frame and its
+// rendering pipeline, OAM, the nametables and the palette. The pattern
+// tables belong to the cartridge.
+func (p *PPU) State(s state.Stream) {
+    ctrl, masks := p.Ctrl.Value(), p.Masks.Value()
+    s.Byte(&ctrl)
+    s.Byte(&masks)
+    if s.Loading() {
+        p.Ctrl.Set(ctrl)
+        p.Masks.Set(masks)
+    }
+
+    s.Bool(&p.Status.SpriteOverflow)
+    s.Bool(&p.Status.Sprite0Hit)
+    s.Bool(&p.Status.VBlankStarted)
+
+    s.Uint16((*uint16)(&p.VRAMAddr))
+    s.Uint16((*uint16)(&p.TempAddr))
+    s.Byte(&p.FineX)
+    s.Bool(&p.AddressLatch)
+    s.Byte(&p.readBuffer)
+    s.Uint16((*uint16)(&p.BusAddress))
+
+    s.Byte(&p.OAMAddr)
+    s.Bytes(p.OAMRAM[:])
+
+    s.Int(&p.Cycle)
+    s.Int(&p.Scanline)
+    s.Int(&p.Frame)
+    s.Int(&p.FramePhase)
+    s.Int(&p.dotPhase)
+    s.Bool(&p.suppressVBlankStarted)
+    s.Bool(&p.suppressNMI)
+
+    b := &p.background
+    s.Byte(&b.nametableByte)
+    s.Byte(&b.attributeByte)
+    s.Byte(&b.patternLow)
+    s.Byte(&b.patternHigh)
+    s.Uint16(&b.shiftPatternLow)
+    s.Uint16(&b.shiftPatternHigh)
+    s.Uint16(&b.shiftAttributeLow)
+    s.Uint16(&b.shiftAttributeHigh)
+
+    s.Int(&p.spriteCount)
+    s.Int(&p.activeCount)
+    for i := range p.evaluated {
+        p.evaluated[i].state(s)
+        p.active[i].state(s)
+    }
+
+    for _, nametable := range p.Nametables {
+        s.Bytes(nametable.buffer)
+    }
+    s.Bytes(p.vram.buffer[:0x20])
+}
+
+func (sp *sprite) state(s state.Stream) {
+    s.Byte(&sp.patternLow)
+    s.Byte(&sp.patternHigh)
+    s.Byte(&sp.attributes)
+    s.Byte(&sp.x)
+    s.Byte(&sp.index)
+}
+
+func (p *Patterntable) State(s state.Stream) {
+    s.Bytes(p.buffer)
+}
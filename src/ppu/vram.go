@@ -16,10 +16,16 @@ func NewVRAM() *VRAM {
 
 func (r *VRAM) normalize(location cpu.Address) cpu.Address {
     if location >= 0x0020 && location < 0x0100 {
-        return location & 0x1f
-    } else {
-        return location
+        location &= 0x1f
     }
+
+    // The first entry of each sprite palette is a mirror of the matching
+    // background palette entry.
+    if location & 0x13 == 0x10 {
+        location &^= 0x10
+    }
+
+    return location
 }
 
 func (r *VRAM) Write(value byte, location cpu.Address) {
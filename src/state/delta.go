@@ -0,0 +1,87 @@
+package state
+
+import (
+    "errors"
+    "encoding/binary"
+)
+
+// Encodes data as the XOR of it against base, run length encoded. Savestates
+// taken close together differ in a few places, so the XOR is mostly zeros
+// and shrinks to a small fraction of the state. Where data is longer than
+// base, base is taken to carry on with zeros.
+//
+// The encoding is data's length, then pairs of a run of zeros and a run of
+// literal bytes, each run's length as a uvarint.
+func Delta(base []byte, data []byte) []byte {
+    delta := binary.AppendUvarint(nil, uint64(len(data)))
+
+    xor := func(i int) byte {
+        if i < len(base) {
+            return data[i] ^ base[i]
+        }
+        return data[i]
+    }
+
+    for i := 0; i < len(data); {
+        zeros := i
+        for zeros < len(data) && xor(zeros) == 0 {
+            zeros++
+        }
+
+        // Runs of one or two zeros aren't worth breaking a literal for.
+        literal := zeros
+        for literal < len(data) {
+            if xor(literal) == 0 && (literal + 2 >= len(data) || xor(literal + 1) == 0 && xor(literal + 2) == 0) {
+                break
+            }
+            literal++
+        }
+
+        delta = binary.AppendUvarint(delta, uint64(zeros - i))
+        delta = binary.AppendUvarint(delta, uint64(literal - zeros))
+        for j := zeros; j < literal; j++ {
+            delta = append(delta, xor(j))
+        }
+
+        i = literal
+    }
+
+    return delta
+}
+
+// Undoes Delta, given the same base.
+func ApplyDelta(base []byte, delta []byte) ([]byte, error) {
+    bad := errors.New("bad delta")
+
+    length, n := binary.Uvarint(delta)
+    if n <= 0 {
+        return nil, bad
+    }
+    delta = delta[n:]
+
+    data := make([]byte, length)
+    copy(data, base)
+
+    for i := 0; i < len(data); {
+        zeros, n := binary.Uvarint(delta)
+        if n <= 0 {
+            return nil, bad
+        }
+        delta = delta[n:]
+
+        literal, n := binary.Uvarint(delta)
+        if n <= 0 || uint64(len(delta) - n) < literal || uint64(len(data) - i) < zeros + literal {
+            return nil, bad
+        }
+        delta = delta[n:]
+
+        i += int(zeros)
+        for j := 0; j < int(literal); j++ {
+            data[i] ^= delta[j]
+            i++
+        }
+        delta = delta[literal:]
+    }
+
+    return data, nil
+}
@@ -0,0 +1,41 @@
+package state
+
+import (
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func TestDeltasRoundTrip(t *testing.T) {
+    base := make([]byte, 1000)
+    data := make([]byte, 1000)
+    for i := range base {
+        base[i] = byte(i * 7)
+        data[i] = base[i]
+    }
+    data[10] ^= 0xff
+    data[11] ^= 0x01
+    data[500] = 0
+    data[999] = 42
+
+    delta := Delta(base, data)
+    assert.True(t, len(delta) < 20)
+
+    applied, err := ApplyDelta(base, delta)
+    assert.Equal(t, err, nil)
+    assert.Equal(t, applied, data)
+}
+
+func TestDeltasHandleDifferentLengths(t *testing.T) {
+    base := []byte { 1, 2, 3 }
+
+    for _, data := range [][]byte { { 1, 2, 3, 4, 5 }, { 1 }, {} } {
+        applied, err := ApplyDelta(base, Delta(base, data))
+        assert.Equal(t, err, nil)
+        assert.Equal(t, applied, data)
+    }
+}
+
+func TestBadDeltasAreRefused(t *testing.T) {
+    _, err := ApplyDelta(nil, []byte { 5, 0, 9, 1 })
+    assert.NotEqual(t, err, nil)
+}
@@ -0,0 +1,262 @@
+package state
+
+import (
+    "io"
+    "bytes"
+    "errors"
+    "fmt"
+    "io/ioutil"
+    "encoding/binary"
+)
+
+// Savestates start with this, then the format's version, then a run of
+// chunks. Each chunk has a four letter ID, its own version and its length,
+// so components can change their layout without breaking older states, and
+// chunks a reader doesn't know about can be skipped.
+const (
+    MAGIC = "GONESSAV"
+    VERSION = 1
+)
+
+// Runs over a component's state, either saving or loading it, so the same
+// code describes the layout both ways. Loading stops at the first error,
+// after which everything reads as zero; Err reports it.
+type Stream interface {
+    Loading() bool
+
+    // The version of the chunk being loaded, or the one being saved.
+    Version() int
+
+    Byte(*byte)
+    Bool(*bool)
+    Uint16(*uint16)
+    Int(*int)
+    Uint64(*uint64)
+    String(*string)
+    Bytes([]byte)
+}
+
+type Writer struct {
+    out io.Writer
+    chunk bytes.Buffer
+    version int
+    err error
+}
+
+func NewWriter(out io.Writer) *Writer {
+    w := &Writer { out: out }
+
+    header := make([]byte, len(MAGIC) + 2)
+    copy(header, MAGIC)
+    binary.LittleEndian.PutUint16(header[len(MAGIC):], VERSION)
+    _, w.err = out.Write(header)
+
+    return w
+}
+
+// Writes a chunk with whatever save puts in it.
+func (w *Writer) Chunk(id string, version int, save func(Stream)) {
+    if len(id) != 4 {
+        panic("chunk IDs are four characters")
+    }
+
+    w.chunk.Reset()
+    w.version = version
+    save(w)
+
+    header := make([]byte, 10)
+    copy(header, id)
+    binary.LittleEndian.PutUint16(header[4:], uint16(version))
+    binary.LittleEndian.PutUint32(header[6:], uint32(w.chunk.Len()))
+
+    if w.err == nil {
+        _, w.err = w.out.Write(header)
+    }
+    if w.err == nil {
+        _, w.err = w.out.Write(w.chunk.Bytes())
+    }
+}
+
+func (w *Writer) Err() error {
+    return w.err
+}
+
+func (w *Writer) Loading() bool {
+    return false
+}
+
+func (w *Writer) Version() int {
+    return w.version
+}
+
+func (w *Writer) Byte(v *byte) {
+    w.chunk.WriteByte(*v)
+}
+
+func (w *Writer) Bool(v *bool) {
+    if *v {
+        w.chunk.WriteByte(1)
+    } else {
+        w.chunk.WriteByte(0)
+    }
+}
+
+func (w *Writer) Uint16(v *uint16) {
+    var buffer [2]byte
+    binary.LittleEndian.PutUint16(buffer[:], *v)
+    w.chunk.Write(buffer[:])
+}
+
+func (w *Writer) Int(v *int) {
+    var buffer [8]byte
+    binary.LittleEndian.PutUint64(buffer[:], uint64(int64(*v)))
+    w.chunk.Write(buffer[:])
+}
+
+func (w *Writer) Uint64(v *uint64) {
+    var buffer [8]byte
+    binary.LittleEndian.PutUint64(buffer[:], *v)
+    w.chunk.Write(buffer[:])
+}
+
+func (w *Writer) String(v *string) {
+    length := uint16(len(*v))
+    w.Uint16(&length)
+    w.chunk.WriteString(*v)
+}
+
+func (w *Writer) Bytes(v []byte) {
+    w.chunk.Write(v)
+}
+
+type chunk struct {
+    version int
+    data []byte
+}
+
+type Reader struct {
+    chunks map[string]chunk
+
+    data []byte
+    version int
+    err error
+}
+
+// Reads a whole savestate, ready for its chunks to be loaded.
+func NewReader(in io.Reader) (*Reader, error) {
+    data, err := ioutil.ReadAll(in)
+    if err != nil {
+        return nil, err
+    }
+
+    if len(data) < len(MAGIC) + 2 || string(data[:len(MAGIC)]) != MAGIC {
+        return nil, errors.New("not a savestate")
+    }
+
+    version := binary.LittleEndian.Uint16(data[len(MAGIC):])
+    if version > VERSION {
+        return nil, fmt.Errorf("savestate version %d is newer than this emulator", version)
+    }
+
+    r := &Reader { chunks: make(map[string]chunk) }
+
+    for data = data[len(MAGIC) + 2:]; len(data) > 0; {
+        if len(data) < 10 {
+            return nil, errors.New("savestate is truncated")
+        }
+
+        id := string(data[:4])
+        version := int(binary.LittleEndian.Uint16(data[4:]))
+        length := int(binary.LittleEndian.Uint32(data[6:]))
+        data = data[10:]
+
+        if length > len(data) {
+            return nil, fmt.Errorf("savestate chunk %q is truncated", id)
+        }
+
+        r.chunks[id] = chunk { version, data[:length] }
+        data = data[length:]
+    }
+
+    return r, nil
+}
+
+func (r *Reader) Has(id string) bool {
+    _, ok := r.chunks[id]
+    return ok
+}
+
+// Loads a chunk with load, if the savestate has it; older states may not.
+// Chunks newer than version, which load doesn't know how to read, are an
+// error.
+func (r *Reader) Chunk(id string, version int, load func(Stream)) error {
+    c, ok := r.chunks[id]
+    if !ok {
+        return nil
+    }
+
+    if c.version > version {
+        return fmt.Errorf("savestate chunk %q version %d is newer than this emulator", id, c.version)
+    }
+
+    r.data, r.version, r.err = c.data, c.version, nil
+    load(r)
+
+    if r.err != nil {
+        return fmt.Errorf("savestate chunk %q: %s", id, r.err)
+    }
+
+    return nil
+}
+
+func (r *Reader) Loading() bool {
+    return true
+}
+
+func (r *Reader) Version() int {
+    return r.version
+}
+
+func (r *Reader) next(n int) []byte {
+    if r.err == nil && len(r.data) < n {
+        r.err = errors.New("too short")
+    }
+    if r.err != nil {
+        return make([]byte, n)
+    }
+
+    value := r.data[:n]
+    r.data = r.data[n:]
+
+    return value
+}
+
+func (r *Reader) Byte(v *byte) {
+    *v = r.next(1)[0]
+}
+
+func (r *Reader) Bool(v *bool) {
+    *v = r.next(1)[0] != 0
+}
+
+func (r *Reader) Uint16(v *uint16) {
+    *v = binary.LittleEndian.Uint16(r.next(2))
+}
+
+func (r *Reader) Int(v *int) {
+    *v = int(int64(binary.LittleEndian.Uint64(r.next(8))))
+}
+
+func (r *Reader) Uint64(v *uint64) {
+    *v = binary.LittleEndian.Uint64(r.next(8))
+}
+
+func (r *Reader) String(v *string) {
+    var length uint16
+    r.Uint16(&length)
+    *v = string(r.next(int(length)))
+}
+
+func (r *Reader) Bytes(v []byte) {
+    copy(v, r.next(len(v)))
+}
@@ -0,0 +1,96 @@
+package state
+
+import (
+    "bytes"
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+type thing struct {
+    a byte
+    b bool
+    c uint16
+    d int
+    e []byte
+    f string
+}
+
+func (t *thing) state(s Stream) {
+    s.Byte(&t.a)
+    s.Bool(&t.b)
+    s.Uint16(&t.c)
+    s.Int(&t.d)
+    s.Bytes(t.e)
+    s.String(&t.f)
+}
+
+func TestChunksRoundTrip(t *testing.T) {
+    saved := &thing { 0x12, true, 0x3456, -7, []byte { 1, 2, 3 }, "name" }
+
+    var out bytes.Buffer
+    w := NewWriter(&out)
+    w.Chunk("THNG", 1, saved.state)
+    w.Chunk("SKIP", 1, saved.state)
+    assert.Equal(t, w.Err(), nil)
+
+    r, err := NewReader(&out)
+    assert.Equal(t, err, nil)
+
+    loaded := &thing { e: make([]byte, 3) }
+    assert.Equal(t, r.Chunk("THNG", 1, loaded.state), nil)
+    assert.Equal(t, loaded, saved)
+}
+
+func TestMissingChunksAreSkipped(t *testing.T) {
+    var out bytes.Buffer
+    NewWriter(&out)
+
+    r, err := NewReader(&out)
+    assert.Equal(t, err, nil)
+
+    loaded := &thing { a: 5 }
+    assert.Equal(t, r.Chunk("THNG", 1, loaded.state), nil)
+    assert.Equal(t, loaded.a, byte(5))
+}
+
+func TestOlderChunksLoadByVersion(t *testing.T) {
+    var out bytes.Buffer
+    w := NewWriter(&out)
+    w.Chunk("THNG", 1, func(s Stream) {
+        var a byte = 9
+        s.Byte(&a)
+    })
+
+    r, _ := NewReader(&out)
+
+    loaded := new(thing)
+    err := r.Chunk("THNG", 2, func(s Stream) {
+        s.Byte(&loaded.a)
+        if s.Version() >= 2 {
+            s.Int(&loaded.d)
+        }
+    })
+
+    assert.Equal(t, err, nil)
+    assert.Equal(t, loaded.a, byte(9))
+}
+
+func TestBadStatesAreRefused(t *testing.T) {
+    _, err := NewReader(bytes.NewReader([]byte("NOTASAVESTATE")))
+    assert.NotEqual(t, err, nil)
+
+    var out bytes.Buffer
+    w := NewWriter(&out)
+    w.Chunk("THNG", 3, func(s Stream) {})
+
+    r, _ := NewReader(&out)
+    assert.NotEqual(t, r.Chunk("THNG", 2, func(s Stream) {}), nil)
+
+    out.Reset()
+    w = NewWriter(&out)
+    w.Chunk("THNG", 1, func(s Stream) {})
+    r, _ = NewReader(&out)
+
+    var a byte
+    assert.NotEqual(t, r.Chunk("THNG", 1, func(s Stream) { s.Byte(&a) }), nil)
+}
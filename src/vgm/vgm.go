@@ -0,0 +1,206 @@
+package vgm
+
+import (
+    "io"
+    "cpu"
+    "bufio"
+    "encoding/binary"
+)
+
+// VGM files are a log of writes to sound chips' registers, with waits
+// between them counted in samples at 44.1kHz.
+//
+// See -- http://vgmrips.net/wiki/VGM_Specification
+const (
+    VERSION = 0x171
+    HEADER_SIZE = 0x100
+    SAMPLE_RATE = 44100
+)
+
+// Commands
+const (
+    WAIT = 0x61
+    WAIT_NTSC = 0x62
+    WAIT_PAL = 0x63
+    WAIT_SHORT = 0x70
+    END = 0x66
+    DATA_BLOCK = 0x67
+    NES_APU = 0xb4
+
+    // The data block type for the NES APU's RAM, which holds DMC samples.
+    NES_APU_RAM = 0xc2
+)
+
+// Logs writes to the 2A03's APU registers. Expansion audio on cartridges
+// isn't logged: VGM only has the FDS's besides, which isn't emulated. The
+// DMC's samples are copied into
+// the log from memory whenever a sample starts which the log doesn't have
+// yet.
+type Writer struct {
+    // The CPU's clock rate.
+    Clock float64
+
+    // The first error writing the log, after which nothing more is written.
+    Err error
+
+    w io.WriteSeeker
+    out *bufio.Writer
+    size int
+
+    cycles func() int
+    memory func(cpu.Address) byte
+
+    start int
+    samples int
+
+    // The last values written to $4012 and $4013, and the samples already
+    // in the log.
+    sampleAddress byte
+    sampleLength byte
+    sent [0x4000]bool
+    ram [0x4000]byte
+}
+
+// cycles gives the number of CPU cycles run so far, and memory reads CPU
+// memory without side effects.
+func NewWriter(w io.WriteSeeker, clock float64, cycles func() int, memory func(cpu.Address) byte) *Writer {
+    v := new(Writer)
+
+    v.Clock = clock
+    v.w = w
+    v.out = bufio.NewWriter(w)
+    v.cycles = cycles
+    v.memory = memory
+    v.start = cycles()
+
+    v.emit(make([]byte, HEADER_SIZE)...)
+
+    return v
+}
+
+func (v *Writer) emit(data ...byte) {
+    if v.Err != nil {
+        return
+    }
+
+    _, v.Err = v.out.Write(data)
+    v.size += len(data)
+}
+
+// Waits until the current cycle.
+func (v *Writer) sync() {
+    target := int(float64(v.cycles() - v.start) * SAMPLE_RATE / v.Clock)
+
+    for wait := target - v.samples; wait > 0; wait = target - v.samples {
+        switch {
+            case wait <= 16:
+                v.emit(WAIT_SHORT + byte(wait - 1))
+            case wait == 735:
+                v.emit(WAIT_NTSC)
+            case wait == 882:
+                v.emit(WAIT_PAL)
+            default:
+                if wait > 0xffff {
+                    wait = 0xffff
+                }
+                v.emit(WAIT, byte(wait), byte(wait >> 8))
+        }
+
+        v.samples += wait
+    }
+}
+
+// Logs a write to a register, given by its full address. Anything outside
+// the APU's $4000-$401F is dropped.
+func (v *Writer) Write(val byte, location cpu.Address) {
+    if location < 0x4000 || location > 0x401f {
+        return
+    }
+    reg := byte(location - 0x4000)
+
+    v.sync()
+
+    switch location {
+        case 0x4012:
+            v.sampleAddress = val
+        case 0x4013:
+            v.sampleLength = val
+        case 0x4015:
+            if val & 0x10 == 0x10 {
+                v.sendSample()
+            }
+    }
+
+    v.emit(NES_APU, reg, val)
+}
+
+// Copies the DMC's current sample into the log if it's new or has changed,
+// e.g. after a bank switch.
+func (v *Writer) sendSample() {
+    start := 0xc000 + int(v.sampleAddress) * 64
+    end := start + int(v.sampleLength) * 16 + 1
+    if end > 0x10000 {
+        end = 0x10000
+    }
+
+    changed := false
+    for location := start; location < end; location++ {
+        value := v.memory(cpu.Address(location))
+
+        if !v.sent[location - 0xc000] || v.ram[location - 0xc000] != value {
+            changed = true
+        }
+
+        v.sent[location - 0xc000] = true
+        v.ram[location - 0xc000] = value
+    }
+
+    if changed {
+        v.DataBlock(cpu.Address(start), v.ram[start - 0xc000:end - 0xc000])
+    }
+}
+
+// Writes data into the APU's RAM, where the DMC's samples are played from.
+func (v *Writer) DataBlock(location cpu.Address, data []byte) {
+    size := make([]byte, 4)
+    binary.LittleEndian.PutUint32(size, uint32(len(data) + 2))
+
+    v.emit(DATA_BLOCK, END, NES_APU_RAM)
+    v.emit(size...)
+    v.emit(byte(location), byte(location >> 8))
+    v.emit(data...)
+}
+
+// Ends the log at the current cycle and fills in the header.
+func (v *Writer) Close() error {
+    v.sync()
+    v.emit(END)
+
+    if v.Err != nil {
+        return v.Err
+    }
+    if v.Err = v.out.Flush(); v.Err != nil {
+        return v.Err
+    }
+
+    header := make([]byte, HEADER_SIZE)
+    copy(header, "Vgm ")
+    binary.LittleEndian.PutUint32(header[0x04:], uint32(v.size - 0x04))
+    binary.LittleEndian.PutUint32(header[0x08:], VERSION)
+    binary.LittleEndian.PutUint32(header[0x18:], uint32(v.samples))
+    binary.LittleEndian.PutUint32(header[0x34:], HEADER_SIZE - 0x34)
+    binary.LittleEndian.PutUint32(header[0x84:], uint32(v.Clock + 0.5))
+
+    if _, v.Err = v.w.Seek(0, io.SeekStart); v.Err != nil {
+        return v.Err
+    }
+    if _, v.Err = v.w.Write(header); v.Err != nil {
+        return v.Err
+    }
+
+    if closer, ok := v.w.(io.Closer); ok {
+        v.Err = closer.Close()
+    }
+
+    return v.Err
+}
@@ -0,0 +1,107 @@
+package vgm
+
+import (
+    "os"
+    "cpu"
+    "bytes"
+    "testing"
+    "io/ioutil"
+    "encoding/binary"
+    "github.com/stretchrcom/testify/assert"
+)
+
+type clock struct {
+    cycles int
+    memory [0x10000]byte
+}
+
+func (c *clock) Cycles() int {
+    return c.cycles
+}
+
+func (c *clock) Read(location cpu.Address) byte {
+    return c.memory[location]
+}
+
+// Records a log and returns its commands.
+func record(t *testing.T, run func(*Writer, *clock)) ([]byte, []byte) {
+    file, err := ioutil.TempFile("", "gones-vgm")
+    assert.Nil(t, err)
+    defer os.Remove(file.Name())
+
+    c := new(clock)
+    c.cycles = 1000
+
+    v := NewWriter(file, 44100, c.Cycles, c.Read)
+    run(v, c)
+    assert.Nil(t, v.Close())
+
+    data, _ := ioutil.ReadFile(file.Name())
+
+    return data[:HEADER_SIZE], data[HEADER_SIZE:]
+}
+
+func TestWriterLogsWritesWithWaits(t *testing.T) {
+    header, commands := record(t, func(v *Writer, c *clock) {
+        v.Write(0xbf, 0x4000)
+        c.cycles += 10
+        v.Write(0xfd, 0x4002)
+        c.cycles += 735
+        v.Write(0x08, 0x4003)
+        c.cycles += 1000
+    })
+
+    assert.Equal(t, commands, []byte {
+        NES_APU, 0x00, 0xbf,
+        WAIT_SHORT + 9,
+        NES_APU, 0x02, 0xfd,
+        WAIT_NTSC,
+        NES_APU, 0x03, 0x08,
+        WAIT, 0xe8, 0x03,
+        END,
+    })
+
+    assert.Equal(t, string(header[:4]), "Vgm ")
+    assert.Equal(t, binary.LittleEndian.Uint32(header[0x04:]), uint32(HEADER_SIZE + len(commands) - 4))
+    assert.Equal(t, binary.LittleEndian.Uint32(header[0x08:]), uint32(VERSION))
+    assert.Equal(t, binary.LittleEndian.Uint32(header[0x18:]), uint32(1745))
+    assert.Equal(t, binary.LittleEndian.Uint32(header[0x34:]), uint32(HEADER_SIZE - 0x34))
+    assert.Equal(t, binary.LittleEndian.Uint32(header[0x84:]), uint32(44100))
+}
+
+func TestWriterDropsWritesOutsideTheAPU(t *testing.T) {
+    _, commands := record(t, func(v *Writer, c *clock) {
+        v.Write(0x01, 0x4080)
+        v.Write(0x02, 0x4023)
+        v.Write(0x03, 0x9000)
+        v.Write(0x40, 0x4017)
+    })
+
+    assert.Equal(t, commands, []byte { NES_APU, 0x17, 0x40, END })
+}
+
+func TestWriterSendsDMCSamplesOnce(t *testing.T) {
+    _, commands := record(t, func(v *Writer, c *clock) {
+        c.memory[0xc040] = 0xaa
+        v.Write(0x01, 0x4012)
+        v.Write(0x00, 0x4013)
+
+        v.Write(0x10, 0x4015)
+        v.Write(0x10, 0x4015)
+
+        c.memory[0xc040] = 0xbb
+        v.Write(0x10, 0x4015)
+    })
+
+    block := func(value byte) []byte {
+        return []byte { DATA_BLOCK, END, NES_APU_RAM, 3, 0, 0, 0, 0x40, 0xc0, value }
+    }
+
+    expected := []byte { NES_APU, 0x12, 0x01, NES_APU, 0x13, 0x00 }
+    expected = append(expected, block(0xaa)...)
+    expected = append(expected, NES_APU, 0x15, 0x10, NES_APU, 0x15, 0x10)
+    expected = append(expected, block(0xbb)...)
+    expected = append(expected, NES_APU, 0x15, 0x10, END)
+
+    assert.True(t, bytes.Equal(commands, expected))
+}
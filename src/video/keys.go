@@ -0,0 +1,62 @@
+package video
+
+import "github.com/go-gl/glfw"
+
+// Names for glfw's special keys. Printable keys are named by their
+// character.
+var KeyNames = map[int]string {
+    glfw.KeySpace:     "Space",
+    glfw.KeyEsc:       "Esc",
+    glfw.KeyF1:        "F1",
+    glfw.KeyF2:        "F2",
+    glfw.KeyF3:        "F3",
+    glfw.KeyF4:        "F4",
+    glfw.KeyF5:        "F5",
+    glfw.KeyF6:        "F6",
+    glfw.KeyF7:        "F7",
+    glfw.KeyF8:        "F8",
+    glfw.KeyF9:        "F9",
+    glfw.KeyF10:       "F10",
+    glfw.KeyF11:       "F11",
+    glfw.KeyF12:       "F12",
+    glfw.KeyUp:        "Up",
+    glfw.KeyDown:      "Down",
+    glfw.KeyLeft:      "Left",
+    glfw.KeyRight:     "Right",
+    glfw.KeyLshift:    "LShift",
+    glfw.KeyRshift:    "RShift",
+    glfw.KeyLctrl:     "LCtrl",
+    glfw.KeyRctrl:     "RCtrl",
+    glfw.KeyLalt:      "LAlt",
+    glfw.KeyRalt:      "RAlt",
+    glfw.KeyTab:       "Tab",
+    glfw.KeyEnter:     "Enter",
+    glfw.KeyBackspace: "Backspace",
+    glfw.KeyInsert:    "Insert",
+    glfw.KeyDel:       "Delete",
+    glfw.KeyPageup:    "PageUp",
+    glfw.KeyPagedown:  "PageDown",
+    glfw.KeyHome:      "Home",
+    glfw.KeyEnd:       "End",
+}
+
+func KeyName(key int) string {
+    if name, ok := KeyNames[key]; ok {
+        return name
+    }
+
+    if key > glfw.KeySpace && key < glfw.KeySpecial {
+        return string(rune(key))
+    }
+
+    return ""
+}
+
+func (v *Video) key(key, state int) {
+    name := KeyName(key)
+    if v.OnKey == nil || name == "" {
+        return
+    }
+
+    v.OnKey(name, state == glfw.Press)
+}
@@ -0,0 +1,28 @@
+package video
+
+import "github.com/go-gl/glfw"
+
+func (v *Video) mousePos(x, y int) {
+    v.mouseX, v.mouseY = x, y
+    v.mouse()
+}
+
+func (v *Video) mouseButton(button, state int) {
+    switch button {
+        case glfw.MouseLeft:
+            v.left = state == glfw.Press
+        case glfw.MouseRight:
+            v.right = state == glfw.Press
+    }
+
+    v.mouse()
+}
+
+func (v *Video) mouse() {
+    w, h := glfw.WindowSize()
+    if v.OnMouse == nil || w == 0 || h == 0 {
+        return
+    }
+
+    v.OnMouse(float64(v.mouseX) / float64(w), float64(v.mouseY) / float64(h), v.left, v.right)
+}
@@ -21,6 +21,16 @@ type Video struct {
 
     Frames chan *Frame
     frame *Frame
+
+    // Called with each key's name as it's pressed or released.
+    OnKey func(name string, pressed bool)
+
+    // Called whenever the mouse moves or a button changes, with where it is
+    // as a fraction of the window's size.
+    OnMouse func(x float64, y float64, left bool, right bool)
+
+    mouseX, mouseY int
+    left, right bool
 }
 
 func NewVideo() *Video {
@@ -96,6 +106,9 @@ func (v *Video) Init(w int, h int) {
     }
 
     glfw.SetWindowSizeCallback(resize)
+    glfw.SetKeyCallback(v.key)
+    glfw.SetMousePosCallback(v.mousePos)
+    glfw.SetMouseButtonCallback(v.mouseButton)
 
     gl.Enable(gl.TEXTURE_2D)
 
@@ -0,0 +1,70 @@
+package viewer
+
+import (
+    "nes"
+    "ppu"
+    "image"
+    "image/color"
+)
+
+// The colours of events on the event map. PPU register accesses are coloured
+// by register, $2000-$2007.
+var RegisterColors = [8]color.RGBA {
+    { 0xff, 0x40, 0x40, 0xff },
+    { 0x40, 0xff, 0x40, 0xff },
+    { 0xff, 0xff, 0x40, 0xff },
+    { 0x80, 0x80, 0x80, 0xff },
+    { 0xc0, 0x80, 0x40, 0xff },
+    { 0x40, 0xc0, 0xff, 0xff },
+    { 0xff, 0x80, 0xff, 0xff },
+    { 0x40, 0x40, 0xff, 0xff },
+}
+
+var EventColors = map[nes.EventKind]color.RGBA {
+    nes.OAMDMAWrite: { 0xff, 0xa0, 0x00, 0xff },
+    nes.MapperWrite: { 0x00, 0xff, 0xc0, 0xff },
+    nes.NMIEvent:    { 0xff, 0xff, 0xff, 0xff },
+    nes.IRQEvent:    { 0xff, 0x00, 0x80, 0xff },
+}
+
+var (
+    visibleColor = color.RGBA { 0x30, 0x30, 0x30, 0xff }
+    blankColor = color.RGBA { 0x10, 0x10, 0x10, 0xff }
+)
+
+func EventColor(e nes.Event) color.RGBA {
+    if e.Kind == nes.PPURegisterRead || e.Kind == nes.PPURegisterWrite {
+        return RegisterColors[e.Address & 0x07]
+    }
+
+    return EventColors[e.Kind]
+}
+
+// Plots events on a grid of every dot of every scanline in a frame, 341 dots
+// wide, with the pre-render scanline at the bottom. The visible part of the
+// frame is lighter than the blanking around it.
+func EventMap(p *ppu.PPU, events []nes.Event) *image.RGBA {
+    scanlines := p.LastScanline + 2
+    img := image.NewRGBA(image.Rect(0, 0, ppu.LAST_CYCLE + 1, scanlines))
+
+    for y := 0; y < scanlines; y++ {
+        for x := 0; x <= ppu.LAST_CYCLE; x++ {
+            if y < ppu.VISIBLE_SCANLINES && x >= 1 && x <= 256 {
+                img.SetRGBA(x, y, visibleColor)
+            } else {
+                img.SetRGBA(x, y, blankColor)
+            }
+        }
+    }
+
+    for _, e := range events {
+        y := e.Scanline
+        if y == ppu.PRERENDER_SCANLINE {
+            y = scanlines - 1
+        }
+
+        img.SetRGBA(e.Dot, y, EventColor(e))
+    }
+
+    return img
+}
@@ -0,0 +1,21 @@
+package viewer
+
+import (
+    "nes"
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func TestEventMapPlotsEvents(t *testing.T) {
+    p := testPPU()
+
+    img := EventMap(p, []nes.Event {
+        { Kind: nes.PPURegisterWrite, Address: 0x2005, Scanline: 100, Dot: 200 },
+        { Kind: nes.MapperWrite, Address: 0x8000, Scanline: -1, Dot: 3 },
+    })
+
+    assert.Equal(t, img.Bounds().Dx(), 341)
+    assert.Equal(t, img.Bounds().Dy(), 262)
+    assert.Equal(t, img.RGBAAt(200, 100), RegisterColors[5])
+    assert.Equal(t, img.RGBAAt(3, 261), EventColors[nes.MapperWrite])
+}
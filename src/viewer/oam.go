@@ -0,0 +1,156 @@
+package viewer
+
+import (
+    "cpu"
+    "ppu"
+    "fmt"
+    "image"
+    "image/color"
+)
+
+// A decoded OAM entry.
+type Sprite struct {
+    Index int
+    X int
+    Y int
+    Tile byte
+    Palette byte
+    BehindBackground bool
+    FlipHorizontal bool
+    FlipVertical bool
+}
+
+func (s Sprite) String() string {
+    var flags = ""
+    if s.BehindBackground { flags += " behind" }
+    if s.FlipHorizontal { flags += " hflip" }
+    if s.FlipVertical { flags += " vflip" }
+
+    return fmt.Sprintf("#%02d x=%3d y=%3d tile=$%02x palette=%d%s",
+        s.Index, s.X, s.Y, s.Tile, s.Palette, flags)
+}
+
+// All 64 sprites in OAM. Y is the scanline the sprite is drawn from, which
+// is one more than the value in OAM.
+func OAM(p *ppu.PPU) []Sprite {
+    sprites := make([]Sprite, 64)
+
+    for i := range sprites {
+        attributes := p.OAMRAM[i*4+2]
+
+        sprites[i] = Sprite {
+            Index: i,
+            X: int(p.OAMRAM[i*4+3]),
+            Y: int(p.OAMRAM[i*4]) + 1,
+            Tile: p.OAMRAM[i*4+1],
+            Palette: attributes & ppu.SPRITE_PALETTE,
+            BehindBackground: attributes & ppu.SPRITE_BEHIND_BACKGROUND != 0,
+            FlipHorizontal: attributes & ppu.SPRITE_FLIP_HORIZONTAL != 0,
+            FlipVertical: attributes & ppu.SPRITE_FLIP_VERTICAL != 0,
+        }
+    }
+
+    return sprites
+}
+
+// Draws a sprite with its flips and palette, transparent pixels left alone.
+func drawSprite(img *image.RGBA, p *ppu.PPU, s Sprite, left int, top int) {
+    height := 8
+    if p.Ctrl.SpriteSize == 1 {
+        height = 16
+    }
+
+    for y := 0; y < height; y++ {
+        row := y
+        if s.FlipVertical {
+            row = height - 1 - y
+        }
+
+        table := cpu.Address(p.Ctrl.SpriteTableAddress)
+        tile := s.Tile
+        if height == 16 {
+            table = cpu.Address(tile & 0x01) * 0x1000
+            tile &^= 0x01
+            if row > 7 {
+                tile++
+                row -= 8
+            }
+        }
+
+        for x := 0; x < 8; x++ {
+            col := x
+            if s.FlipHorizontal {
+                col = 7 - x
+            }
+
+            pixel := tilePixel(p, table, tile, col, row)
+            if pixel == 0 {
+                continue
+            }
+
+            img.SetRGBA(left + x, top + y, PaletteColor(p, 0x10 | s.Palette << 2 | pixel))
+        }
+    }
+}
+
+// Every sprite in OAM order, in an 8x8 grid over the backdrop colour.
+func Sprites(p *ppu.PPU) *image.RGBA {
+    img := image.NewRGBA(image.Rect(0, 0, SPRITES_WIDTH, SPRITES_HEIGHT))
+    backdrop := PaletteColor(p, 0x00)
+
+    for y := 0; y < SPRITES_HEIGHT; y++ {
+        for x := 0; x < SPRITES_WIDTH; x++ {
+            img.SetRGBA(x, y, backdrop)
+        }
+    }
+
+    for _, s := range OAM(p) {
+        drawSprite(img, p, s, (s.Index % 8) * SPRITE_CELL_WIDTH, (s.Index / 8) * SPRITE_CELL_HEIGHT)
+    }
+
+    return img
+}
+
+// The sprites drawn where they'd appear on screen, each outlined, so that
+// offscreen and overlapping sprites are easy to spot. Sprites earlier in OAM
+// are drawn on top.
+func SpriteLayout(p *ppu.PPU) *image.RGBA {
+    img := image.NewRGBA(image.Rect(0, 0, 256, 240))
+    backdrop := PaletteColor(p, 0x00)
+
+    for y := 0; y < 240; y++ {
+        for x := 0; x < 256; x++ {
+            img.SetRGBA(x, y, backdrop)
+        }
+    }
+
+    height := 8
+    if p.Ctrl.SpriteSize == 1 {
+        height = 16
+    }
+
+    sprites := OAM(p)
+    for i := len(sprites) - 1; i >= 0; i-- {
+        s := sprites[i]
+        if s.Y >= 240 {
+            continue
+        }
+
+        drawSprite(img, p, s, s.X, s.Y)
+        box(img, s.X, s.Y, 8, height, ViewportColor)
+    }
+
+    return img
+}
+
+// Draws a rectangle outline, clipped to the image.
+func box(img *image.RGBA, left int, top int, width int, height int, c color.RGBA) {
+    for i := 0; i < width; i++ {
+        img.SetRGBA(left + i, top, c)
+        img.SetRGBA(left + i, top + height - 1, c)
+    }
+    for i := 0; i < height; i++ {
+        img.SetRGBA(left, top + i, c)
+        img.SetRGBA(left + width - 1, top + i, c)
+    }
+}
@@ -0,0 +1,189 @@
+package viewer
+
+import (
+    "cpu"
+    "ppu"
+    "image"
+    "image/color"
+)
+
+// Images of the live state of a PPU, for debugging. Everything is read back
+// through the PPU's memory, so it's exactly what rendering would see, but
+// without putting anything on the PPU's bus.
+
+const (
+    NAMETABLES_WIDTH = 512
+    NAMETABLES_HEIGHT = 480
+
+    PATTERNTABLES_WIDTH = 256
+    PATTERNTABLES_HEIGHT = 128
+
+    SWATCH_SIZE = 16
+    PALETTE_WIDTH = 16 * SWATCH_SIZE
+    PALETTE_HEIGHT = 2 * SWATCH_SIZE
+
+    // Sprites are laid out in an 8x8 grid of cells tall enough for 8x16
+    // sprites, with a pixel between them.
+    SPRITE_CELL_WIDTH = 9
+    SPRITE_CELL_HEIGHT = 17
+    SPRITES_WIDTH = 8 * SPRITE_CELL_WIDTH
+    SPRITES_HEIGHT = 8 * SPRITE_CELL_HEIGHT
+)
+
+var ViewportColor = color.RGBA { 0xff, 0x00, 0xff, 0xff }
+
+func colors(p *ppu.PPU) *[64]uint32 {
+    if p.Region == cpu.NTSC {
+        return &ppu.Palette
+    }
+
+    return &ppu.PALPalette
+}
+
+// The colour of one of the 64 colours the PPU can output.
+func Color(p *ppu.PPU, index byte) color.RGBA {
+    rgb := colors(p)[index & 0x3f]
+
+    return color.RGBA { byte(rgb >> 16), byte(rgb >> 8), byte(rgb), 0xff }
+}
+
+// The colour of an entry in palette RAM, $00-$1f.
+func PaletteColor(p *ppu.PPU, entry byte) color.RGBA {
+    return Color(p, p.Memory.Read(0x3f00 + cpu.Address(entry & 0x1f)))
+}
+
+// The 2 bit pixel at (x, y) of a tile in the pattern tables, where table is
+// $0000 or $1000.
+func tilePixel(p *ppu.PPU, table cpu.Address, tile byte, x int, y int) byte {
+    location := table + cpu.Address(tile) * 16 + cpu.Address(y)
+
+    low := p.Memory.Read(location)
+    high := p.Memory.Read(location + 8)
+
+    shift := uint(7 - x)
+    return (low >> shift) & 0x01 | ((high >> shift) & 0x01) << 1
+}
+
+func drawTile(img *image.RGBA, p *ppu.PPU, table cpu.Address, tile byte, palette byte, left int, top int) {
+    for y := 0; y < 8; y++ {
+        for x := 0; x < 8; x++ {
+            pixel := tilePixel(p, table, tile, x, y)
+
+            var entry = byte(0x00)
+            if pixel != 0 {
+                entry = palette << 2 | pixel
+            }
+
+            img.SetRGBA(left + x, top + y, PaletteColor(p, entry))
+        }
+    }
+}
+
+// Both pattern tables side by side, coloured with one of the eight palettes:
+// 0-3 for the background and 4-7 for sprites.
+func Patterntables(p *ppu.PPU, palette int) *image.RGBA {
+    img := image.NewRGBA(image.Rect(0, 0, PATTERNTABLES_WIDTH, PATTERNTABLES_HEIGHT))
+
+    for table := 0; table < 2; table++ {
+        for tile := 0; tile < 256; tile++ {
+            left := table * 128 + (tile % 16) * 8
+            top := (tile / 16) * 8
+
+            drawTile(img, p, cpu.Address(table * 0x1000), byte(tile), byte(palette & 0x07), left, top)
+        }
+    }
+
+    return img
+}
+
+// All 32 entries of palette RAM, the background palettes on the top row and
+// the sprite palettes on the bottom.
+func Palettes(p *ppu.PPU) *image.RGBA {
+    img := image.NewRGBA(image.Rect(0, 0, PALETTE_WIDTH, PALETTE_HEIGHT))
+
+    for entry := 0; entry < 32; entry++ {
+        c := PaletteColor(p, byte(entry))
+
+        left := (entry % 16) * SWATCH_SIZE
+        top := (entry / 16) * SWATCH_SIZE
+
+        for y := top; y < top + SWATCH_SIZE; y++ {
+            for x := left; x < left + SWATCH_SIZE; x++ {
+                img.SetRGBA(x, y, c)
+            }
+        }
+    }
+
+    return img
+}
+
+// The four nametables as they're arranged in the PPU's address space, with
+// the part the next frame starts scrolled to outlined.
+func Nametables(p *ppu.PPU) *image.RGBA {
+    img := image.NewRGBA(image.Rect(0, 0, NAMETABLES_WIDTH, NAMETABLES_HEIGHT))
+    table := cpu.Address(p.Ctrl.BackgroundTableAddress)
+
+    for n := 0; n < 4; n++ {
+        base := cpu.Address(0x2000 + n * 0x400)
+
+        for row := 0; row < 30; row++ {
+            for col := 0; col < 32; col++ {
+                tile := p.Memory.Read(base + cpu.Address(row * 32 + col))
+
+                attribute := p.Memory.Read(base + 0x3c0 + cpu.Address(row / 4 * 8 + col / 4))
+                shift := uint((row & 0x02) << 1 | (col & 0x02))
+                palette := (attribute >> shift) & 0x03
+
+                left := (n % 2) * 256 + col * 8
+                top := (n / 2) * 240 + row * 8
+
+                drawTile(img, p, table, tile, palette, left, top)
+            }
+        }
+    }
+
+    x, y := Scroll(p)
+    outline(img, x, y, 256, 240, ViewportColor)
+
+    return img
+}
+
+// The scroll position rendering will start the next frame from, measured in
+// pixels across all four nametables. This is the t register, which is copied
+// into v on the pre-render scanline.
+func Scroll(p *ppu.PPU) (int, int) {
+    t := p.TempAddr
+
+    x := int(t & 0x1f) * 8 + int(p.FineX) + int((t >> 10) & 0x01) * 256
+    y := int((t >> 5) & 0x1f) * 8 + int((t >> 12) & 0x07) + int((t >> 11) & 0x01) * 240
+
+    return x, y
+}
+
+// Draws a rectangle outline which wraps around the edges of the image, the
+// way the scroll wraps around the nametables.
+func outline(img *image.RGBA, left int, top int, width int, height int, c color.RGBA) {
+    bounds := img.Bounds()
+    w, h := bounds.Dx(), bounds.Dy()
+
+    for i := 0; i < width; i++ {
+        img.SetRGBA((left + i) % w, top % h, c)
+        img.SetRGBA((left + i) % w, (top + height - 1) % h, c)
+    }
+    for i := 0; i < height; i++ {
+        img.SetRGBA(left % w, (top + i) % h, c)
+        img.SetRGBA((left + width - 1) % w, (top + i) % h, c)
+    }
+}
+
+// The last frame the PPU drew.
+func Screen(p *ppu.PPU) *image.RGBA {
+    img := image.NewRGBA(image.Rect(0, 0, 256, 240))
+
+    for i := 0; i < 256 * 240; i++ {
+        copy(img.Pix[i*4:i*4+3], p.Display[i*3:i*3+3])
+        img.Pix[i*4+3] = 0xff
+    }
+
+    return img
+}
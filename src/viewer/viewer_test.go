@@ -0,0 +1,77 @@
+package viewer
+
+import (
+    "ppu"
+    "testing"
+    "github.com/stretchrcom/testify/assert"
+)
+
+func testPPU() *ppu.PPU {
+    p := ppu.NewPPU()
+    p.Memory.Mount(ppu.NewPatterntable(make([]byte, 0x1000)), 0x0000, 0x0fff)
+    p.Memory.Mount(ppu.NewPatterntable(make([]byte, 0x1000)), 0x1000, 0x1fff)
+
+    return p
+}
+
+func TestScrollFollowsTempAddr(t *testing.T) {
+    p := testPPU()
+    p.AddressLatch = true
+    p.Write(0x03, ppu.PPUCTRL)
+    p.Write(0x7d, ppu.PPUSCROLL)
+    p.Write(0x5e, ppu.PPUSCROLL)
+
+    x, y := Scroll(p)
+
+    assert.Equal(t, x, 256 + 0x7d)
+    assert.Equal(t, y, 240 + 0x5e)
+}
+
+func TestPalettesShowsPaletteRAM(t *testing.T) {
+    p := testPPU()
+    p.Memory.Write(0x16, 0x3f11)
+
+    img := Palettes(p)
+
+    assert.Equal(t, img.RGBAAt(SWATCH_SIZE, SWATCH_SIZE), Color(p, 0x16))
+    assert.Equal(t, img.RGBAAt(0, 0), Color(p, 0x00))
+}
+
+func TestPatterntablesUseTheChosenPalette(t *testing.T) {
+    p := testPPU()
+    p.Memory.Write(0x80, 0x1010)
+    p.Memory.Write(0x80, 0x1018)
+    p.Memory.Write(0x21, 0x3f17)
+
+    img := Patterntables(p, 5)
+
+    assert.Equal(t, img.RGBAAt(128 + 8, 0), Color(p, 0x21))
+    assert.Equal(t, img.RGBAAt(128 + 9, 0), Color(p, 0x00))
+}
+
+func TestNametablesUseAttributes(t *testing.T) {
+    p := testPPU()
+    p.Memory.Write(0xff, 0x0010)
+    p.Memory.Write(0x01, 0x2442)
+    p.Memory.Write(0x40, 0x27c0)
+    p.Memory.Write(0x2a, 0x3f05)
+
+    img := Nametables(p)
+
+    assert.Equal(t, img.RGBAAt(256 + 16, 16), Color(p, 0x2a))
+}
+
+func TestOAMDecodesAttributes(t *testing.T) {
+    p := testPPU()
+    copy(p.OAMRAM[4:8], []byte { 0x10, 0x42, 0xe3, 0x20 })
+
+    s := OAM(p)[1]
+
+    assert.Equal(t, s.X, 0x20)
+    assert.Equal(t, s.Y, 0x11)
+    assert.Equal(t, s.Tile, byte(0x42))
+    assert.Equal(t, s.Palette, byte(0x03))
+    assert.True(t, s.BehindBackground)
+    assert.True(t, s.FlipHorizontal)
+    assert.True(t, s.FlipVertical)
+}